@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/spf13/cobra"
+)
+
+var (
+	achievementsFormat string
+	achievementsOutput string
+)
+
+var achievementsCmd = &cobra.Command{
+	Use:   "achievements <game>",
+	Short: "Show your achievements in a game",
+	Long: `List a game's achievements with when you unlocked them and how rare they are
+among GOG players. Hidden achievements you haven't unlocked are shown without
+their description.
+
+--format json or csv exports them, e.g. to track progress across platforms.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch achievementsFormat {
+		case "table", "json", "csv":
+		default:
+			return fmt.Errorf("unknown format %q (want table, json or csv)", achievementsFormat)
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		product, err := gog.MatchProduct(products, args[0])
+		if err != nil {
+			return err
+		}
+		clientID, err := galaxyClientID(content.NewClient(client), product)
+		if err != nil {
+			return err
+		}
+		user, err := client.GetUserData()
+		if err != nil {
+			return err
+		}
+		achievements, err := client.GetAchievements(clientID, user.GalaxyUserID)
+		if err != nil {
+			return err
+		}
+
+		out := io.Writer(os.Stdout)
+		if achievementsOutput != "" {
+			f, err := os.Create(achievementsOutput)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+		if achievementsFormat != "table" {
+			return gog.WriteAchievements(out, achievements, achievementsFormat)
+		}
+		if len(achievements) == 0 {
+			fmt.Fprintf(out, "%s has no achievements.\n", product.Title)
+			return nil
+		}
+		unlocked := 0
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tACHIEVEMENT\tUNLOCKED\tRARITY\tDESCRIPTION")
+		for _, a := range achievements {
+			mark, when, desc := "", "-", a.Description
+			if a.UnlockedAt != nil {
+				mark, when = "✓", a.UnlockedAt.Local().Format("2006-01-02")
+				unlocked++
+			} else if !a.Visible {
+				desc = "(hidden)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f%%\t%s\n", mark, a.Name, when, a.Rarity, desc)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "\n%d of %d unlocked\n", unlocked, len(achievements))
+		return nil
+	},
+}
+
+// galaxyClientID returns the Galaxy client ID of product, which achievements
+// and play statistics are kept under. It comes with the build manifests, so
+// the installed builds are tried first, then the latest Windows and Mac ones.
+func galaxyClientID(cc *content.Client, product gog.Product) (string, error) {
+	type candidate struct{ os, build, branch, password string }
+	var candidates []candidate
+	if reg, err := gog.LoadRegistry(); err == nil {
+		for _, g := range reg.Installs(product.ID) {
+			if g.OS != "" {
+				candidates = append(candidates, candidate{g.OS, g.Build, g.Branch, g.BranchPassword})
+			}
+		}
+	}
+	candidates = append(candidates, candidate{os: "windows"}, candidate{os: "mac"})
+	for _, c := range candidates {
+		builds, err := cc.Builds(product.ID, c.os, c.password)
+		if err != nil {
+			return "", err
+		}
+		build, err := findBuild(builds, c.build, c.branch)
+		if err != nil {
+			continue
+		}
+		manifest, err := cc.Manifest(build)
+		if err != nil {
+			return "", err
+		}
+		if manifest.ClientID != "" {
+			return manifest.ClientID, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not a Galaxy game and has no achievements", product.Title)
+}
+
+func init() {
+	achievementsCmd.Flags().StringVar(&achievementsFormat, "format", "table", "Output format (table, json, csv)")
+	achievementsCmd.Flags().StringVarP(&achievementsOutput, "output", "o", "", "Write to a file instead of stdout")
+	rootCmd.AddCommand(achievementsCmd)
+}
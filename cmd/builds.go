@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildsOS         string
+	buildsBranch     string
+	buildsBranchPass string
+	buildsJSON       bool
+)
+
+var buildsCmd = &cobra.Command{
+	Use:   "builds <game>",
+	Short: "List the published builds of a game",
+	Long: `List every build of a game GOG has published through its content system,
+newest first, with version names, dates and branches. The build you have
+installed is marked with '*'. --branch-password also lists the builds of the
+branch it unlocks, and --branch shows just one branch ("default" for the
+main one).
+
+Install or roll back to one of them with 'goggle install <game> --build <id>'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		product, err := gog.MatchProduct(products, args[0])
+		if err != nil {
+			return err
+		}
+		targetOS := buildsOS
+		if targetOS == "" {
+			targetOS = gog.DetectOS()
+		}
+
+		builds, err := content.NewClient(client).Builds(product.ID, targetOS, buildsBranchPass)
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("branch") {
+			builds = branchBuilds(builds, buildsBranch)
+		}
+		if buildsJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if builds == nil {
+				builds = []content.Build{}
+			}
+			return enc.Encode(builds)
+		}
+		if len(builds) == 0 {
+			fmt.Printf("%s has no matching %s builds.\n", product.Title, targetOS)
+			return nil
+		}
+
+		installed := make(map[string]bool)
+		if reg, err := gog.LoadRegistry(); err == nil {
+			for _, g := range reg.Games {
+				if g.ID == product.ID && g.Build != "" {
+					installed[g.Build] = true
+				}
+			}
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "\tBUILD\tVERSION\tBRANCH\tPUBLISHED")
+		for _, b := range builds {
+			mark := ""
+			if installed[b.ID] {
+				mark = "*"
+			}
+			published := "-"
+			if !b.Published.IsZero() {
+				published = b.Published.Format("2006-01-02")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", mark, b.ID, orDash(b.Version), orDash(b.Branch), published)
+		}
+		return tw.Flush()
+	},
+}
+
+// branchBuilds returns the builds on branch; "default" names the main one.
+func branchBuilds(builds []content.Build, branch string) []content.Build {
+	branch = branchName(branch)
+	var filtered []content.Build
+	for _, b := range builds {
+		if strings.EqualFold(b.Branch, branch) {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered
+}
+
+// branchName returns the branch a --branch value names: "default" is the
+// main branch, which builds list as "".
+func branchName(branch string) string {
+	if strings.EqualFold(branch, "default") {
+		return ""
+	}
+	return branch
+}
+
+func init() {
+	buildsCmd.Flags().StringVar(&buildsOS, "os", "", "Build OS (windows, mac, linux). Defaults to current OS.")
+	buildsCmd.Flags().StringVar(&buildsBranch, "branch", "", "Only list builds of this branch (\"default\" for the main one)")
+	buildsCmd.Flags().StringVar(&buildsBranchPass, "branch-password", "", "Password unlocking a protected branch")
+	buildsCmd.Flags().BoolVar(&buildsJSON, "json", false, "Print the builds as JSON")
+	rootCmd.AddCommand(buildsCmd)
+}
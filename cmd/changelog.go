@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog <game>",
+	Short: "Show a game's changelog",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		product, err := gog.MatchProduct(products, args[0])
+		if err != nil {
+			return err
+		}
+		details, err := client.GetGameDetails(product.ID)
+		if err != nil {
+			return err
+		}
+
+		text := renderChangelog(details.Changelog)
+		if text == "" {
+			fmt.Printf("%s has no changelog.\n", product.Title)
+			return nil
+		}
+		fmt.Printf("%s\n\n%s\n", product.Title, text)
+		return nil
+	},
+}
+
+var (
+	// Block-level tags end a line; headings and paragraphs also start after a
+	// blank line
+	changelogBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|ul|ol|h[1-6])>`)
+	changelogBlockRe = regexp.MustCompile(`(?i)<(p|h[1-6])(\s[^>]*)?>`)
+	changelogItemRe  = regexp.MustCompile(`(?i)<li(\s[^>]*)?>`)
+)
+
+// paragraphMark stands in for the blank line before a paragraph or heading
+// while the HTML is flattened.
+const paragraphMark = "\x00"
+
+// renderChangelog turns GOG's changelog HTML into plain text with one entry
+// per line and list items as bullets.
+func renderChangelog(s string) string {
+	s = strings.NewReplacer("\r", "", "\n", " ").Replace(s)
+	s = changelogBlockRe.ReplaceAllString(s, "\n"+paragraphMark+"\n")
+	s = changelogItemRe.ReplaceAllString(s, "\n- ")
+	s = changelogBreakRe.ReplaceAllString(s, "\n")
+	s = stripHTML(s)
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == paragraphMark {
+			blank = len(lines) > 0
+			continue
+		}
+		if line == "" || line == "-" {
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		if strings.HasPrefix(line, "- ") {
+			line = "  " + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// changelogExcerpt returns the newest entry of a rendered changelog: the
+// text up to the first blank line after some bullets, at most maxLines
+// lines long.
+func changelogExcerpt(text string, maxLines int) string {
+	var out []string
+	bullets := false
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			if bullets {
+				break
+			}
+			continue
+		}
+		if len(out) == maxLines {
+			out = append(out, "  ...")
+			break
+		}
+		bullets = bullets || strings.HasPrefix(line, "  - ")
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+func init() {
+	rootCmd.AddCommand(changelogCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import "testing"
+
+func TestRenderChangelog(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "empty",
+			input: "",
+			want:  "",
+		},
+		{
+			name: "headings and lists",
+			input: "<h4>Update 1.2 (18 May 2020):</h4>\n<ul><li>Fixed crash &amp; hang</li>\n<li>New <b>map</b></li></ul>" +
+				"<h4>Update 1.1:</h4><ul><li>Initial patch</li></ul>",
+			want: "Update 1.2 (18 May 2020):\n  - Fixed crash & hang\n  - New map\n\nUpdate 1.1:\n  - Initial patch",
+		},
+		{
+			name:  "paragraphs and line breaks",
+			input: "<p>Version 2.0<br>Balance   changes</p><p>Version 1.0</p>",
+			want:  "Version 2.0\nBalance changes\n\nVersion 1.0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderChangelog(tt.input); got != tt.want {
+				t.Errorf("renderChangelog() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChangelogExcerpt(t *testing.T) {
+	text := "Update 1.2:\n  - One\n  - Two\n  - Three\n\nUpdate 1.1:\n  - Old"
+	tests := []struct {
+		name     string
+		maxLines int
+		want     string
+	}{
+		{"newest entry", 10, "Update 1.2:\n  - One\n  - Two\n  - Three"},
+		{"truncated", 2, "Update 1.2:\n  - One\n  ..."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changelogExcerpt(text, tt.maxLines); got != tt.want {
+				t.Errorf("changelogExcerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
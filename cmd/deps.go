@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/spf13/cobra"
+)
+
+var (
+	depsOS       string
+	depsDir      string
+	depsDownload bool
+	depsJSON     bool
+)
+
+var depsCmd = &cobra.Command{
+	Use:   "deps <game>",
+	Short: "List the redistributables a game's build depends on",
+	Long: `List the dependencies a game's content-system build declares, such as DirectX,
+Visual C++ runtimes, .NET or DOSBox, from GOG's dependency repository. For a
+game installed with --content or --build the installed build is used,
+otherwise the latest build for --os.
+
+Dependencies are downloaded once into a cache shared by all games
+(~/.cache/goggle/dependencies on Linux); --download fetches the missing ones.
+goggle never runs their installers; each one's installer is listed so you can
+run it yourself, e.g. with Wine.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		product, err := gog.MatchProduct(products, args[0])
+		if err != nil {
+			return err
+		}
+
+		cc := content.NewClient(client)
+		manifest, err := dependencyManifest(cc, product)
+		if err != nil {
+			return err
+		}
+		if len(manifest.Dependencies) == 0 {
+			fmt.Printf("%s declares no dependencies.\n", product.Title)
+			return nil
+		}
+		repo, err := cc.Dependencies()
+		if err != nil {
+			return err
+		}
+		deps, missing := repo.Find(manifest.Dependencies)
+		for _, id := range missing {
+			fmt.Fprintf(os.Stderr, "Warning: dependency %s is not in GOG's repository\n", id)
+		}
+		cache, err := content.DefaultDependencyCache()
+		if err != nil {
+			return err
+		}
+		if depsDownload {
+			if err := cacheDependencies(cc, cache, deps); err != nil {
+				return err
+			}
+		}
+
+		if depsJSON {
+			type depInfo struct {
+				content.Dependency
+				Cached    bool   `json:"cached"`
+				Path      string `json:"path,omitempty"`
+				Installer string `json:"installer,omitempty"`
+			}
+			infos := make([]depInfo, len(deps))
+			for i, d := range deps {
+				infos[i] = depInfo{Dependency: d, Cached: cache.Has(d)}
+				if infos[i].Cached {
+					infos[i].Path, _ = cache.Path(d)
+					infos[i].Installer = dependencyInstaller(cache, d)
+				}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(infos)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tNAME\tSIZE\tCACHED")
+		for _, d := range deps {
+			cached := "no"
+			if cache.Has(d) {
+				cached = "yes"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.ID, d.Name, gog.FormatSize(d.Size), cached)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		header := false
+		for _, d := range deps {
+			inst := dependencyInstaller(cache, d)
+			if inst == "" || !cache.Has(d) {
+				continue
+			}
+			if !header {
+				fmt.Println("\nInstallers:")
+				header = true
+			}
+			fmt.Printf("  %s: %s %s\n", d.ID, inst, d.Executable.Arguments)
+		}
+		return nil
+	},
+}
+
+// dependencyManifest returns the manifest of the build of product that is
+// installed, or of the latest one for --os.
+func dependencyManifest(cc *content.Client, product gog.Product) (*content.Manifest, error) {
+	targetOS, buildID, password := depsOS, "", ""
+	if reg, err := gog.LoadRegistry(); err == nil {
+		g, err := reg.Game(product.ID, depsDir)
+		if err != nil {
+			return nil, err
+		}
+		if g != nil && g.Build != "" && (depsOS == "" || depsOS == g.OS) {
+			targetOS, buildID, password = g.OS, g.Build, g.BranchPassword
+		}
+	}
+	if targetOS == "" {
+		targetOS = gog.DetectOS()
+	}
+	builds, err := cc.Builds(product.ID, targetOS, password)
+	if err != nil {
+		return nil, err
+	}
+	build, err := findBuild(builds, buildID, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", product.Title, err)
+	}
+	return cc.Manifest(build)
+}
+
+// cacheDependencies downloads deps into the shared cache, skipping the
+// ones cached already.
+func cacheDependencies(cc *content.Client, cache *content.DependencyCache, deps []content.Dependency) error {
+	for _, d := range deps {
+		if cache.Has(d) {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "Downloading %s...\n", d.Name)
+		_, _, err := cc.FetchDependency(d, cache, content.DownloadOptions{
+			Progress: func(done, total int64) {
+				fmt.Fprintf(os.Stderr, "\r  %s / %s", gog.FormatSize(done), gog.FormatSize(total))
+			},
+		})
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencyInstaller is the path of d's own installer in the cache, "" if
+// it has none.
+func dependencyInstaller(cache *content.DependencyCache, d content.Dependency) string {
+	dir, err := cache.Path(d)
+	if err != nil || d.Executable.Path == "" {
+		return ""
+	}
+	return filepath.Join(dir, filepath.FromSlash(strings.ReplaceAll(d.Executable.Path, `\`, "/")))
+}
+
+func init() {
+	depsCmd.Flags().StringVar(&depsOS, "os", "", "Build OS (windows, mac, linux). Defaults to the installed build's, or the current OS.")
+	depsCmd.Flags().StringVar(&depsDir, "dir", "", "Directory of the install to use, for games installed more than once")
+	depsCmd.Flags().BoolVar(&depsDownload, "download", false, "Download missing dependencies into the shared cache")
+	depsCmd.Flags().BoolVar(&depsJSON, "json", false, "Print the dependencies as JSON")
+	rootCmd.AddCommand(depsCmd)
+}
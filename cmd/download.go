@@ -1,155 +1,400 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"sort"
+	"runtime"
 	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
 
 	"github.com/josh/goggle/pkg/gog"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
-var downloadOS string
+var (
+	downloadOS          string
+	downloadIgnoreSpace bool
+	downloadAll         bool
+	downloadDryRun      bool
+	downloadJSON        bool
+	downloadPlanFile    string
+	downloadPlanOut     string
+	downloadLangs       []string
+	downloadDest        string
+	downloadWriteKeys   bool
+	downloadMovies      bool
+)
 
 var downloadCmd = &cobra.Command{
-	Use:   "download",
+	Use:   "download [game...]",
 	Short: "Download a game from your GOG library",
+	Long: `Download games from your GOG library.
+
+With no arguments an interactive picker is shown. Games can also be given by
+ID or title, or --all can be used to download the whole library.
+
+--lang takes languages in order of preference, e.g. "de,en": each game is
+downloaded in the first of them it is available in.
+
+--dry-run prints what would be downloaded without fetching anything, and
+--json prints it as JSON. --plan-out saves the plan to a file instead, to run
+it later with --plan.
+
+--movies downloads movies instead, with their extras, into a folder per
+movie, e.g. "Movie/Movie (1080p).mp4".`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		client, err := gog.NewClient()
+		client, err := newClient()
 		if err != nil {
 			return err
 		}
 
-		fmt.Println("Fetching library...")
-		ids, err := client.GetOwnedGameIDs()
+		reg, err := gog.LoadRegistry()
 		if err != nil {
 			return err
 		}
 
-		products, err := client.GetProducts(ids)
-		if err != nil {
-			return err
+		var plan *gog.Plan
+		interactive := false
+		if downloadPlanFile != "" {
+			if plan, err = gog.LoadPlan(downloadPlanFile); err != nil {
+				return err
+			}
+		} else {
+			if plan, interactive, err = buildDownloadPlan(client, args); err != nil {
+				return err
+			}
 		}
+		plan.SkipDownloaded(reg)
 
-		sort.Slice(products, func(i, j int) bool {
-			return products[i].Title < products[j].Title
-		})
-
-		// Pick a game
-		templates := &promptui.SelectTemplates{
-			Active:   "\u25b8 {{ .Title | cyan }}",
-			Inactive: "  {{ .Title }}",
-			Selected: "\u2714 {{ .Title | green }}",
-		}
-		searcher := func(input string, index int) bool {
-			return strings.Contains(
-				strings.ToLower(products[index].Title),
-				strings.ToLower(input),
-			)
-		}
-		gamePrompt := promptui.Select{
-			Label:     "Select a game to download",
-			Items:     products,
-			Templates: templates,
-			Size:      20,
-			Searcher:  searcher,
+		if downloadPlanOut != "" {
+			if err := gog.SavePlan(plan, downloadPlanOut); err != nil {
+				return err
+			}
+			fmt.Printf("Saved plan for %d files to %s\n", len(plan.Pending()), downloadPlanOut)
+			return nil
 		}
-		idx, _, err := gamePrompt.Run()
-		if err != nil {
-			return err
+		if downloadDryRun || downloadJSON {
+			if downloadJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(plan)
+			}
+			return printPlan(plan)
 		}
-		selected := products[idx]
 
-		fmt.Printf("Fetching details for %s...\n", selected.Title)
-		details, err := client.GetGameDetails(selected.ID)
-		if err != nil {
-			return err
+		pending := plan.Pending()
+		for _, item := range plan.Items {
+			if item.Skip != "" {
+				fmt.Printf("Skipping %s: %s\n", item.Game, item.Skip)
+			}
 		}
-
-		installers, err := gog.ParseInstallers(details)
-		if err != nil {
-			return err
+		if len(pending) == 0 {
+			return fmt.Errorf("nothing to download")
 		}
 
-		targetOS := downloadOS
-		if targetOS == "" {
-			targetOS = gog.DetectOS()
+		// Pick installer if multiple, unless --lang already chose
+		if interactive && len(pending) > 1 && len(downloadLangs) == 0 {
+			chosen, err := selectPlanItem(pending)
+			if err != nil {
+				return err
+			}
+			pending = []gog.PlanItem{chosen}
 		}
 
-		filtered := gog.FilterInstallersByOS(installers, targetOS)
-		if len(filtered) == 0 {
-			return fmt.Errorf("no %s installers found for %s", targetOS, selected.Title)
+		// Several files are checked against their total once; a single one
+		// against its exact size when it is resolved
+		spaceChecked := false
+		if len(pending) > 1 {
+			var total int64
+			for _, item := range pending {
+				total += item.Bytes
+			}
+			if err := checkSpace(plan.Dest, total, downloadIgnoreSpace); err != nil {
+				return err
+			}
+			spaceChecked = true
 		}
 
-		// Pick installer if multiple
-		var chosen gog.Installer
-		if len(filtered) == 1 {
-			chosen = filtered[0]
-		} else {
-			instTemplates := &promptui.SelectTemplates{
-				Active:   "\u25b8 {{ .Name | cyan }} ({{ .Size }}, {{ .Language }})",
-				Inactive: "  {{ .Name }} ({{ .Size }}, {{ .Language }})",
-				Selected: "\u2714 {{ .Name | green }}",
-			}
-			instPrompt := promptui.Select{
-				Label:     "Select installer",
-				Items:     filtered,
-				Templates: instTemplates,
+		for _, item := range pending {
+			var path string
+			if spaceChecked {
+				path, err = fetchItem(client, item, nil)
+			} else {
+				path, err = downloadItem(client, item, downloadIgnoreSpace)
 			}
-			instIdx, _, err := instPrompt.Run()
 			if err != nil {
 				return err
 			}
-			chosen = filtered[instIdx]
+			if err := recordDownload(reg, item, path); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to record download: %v\n", err)
+			}
+			if interactive && strings.HasSuffix(strings.ToLower(path), ".pkg") {
+				if err := promptPkgAction(path); err != nil {
+					return err
+				}
+			}
 		}
+		if downloadWriteKeys {
+			return writeKeys(client, pending)
+		}
+		return nil
+	},
+}
 
-		fmt.Printf("Resolving download URL for %s...\n", chosen.Name)
-		dlURL, err := client.ResolveDownloadURL(chosen.ManualURL)
+// writeKeys saves the CD keys of the downloaded games to keys.txt next to
+// their installers.
+func writeKeys(client *gog.Client, items []gog.PlanItem) error {
+	done := make(map[string]bool)
+	for _, item := range items {
+		path := filepath.Join(item.Dest, "keys.txt")
+		id := fmt.Sprintf("%d %s", item.GameID, path)
+		if done[id] {
+			continue
+		}
+		done[id] = true
+
+		keys, err := fetchKeys(client, item.GameID)
 		if err != nil {
 			return err
 		}
+		if len(keys) == 0 {
+			continue
+		}
+		if err := gog.WriteKeysFile(path, item.Game, keys); err != nil {
+			return fmt.Errorf("failed to write keys: %w", err)
+		}
+		fmt.Printf("Saved %d CD keys for %s to %s\n", len(keys), item.Game, path)
+	}
+	return nil
+}
+
+// buildDownloadPlan resolves the games to download from args, --all or the
+// interactive picker and collects their installers into a plan. The returned
+// bool reports whether the picker was used.
+func buildDownloadPlan(client *gog.Client, args []string) (*gog.Plan, bool, error) {
+	targetOS := downloadOS
+	if targetOS == "" {
+		targetOS = gog.DetectOS()
+	}
 
+	dest := downloadDest
+	if dest == "" {
 		home, err := os.UserHomeDir()
 		if err != nil {
-			return err
+			return nil, false, err
 		}
-		destDir := filepath.Join(home, "Downloads")
+		dest = filepath.Join(home, "Downloads")
+	}
 
-		fmt.Printf("Downloading to %s...\n", destDir)
-		path, err := client.DownloadFile(dlURL, destDir)
+	fetch, kind := fetchLibrary, "game"
+	if downloadMovies {
+		fetch, kind = fetchMovies, "movie"
+	}
+	products, err := fetch(client)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var games []gog.Product
+	interactive := false
+	switch {
+	case downloadAll:
+		games = products
+	case len(args) > 0:
+		if games, err = matchGames(products, args); err != nil {
+			return nil, false, err
+		}
+	default:
+		selected, err := selectGame(products, "Select a "+kind+" to download")
 		if err != nil {
-			return err
+			return nil, false, err
 		}
+		games = []gog.Product{selected}
+		interactive = true
+	}
 
-		fmt.Printf("Done! Saved to %s\n", path)
+	plan := gog.NewPlan(gog.PlanOptions{OS: targetOS, Languages: downloadLangs, Dest: dest})
+	for _, game := range games {
+		fmt.Fprintf(os.Stderr, "Fetching details for %s...\n", game.Title)
+		details, err := client.GetGameDetails(game.ID)
+		if err != nil {
+			return nil, false, err
+		}
 
-		if strings.HasSuffix(strings.ToLower(path), ".pkg") {
-			installPrompt := promptui.Select{
-				Label: fmt.Sprintf("Install %s?", filepath.Base(path)),
-				Items: []string{"Yes", "No"},
-			}
-			_, result, err := installPrompt.Run()
-			if err != nil {
-				return err
-			}
-			if result == "Yes" {
-				fmt.Printf("Running installer %s...\n", filepath.Base(path))
-				installCmd := exec.Command("open", path)
-				if err := installCmd.Run(); err != nil {
-					return fmt.Errorf("failed to open installer: %w", err)
-				}
+		if downloadMovies {
+			if err := plan.AddMovie(game, details); err != nil {
+				return nil, false, err
 			}
+			continue
+		}
+		installers, err := gog.ParseInstallers(details)
+		if err != nil {
+			return nil, false, err
+		}
+		plan.AddGame(game, installers)
+	}
+	return plan, interactive, nil
+}
+
+func selectPlanItem(items []gog.PlanItem) (gog.PlanItem, error) {
+	funcs := template.FuncMap{"size": gog.FormatSize}
+	for name, fn := range promptui.FuncMap {
+		funcs[name] = fn
+	}
+	templates := &promptui.SelectTemplates{
+		Active:   "\u25b8 {{ .Name | cyan }} ({{ size .Bytes }}, {{ .Language }})",
+		Inactive: "  {{ .Name }} ({{ size .Bytes }}, {{ .Language }})",
+		Selected: "\u2714 {{ .Name | green }}",
+		FuncMap:  funcs,
+	}
+	prompt := promptui.Select{
+		Label:     "Select installer",
+		Items:     items,
+		Templates: templates,
+	}
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return gog.PlanItem{}, err
+	}
+	return items[idx], nil
+}
+
+func printPlan(plan *gog.Plan) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GAME\tINSTALLER\tLANGUAGE\tSIZE\tDESTINATION")
+	for _, item := range plan.Items {
+		if item.Skip != "" {
+			fmt.Fprintf(tw, "%s\t-\t-\t-\tskip: %s\n", item.Game, item.Skip)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", item.Game, item.Name, item.Language, gog.FormatSize(item.Bytes), item.Dest)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d files, %s total\n", len(plan.Pending()), gog.FormatSize(plan.TotalBytes()))
+	if err := gog.CheckFreeSpace(plan.Dest, plan.TotalBytes()); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	return nil
+}
+
+// downloadItem downloads item after making sure its destination has room
+// for it.
+func downloadItem(client *gog.Client, item gog.PlanItem, ignoreSpace bool) (string, error) {
+	return fetchItem(client, item, func(size int64) error {
+		return checkSpace(item.Dest, size, ignoreSpace)
+	})
+}
+
+// fetchItem downloads item, first passing its exact size to check unless
+// check is nil.
+func fetchItem(client *gog.Client, item gog.PlanItem, check func(size int64) error) (string, error) {
+	fmt.Printf("Resolving download URL for %s...\n", item.Name)
+	dlURL, err := client.ResolveDownloadURL(item.ManualURL)
+	if err != nil {
+		return "", err
+	}
+
+	if check != nil {
+		size := item.Bytes
+		if n, err := client.ContentLength(dlURL); err == nil && n > 0 {
+			size = n
+		}
+		if err := check(size); err != nil {
+			return "", err
 		}
+	}
 
+	fmt.Printf("Downloading to %s...\n", item.Dest)
+	path, err := client.DownloadFileAs(dlURL, item.Dest, item.FileName)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Printf("Done! Saved to %s\n", path)
+	return path, nil
+}
+
+// recordDownload remembers a downloaded installer so 'goggle outdated' can
+// check it later.
+func recordDownload(reg *gog.Registry, item gog.PlanItem, path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	reg.AddDownload(gog.DownloadRecord{
+		GameID:       item.GameID,
+		Title:        item.Game,
+		Version:      item.Version,
+		OS:           item.OS,
+		Language:     item.Language,
+		ManualURL:    item.ManualURL,
+		Path:         abs,
+		DownloadedAt: time.Now(),
+	})
+	return reg.Save()
+}
+
+// promptPkgAction offers to extract a downloaded .pkg, open it with the
+// macOS installer, or leave it alone.
+func promptPkgAction(path string) error {
+	items := []string{"Extract", "Skip"}
+	if runtime.GOOS == "darwin" {
+		items = []string{"Extract", "Open installer", "Skip"}
+	}
+	prompt := promptui.Select{
+		Label: fmt.Sprintf("What should be done with %s?", filepath.Base(path)),
+		Items: items,
+	}
+	_, result, err := prompt.Run()
+	if err != nil {
+		return err
+	}
+	switch result {
+	case "Extract":
+		return extractPkg(path, "", false)
+	case "Open installer":
+		fmt.Printf("Running installer %s...\n", filepath.Base(path))
+		if err := exec.Command("open", path).Run(); err != nil {
+			return fmt.Errorf("failed to open installer: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkSpace makes sure destDir can hold size bytes. With ignore set, a
+// shortfall is only reported as a warning.
+func checkSpace(destDir string, size int64, ignore bool) error {
+	err := gog.CheckFreeSpace(destDir, size)
+	if err == nil {
 		return nil
-	},
+	}
+	if ignore {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return fmt.Errorf("%w (use --ignore-space to download anyway)", err)
 }
 
 func init() {
 	downloadCmd.Flags().StringVar(&downloadOS, "os", "", "Target OS (windows, mac, linux). Defaults to current OS.")
+	downloadCmd.Flags().BoolVar(&downloadIgnoreSpace, "ignore-space", false, "Download even if the destination looks too small")
+	downloadCmd.Flags().BoolVar(&downloadAll, "all", false, "Download every game in your library")
+	downloadCmd.Flags().BoolVar(&downloadMovies, "movies", false, "Download movies and their extras instead of games")
+	downloadCmd.Flags().BoolVar(&downloadDryRun, "dry-run", false, "Print the download plan without downloading anything")
+	downloadCmd.Flags().BoolVar(&downloadJSON, "json", false, "Print the plan as JSON instead of downloading (implies --dry-run)")
+	downloadCmd.Flags().StringVar(&downloadPlanOut, "plan-out", "", "Save the plan to a file instead of downloading")
+	downloadCmd.Flags().StringVar(&downloadPlanFile, "plan", "", "Execute a plan saved with --plan-out or --json")
+	downloadCmd.Flags().StringSliceVar(&downloadLangs, "lang", nil, "Preferred installer languages, best first, as names or codes (e.g. de,en)")
+	downloadCmd.Flags().StringVar(&downloadDest, "dest", "", "Destination directory. Defaults to ~/Downloads.")
+	downloadCmd.Flags().BoolVar(&downloadWriteKeys, "write-keys", false, "Save the games' CD keys to keys.txt next to the installers")
 	rootCmd.AddCommand(downloadCmd)
 }
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/innosetup"
+	"github.com/josh/goggle/pkg/gog/macpkg"
+	"github.com/spf13/cobra"
+)
+
+var (
+	extractList  bool
+	extractDir   string
+	extractLangs []string
+)
+
+var extractCmd = &cobra.Command{
+	Use:   "extract <setup.exe|game.pkg>",
+	Short: "Inspect or extract a Windows or macOS installer",
+	Long: `Inspect or extract a GOG Windows (Inno Setup .exe) or macOS (.pkg)
+installer without running it. Works on any OS.
+
+For Windows installers, game data stored in setup-N.bin files next to the
+installer is read automatically, and files bound for system folders
+(redistributables, temp files) are skipped.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe":
+			return extractInno(path)
+		case ".pkg":
+			if len(extractLangs) > 0 {
+				return fmt.Errorf("--lang is only supported for Windows installers")
+			}
+			return extractPkg(path, extractDir, extractList)
+		default:
+			return fmt.Errorf("%s is not a Windows .exe or macOS .pkg installer", filepath.Base(path))
+		}
+	},
+}
+
+func extractInno(path string) error {
+	setup, err := innosetup.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = setup.Close() }()
+
+	files := setup.FilesFor(extractLangs)
+	fmt.Printf("Name:       %s\n", setup.AppName)
+	fmt.Printf("Version:    %s\n", setup.AppVersion)
+	if setup.AppPublisher != "" {
+		fmt.Printf("Publisher:  %s\n", setup.AppPublisher)
+	}
+	if id := setup.GameID(); id != "" {
+		fmt.Printf("Game ID:    %s\n", id)
+	}
+	fmt.Printf("Inno Setup: %s\n", setup.Version)
+	if langs := setup.Languages(); len(langs) > 0 {
+		fmt.Printf("Languages:  %v\n", langs)
+	}
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	fmt.Printf("Files:      %d (%s)\n", len(files), gog.FormatSize(total))
+
+	if extractList {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, f := range files {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", f.Path, gog.FormatSize(f.Size))
+		}
+		return w.Flush()
+	}
+
+	dir, err := gameDir(extractDir, setup.AppName)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nExtracting to %s...\n", dir)
+	written, err := setup.Extract(dir, extractLangs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Done! Extracted %d files to %s\n", len(written), dir)
+	return nil
+}
+
+func extractPkg(path, dir string, list bool) error {
+	pkg, err := macpkg.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = pkg.Close() }()
+
+	name := pkg.Title
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	fmt.Printf("Name:       %s\n", name)
+	if pkg.Version != "" {
+		fmt.Printf("Version:    %s\n", pkg.Version)
+	}
+	if pkg.Identifier != "" {
+		fmt.Printf("Identifier: %s\n", pkg.Identifier)
+	}
+
+	if list {
+		entries, err := pkg.Entries()
+		if err != nil {
+			return err
+		}
+		var paths []string
+		var total int64
+		for _, e := range entries {
+			paths = append(paths, e.Path)
+			total += e.Size
+		}
+		if id := macpkg.GameID(paths); id != "" {
+			fmt.Printf("Game ID:    %s\n", id)
+		}
+		fmt.Printf("Files:      %d (%s)\n\n", len(entries), gog.FormatSize(total))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, e := range entries {
+			if e.IsDir() {
+				_, _ = fmt.Fprintf(w, "%s/\t\n", e.Path)
+				continue
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", e.Path, gog.FormatSize(e.Size))
+		}
+		return w.Flush()
+	}
+
+	dir, err = gameDir(dir, name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nExtracting to %s...\n", dir)
+	written, err := pkg.Extract(dir)
+	if err != nil {
+		return err
+	}
+	if id := macpkg.GameID(written); id != "" {
+		fmt.Printf("Game ID:    %s\n", id)
+	}
+	fmt.Printf("Done! Extracted %d files to %s\n", len(written), dir)
+	return nil
+}
+
+// gameDir returns dir, or ~/GOG Games/<name> if it is empty. name comes from
+// an installer or GOG's API, so it is made a valid file name first, which
+// also keeps it from leaving GOG Games.
+func gameDir(dir, name string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+	safe := gog.SafeFileName(name)
+	if safe == "" || safe == "." || safe == ".." {
+		return "", fmt.Errorf("can't name a directory after %q; choose one with --dir", name)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, "GOG Games", safe), nil
+}
+
+func init() {
+	extractCmd.Flags().BoolVar(&extractList, "list", false, "List the installer's files instead of extracting them")
+	extractCmd.Flags().StringVar(&extractDir, "dir", "", "Extract directory. Defaults to ~/GOG Games/<game>.")
+	extractCmd.Flags().StringSliceVar(&extractLangs, "lang", nil, "Only extract files for these installer languages (e.g. english,german)")
+	rootCmd.AddCommand(extractCmd)
+}
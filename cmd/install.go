@@ -0,0 +1,546 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/josh/goggle/pkg/gog/innosetup"
+	"github.com/josh/goggle/pkg/gog/macpkg"
+	"github.com/josh/goggle/pkg/gog/mojosetup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	installDir         string
+	installOS          string
+	installLangs       []string
+	installDest        string
+	installIgnoreSpace bool
+	installContent     bool
+	installBuildID     string
+	installBranch      string
+	installBranchPass  string
+	installNoDeps      bool
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install <game|installer>",
+	Short: "Download and install a game",
+	Long: `Install a game from your library, or from an installer you already have.
+
+Given a game ID or title, the installer is downloaded and extracted. Given a
+path to a Linux .sh, Windows .exe or macOS .pkg installer, it is extracted
+directly; installers are never run.
+
+With --content, the game's files are fetched from GOG's content system (the
+service GOG Galaxy installs from) instead, without any installer. This works
+for any OS's build on any machine, e.g. a Windows-only game on Linux.
+--build installs a specific build listed by 'goggle builds' the same way,
+rolling an existing install back or forward, and pins it so 'goggle upgrade'
+leaves it alone. Installing without --build unpins the game again.
+
+--branch installs the latest build of a beta branch instead of the default
+one, with --branch-password for password-protected branches. The branch is
+remembered, and 'goggle outdated' and 'goggle upgrade' follow it. As with
+'goggle builds', --branch default names the default branch.
+
+Redistributables the build depends on are downloaded into a cache shared by
+all games unless --no-deps is given; see 'goggle deps'.
+
+Installed games are recorded so 'goggle installed' can list them and
+'goggle uninstall' can remove them again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := gog.LoadRegistry()
+		if err != nil {
+			return err
+		}
+
+		var game gog.InstalledGame
+		if _, err := os.Stat(args[0]); err == nil {
+			game, err = installFromFile(args[0], installDir, "")
+			if err != nil {
+				return err
+			}
+		} else {
+			game, err = installFromLibrary(args[0], reg)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Reinstalling over a registered install, e.g. rolling back to an
+		// older build, drops the files the new one doesn't ship
+		if prev := reg.InDir(game.Dir); prev != nil && prev.ID != 0 && prev.ID == game.ID {
+			stale := gog.InstalledGame{Dir: prev.Dir, Files: missingFrom(prev.Files, game.Files)}
+			if err := stale.RemoveFiles(); err != nil {
+				return err
+			}
+		}
+		if err := gog.WriteManifest(game); err != nil {
+			return err
+		}
+		reg.Add(game)
+		if err := reg.Save(); err != nil {
+			return fmt.Errorf("failed to update installed games: %w", err)
+		}
+		fmt.Printf("Done! Installed %d files to %s\n", len(game.Files), game.Dir)
+		return nil
+	},
+}
+
+// installFromLibrary downloads the installer for query and extracts it.
+func installFromLibrary(query string, reg *gog.Registry) (gog.InstalledGame, error) {
+	client, err := newClient()
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	products, err := fetchLibrary(client)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	product, err := gog.MatchProduct(products, query)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	// Only an install in the directory being installed to is replaced
+	dir, err := gameDir(installDir, product.Title)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	prev := reg.InDir(dir)
+	if prev != nil && prev.ID != product.ID {
+		prev = nil
+	}
+	return installProduct(client, reg, product, installOptions{
+		OS:             installOS,
+		Langs:          installLangs,
+		Dest:           installDest,
+		Dir:            installDir,
+		IgnoreSpace:    installIgnoreSpace,
+		Content:        installContent,
+		Build:          installBuildID,
+		Branch:         installBranch,
+		BranchPassword: installBranchPass,
+		NoDeps:         installNoDeps,
+		Previous:       prev,
+	})
+}
+
+type installOptions struct {
+	OS             string   // defaults to the current OS
+	Langs          []string // preferred GOG languages
+	Dest           string   // download directory, defaults to ~/Downloads
+	Dir            string   // install directory, defaults to ~/GOG Games/<game>
+	IgnoreSpace    bool
+	Content        bool   // install the latest build file by file instead of via an installer
+	Build          string // install and pin this content-system build
+	Branch         string // content-system branch to install the latest build of
+	BranchPassword string
+	NoDeps         bool               // skip caching the build's dependencies
+	Previous       *gog.InstalledGame // install being replaced; patched in place if it came from a build
+}
+
+// installProduct downloads the current installer of product and extracts
+// it. Installers reg has on disk already are used as they are, and new ones
+// are recorded there like 'goggle download' does.
+func installProduct(client *gog.Client, reg *gog.Registry, product gog.Product, opts installOptions) (gog.InstalledGame, error) {
+	targetOS := opts.OS
+	if targetOS == "" {
+		targetOS = gog.DetectOS()
+	}
+	if opts.BranchPassword != "" && opts.Branch == "" {
+		return gog.InstalledGame{}, errors.New("--branch-password needs the --branch it unlocks")
+	}
+	if opts.Content || opts.Build != "" || opts.Branch != "" {
+		opts.Branch = branchName(opts.Branch)
+		return installBuild(client, product, targetOS, opts)
+	}
+	dest := opts.Dest
+	if dest == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return gog.InstalledGame{}, err
+		}
+		dest = filepath.Join(home, "Downloads")
+	}
+
+	fmt.Fprintf(os.Stderr, "Fetching details for %s...\n", product.Title)
+	details, err := client.GetGameDetails(product.ID)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	installers, err := gog.ParseInstallers(details)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	plan := gog.NewPlan(gog.PlanOptions{OS: targetOS, Languages: opts.Langs, Dest: dest})
+	plan.AddGame(product, installers)
+	items := installerSet(plan.Pending())
+	if len(items) == 0 {
+		return gog.InstalledGame{}, fmt.Errorf("%s: %s", product.Title, plan.Items[0].Skip)
+	}
+
+	var paths []string
+	for _, item := range items {
+		if d := reg.Downloaded(item.ManualURL, item.Version); d != nil {
+			fmt.Printf("Using %s, downloaded already\n", d.Path)
+			paths = append(paths, d.Path)
+			continue
+		}
+		path, err := downloadItem(client, item, opts.IgnoreSpace)
+		if err != nil {
+			return gog.InstalledGame{}, err
+		}
+		if path, err = filepath.Abs(path); err != nil {
+			return gog.InstalledGame{}, err
+		}
+		if err := recordDownload(reg, item, path); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record download: %v\n", err)
+		}
+		paths = append(paths, path)
+	}
+	main := mainInstaller(paths)
+	if main == "" {
+		return gog.InstalledGame{}, fmt.Errorf("no supported installer among %s", strings.Join(paths, ", "))
+	}
+
+	dir, err := gameDir(opts.Dir, product.Title)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	game, err := installFromFile(main, dir, items[0].Language)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	game.ID = product.ID
+	game.Title = product.Title
+	game.Version = items[0].Version
+	game.OS = targetOS
+	game.Language = items[0].Language
+	game.Installers = paths
+	return game, nil
+}
+
+// installBuild installs a build of product for targetOS from the content
+// system: opts.Build if set, otherwise the latest on opts.Branch.
+func installBuild(client *gog.Client, product gog.Product, targetOS string, opts installOptions) (gog.InstalledGame, error) {
+	cc := content.NewClient(client)
+	fmt.Fprintf(os.Stderr, "Fetching builds for %s...\n", product.Title)
+	builds, err := cc.Builds(product.ID, targetOS, opts.BranchPassword)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	if len(builds) == 0 {
+		return gog.InstalledGame{}, fmt.Errorf("%s has no %s builds", product.Title, targetOS)
+	}
+	build, err := findBuild(builds, opts.Build, opts.Branch)
+	if err != nil {
+		return gog.InstalledGame{}, fmt.Errorf("%s: %w", product.Title, err)
+	}
+	manifest, err := cc.Manifest(build)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+
+	prefs := opts.Langs
+	if len(prefs) == 0 {
+		prefs = []string{"English"}
+	}
+	for _, lang := range prefs {
+		if gog.LanguageCode(lang) == "" {
+			return gog.InstalledGame{}, fmt.Errorf("unknown language %q", lang)
+		}
+	}
+	lang := prefs[0]
+	if available := manifest.Languages(); len(available) > 0 {
+		var ok bool
+		if lang, ok = gog.PreferredLanguage(available, prefs); !ok {
+			return gog.InstalledGame{}, fmt.Errorf("%s build %s is not available in %s (only %s)",
+				product.Title, build.ID, strings.Join(prefs, ", "), strings.Join(available, ", "))
+		}
+	}
+	code := gog.LanguageCode(lang)
+	dir, err := gameDir(opts.Dir, product.Title)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		return gog.InstalledGame{}, err
+	}
+
+	dlOpts := content.DownloadOptions{
+		Languages: []string{code},
+		Progress: func(done, total int64) {
+			fmt.Fprintf(os.Stderr, "\r  %s / %s", gog.FormatSize(done), gog.FormatSize(total))
+		},
+	}
+	var files []string
+	if prev := opts.Previous; prev != nil && prev.Build != "" && prev.OS == targetOS && gog.SamePath(prev.Dir, dir) && listed(builds, prev.Build) {
+		fmt.Printf("Updating %s from build %s to %s (%s)...\n", product.Title, prev.Build, build.ID, build.Version)
+		files, err = patchBuild(cc, builds, *prev, manifest, dlOpts)
+	} else {
+		fmt.Printf("Installing %s %s (build %s) to %s...\n", product.Title, build.Version, build.ID, dir)
+		files, err = cc.Download(manifest, dir, dlOpts)
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	if len(manifest.Dependencies) > 0 && !opts.NoDeps {
+		if err := installDependencies(cc, manifest.Dependencies); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v; retry with 'goggle deps %d --download'\n", err, product.ID)
+		}
+	}
+	return gog.InstalledGame{
+		ID:             product.ID,
+		Title:          product.Title,
+		Version:        build.Version,
+		OS:             targetOS,
+		Language:       lang,
+		Build:          build.ID,
+		Pinned:         opts.Build != "",
+		Branch:         build.Branch,
+		BranchPassword: opts.BranchPassword,
+		Dependencies:   manifest.Dependencies,
+		Dir:            dir,
+		InstalledAt:    time.Now(),
+		Files:          files,
+	}, nil
+}
+
+// installDependencies caches the dependencies with ids.
+func installDependencies(cc *content.Client, ids []string) error {
+	repo, err := cc.Dependencies()
+	if err != nil {
+		return err
+	}
+	deps, missing := repo.Find(ids)
+	for _, id := range missing {
+		fmt.Fprintf(os.Stderr, "Warning: dependency %s is not in GOG's repository\n", id)
+	}
+	cache, err := content.DefaultDependencyCache()
+	if err != nil {
+		return err
+	}
+	if err := cacheDependencies(cc, cache, deps); err != nil {
+		return err
+	}
+	fmt.Printf("Dependencies are in %s; goggle doesn't run their installers.\n", cache.Dir)
+	return nil
+}
+
+// patchBuild updates prev to the build of manifest, downloading only what
+// changed, and returns the files of the new build.
+func patchBuild(cc *content.Client, builds []content.Build, prev gog.InstalledGame, manifest *content.Manifest, opts content.DownloadOptions) ([]string, error) {
+	installed, err := installedFiles(cc, builds, prev)
+	if err != nil {
+		return nil, err
+	}
+	target, err := cc.Files(manifest.SelectDepots(opts.Languages, opts.ProductIDs))
+	if err != nil {
+		return nil, err
+	}
+	res, err := cc.Patch(installed, target, prev.Dir, opts)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("Updated %d files, removed %d. Downloaded %s instead of %s (saved %s).\n",
+		len(res.Changed), len(res.Removed), gog.FormatSize(res.Downloaded), gog.FormatSize(res.FullSize), gog.FormatSize(res.Saved()))
+	return res.Files, nil
+}
+
+// installedFiles returns the files of the build game was installed from.
+func installedFiles(cc *content.Client, builds []content.Build, game gog.InstalledGame) ([]content.FileEntry, error) {
+	build, err := findBuild(builds, game.Build, game.Branch)
+	if err != nil {
+		return nil, fmt.Errorf("installed build no longer listed: %w", err)
+	}
+	manifest, err := cc.Manifest(build)
+	if err != nil {
+		return nil, err
+	}
+	var langs []string
+	if code := gog.LanguageCode(game.Language); code != "" {
+		langs = []string{code}
+	}
+	return cc.Files(manifest.SelectDepots(langs, nil))
+}
+
+// findBuild returns the build with the given ID, or for "" the latest on
+// branch.
+func findBuild(builds []content.Build, id, branch string) (content.Build, error) {
+	branch = branchName(branch)
+	if id == "" {
+		if b, ok := content.Latest(builds, branch); ok {
+			return b, nil
+		}
+		if branch == "" {
+			return content.Build{}, errors.New("no builds on the default branch")
+		}
+		return content.Build{}, fmt.Errorf("no builds on branch %q; see 'goggle builds'", branch)
+	}
+	for _, b := range builds {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return content.Build{}, fmt.Errorf("no build %s; see 'goggle builds'", id)
+}
+
+// listed reports whether builds include the build with id.
+func listed(builds []content.Build, id string) bool {
+	_, err := findBuild(builds, id, "")
+	return err == nil
+}
+
+// installerSet picks the files making up one installation: all parts in
+// the first language offered.
+func installerSet(items []gog.PlanItem) []gog.PlanItem {
+	var set []gog.PlanItem
+	for _, item := range items {
+		if item.Language == items[0].Language {
+			set = append(set, item)
+		}
+	}
+	return set
+}
+
+// mainInstaller returns the installer to extract among downloaded parts;
+// Windows .bin parts are read through their .exe.
+func mainInstaller(paths []string) string {
+	for _, p := range paths {
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".sh", ".exe", ".pkg":
+			return p
+		}
+	}
+	return ""
+}
+
+// installFromFile extracts the installer at path into dir (or the default
+// game directory) and describes the result. lang is the GOG language the
+// installer was downloaded for, used to pick files in multi-language
+// Windows installers.
+func installFromFile(path, dir, lang string) (gog.InstalledGame, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return gog.InstalledGame{}, err
+	}
+	game := gog.InstalledGame{Installers: []string{abs}, InstalledAt: time.Now()}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sh":
+		inst, err := mojosetup.Open(path)
+		if err != nil {
+			return game, err
+		}
+		defer func() { _ = inst.Close() }()
+		info, err := inst.GameInfo()
+		if err != nil {
+			return game, fmt.Errorf("failed to read game info: %w", err)
+		}
+		game.Title, game.Version, game.OS = info.Name, info.Version, "linux"
+		if game.Dir, err = gameDir(dir, game.Title); err != nil {
+			return game, err
+		}
+		fmt.Printf("Installing %s %s to %s...\n", game.Title, game.Version, game.Dir)
+		game.Files, err = inst.Extract(game.Dir)
+		if err != nil {
+			return game, err
+		}
+
+	case ".exe":
+		setup, err := innosetup.Open(path)
+		if err != nil {
+			return game, err
+		}
+		defer func() { _ = setup.Close() }()
+		game.Title, game.Version, game.OS = setup.AppName, setup.AppVersion, "windows"
+		game.ID = parseGameID(setup.GameID())
+		if game.Dir, err = gameDir(dir, game.Title); err != nil {
+			return game, err
+		}
+		fmt.Printf("Installing %s %s to %s...\n", game.Title, game.Version, game.Dir)
+		game.Files, err = setup.Extract(game.Dir, innoLanguages(setup.Languages(), lang))
+		if err != nil {
+			return game, err
+		}
+
+	case ".pkg":
+		pkg, err := macpkg.Open(path)
+		if err != nil {
+			return game, err
+		}
+		defer func() { _ = pkg.Close() }()
+		game.Title, game.Version, game.OS = pkg.Title, pkg.Version, "mac"
+		if game.Title == "" {
+			game.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		if game.Dir, err = gameDir(dir, game.Title); err != nil {
+			return game, err
+		}
+		fmt.Printf("Installing %s %s to %s...\n", game.Title, game.Version, game.Dir)
+		game.Files, err = pkg.Extract(game.Dir)
+		if err != nil {
+			return game, err
+		}
+		game.ID = parseGameID(macpkg.GameID(game.Files))
+
+	default:
+		return game, fmt.Errorf("%s is not a .sh, .exe or .pkg installer", filepath.Base(path))
+	}
+	game.Dir, err = filepath.Abs(game.Dir)
+	return game, err
+}
+
+// innoLanguages chooses which language-specific files of a Windows
+// installer to install: the download language if the installer knows it,
+// otherwise English, otherwise the installer's first language, as language
+// variants of a file share its path. langs are the installer's own
+// language names ("french", "german"), lang is as GOG names it ("français")
+// or a code, so both are compared as language codes.
+func innoLanguages(langs []string, lang string) []string {
+	if len(langs) == 0 {
+		return nil
+	}
+	for _, want := range []string{lang, "english"} {
+		if want == "" {
+			continue
+		}
+		for _, l := range langs {
+			if gog.SameLanguage(l, want) {
+				return []string{l}
+			}
+		}
+	}
+	return langs[:1]
+}
+
+// parseGameID converts the ID found in an installer, 0 if there was none.
+func parseGameID(s string) int {
+	id, _ := strconv.Atoi(s)
+	return id
+}
+
+func init() {
+	installCmd.Flags().StringVar(&installDir, "dir", "", "Install directory. Defaults to ~/GOG Games/<game>.")
+	installCmd.Flags().StringVar(&installOS, "os", "", "Installer OS (windows, mac, linux). Defaults to current OS.")
+	installCmd.Flags().StringSliceVar(&installLangs, "lang", nil, "Preferred languages, best first, as names or codes (e.g. de,en)")
+	installCmd.Flags().StringVar(&installDest, "dest", "", "Where to keep downloaded installers. Defaults to ~/Downloads.")
+	installCmd.Flags().BoolVar(&installIgnoreSpace, "ignore-space", false, "Download even if the destination looks too small")
+	installCmd.Flags().BoolVar(&installContent, "content", false, "Install the latest build from the content system, without an installer")
+	installCmd.Flags().StringVar(&installBuildID, "build", "", "Install and pin a specific content-system build (see 'goggle builds')")
+	installCmd.Flags().StringVar(&installBranch, "branch", "", "Install the latest build of this content-system branch, e.g. a beta")
+	installCmd.Flags().StringVar(&installBranchPass, "branch-password", "", "Password of a protected --branch")
+	installCmd.Flags().BoolVar(&installNoDeps, "no-deps", false, "Don't download the redistributables a content build depends on")
+	rootCmd.AddCommand(installCmd)
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+)
+
+func TestMainInstaller(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  string
+	}{
+		{
+			name:  "windows with parts",
+			paths: []string{"/dl/setup_game-1.bin", "/dl/setup_game.exe", "/dl/setup_game-2.bin"},
+			want:  "/dl/setup_game.exe",
+		},
+		{
+			name:  "linux",
+			paths: []string{"/dl/game_1_0.sh"},
+			want:  "/dl/game_1_0.sh",
+		},
+		{
+			name:  "unsupported",
+			paths: []string{"/dl/game.dmg"},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mainInstaller(tt.paths); got != tt.want {
+				t.Errorf("mainInstaller() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInnoLanguages(t *testing.T) {
+	langs := []string{"english", "french", "german"}
+	tests := []struct {
+		lang string
+		want []string
+	}{
+		{"français", []string{"french"}},
+		{"Deutsch", []string{"german"}},
+		{"de-DE", []string{"german"}},
+		{"polski", []string{"english"}},
+		{"", []string{"english"}},
+	}
+	for _, tt := range tests {
+		got := innoLanguages(langs, tt.lang)
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) {
+			t.Errorf("innoLanguages(%q) = %v, want %v", tt.lang, got, tt.want)
+		}
+	}
+	if got := innoLanguages([]string{"french", "german"}, "polski"); len(got) != 1 || got[0] != "french" {
+		t.Errorf("without English got %v, want the first language", got)
+	}
+	if got := innoLanguages(nil, "polski"); got != nil {
+		t.Errorf("without languages got %v, want every file", got)
+	}
+}
+
+func TestGameDir(t *testing.T) {
+	t.Setenv("HOME", "/home/user")
+	t.Setenv("USERPROFILE", `C:\Users\user`)
+	home, _ := os.UserHomeDir()
+	tests := map[string]string{
+		"The Witcher 3: Wild Hunt": "The Witcher 3_ Wild Hunt",
+		"../../.ssh":               ".._.._.ssh",
+		`..\Windows`:               `.._Windows`,
+	}
+	for name, want := range tests {
+		got, err := gameDir("", name)
+		if err != nil || got != filepath.Join(home, "GOG Games", want) {
+			t.Errorf("gameDir(%q) = %q, %v, want %s", name, got, err, want)
+		}
+	}
+	for _, name := range []string{"", ".", "..", " .. "} {
+		if got, err := gameDir("", name); err == nil {
+			t.Errorf("gameDir(%q) = %q, want an error", name, got)
+		}
+	}
+	if got, err := gameDir("/games/x", ".."); err != nil || got != "/games/x" {
+		t.Errorf("an explicit dir should be kept, got %q, %v", got, err)
+	}
+}
+
+func TestInstallerSet(t *testing.T) {
+	items := []gog.PlanItem{
+		{Name: "setup.exe", Language: "English"},
+		{Name: "setup-1.bin", Language: "English"},
+		{Name: "setup.exe", Language: "Deutsch"},
+	}
+	got := installerSet(items)
+	if len(got) != 2 {
+		t.Fatalf("got %d items, want 2", len(got))
+	}
+	for _, item := range got {
+		if item.Language != "English" {
+			t.Errorf("unexpected %s item", item.Language)
+		}
+	}
+}
+
+func TestFindBuild(t *testing.T) {
+	builds := []content.Build{{ID: "400", Version: "2.0b", Branch: "beta"}, {ID: "300", Version: "1.2"}, {ID: "200", Version: "1.1"}}
+	if b, err := findBuild(builds, "", ""); err != nil || b.ID != "300" {
+		t.Errorf("latest = %+v, %v", b, err)
+	}
+	if b, err := findBuild(builds, "", "Default"); err != nil || b.ID != "300" {
+		t.Errorf("latest on the default branch = %+v, %v", b, err)
+	}
+	if b, err := findBuild(builds, "", "beta"); err != nil || b.ID != "400" {
+		t.Errorf("latest beta = %+v, %v", b, err)
+	}
+	if _, err := findBuild(builds, "", "nightly"); err == nil {
+		t.Error("expected error for unknown branch")
+	}
+	if b, err := findBuild(builds, "200", ""); err != nil || b.Version != "1.1" {
+		t.Errorf("build 200 = %+v, %v", b, err)
+	}
+	if _, err := findBuild(builds, "100", ""); err == nil {
+		t.Error("expected error for unknown build")
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var installedJSON bool
+
+var installedCmd = &cobra.Command{
+	Use:   "installed",
+	Short: "List games installed with goggle",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := gog.LoadRegistry()
+		if err != nil {
+			return err
+		}
+
+		if installedJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(reg.Games)
+		}
+		if len(reg.Games) == 0 {
+			fmt.Println("No games installed. Use 'goggle install <game>' to install one.")
+			return nil
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "GAME\tVERSION\tOS\tLANGUAGE\tFILES\tDIRECTORY")
+		for _, g := range reg.Games {
+			version := orDash(g.Version)
+			if g.Pinned {
+				version += " (pinned)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\n", g.Title, version, orDash(g.OS), orDash(g.Language), len(g.Files), g.Dir)
+		}
+		return tw.Flush()
+	},
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	installedCmd.Flags().BoolVar(&installedJSON, "json", false, "Print the installed games as JSON")
+	rootCmd.AddCommand(installedCmd)
+}
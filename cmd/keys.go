@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var keysJSON bool
+
+// gameKeys is the JSON output of 'goggle keys'.
+type gameKeys struct {
+	GameID int         `json:"game_id"`
+	Title  string      `json:"title"`
+	Keys   []gog.CDKey `json:"keys"`
+}
+
+var keysCmd = &cobra.Command{
+	Use:   "keys [game...]",
+	Short: "Show CD keys and serials",
+	Long: `Show the CD keys and serials GOG provides for some games, such as classics
+and multiplayer titles. With no arguments the whole library is checked.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			if products, err = matchGames(products, args); err != nil {
+				return err
+			}
+		}
+
+		all := []gameKeys{}
+		for _, p := range products {
+			fmt.Fprintf(os.Stderr, "Checking %s...\n", p.Title)
+			keys, err := fetchKeys(client, p.ID)
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				all = append(all, gameKeys{GameID: p.ID, Title: p.Title, Keys: keys})
+			}
+		}
+
+		if keysJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(all)
+		}
+		if len(all) == 0 {
+			fmt.Println("No CD keys found.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "GAME\tFOR\tKEY")
+		for _, g := range all {
+			for _, k := range g.Keys {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", g.Title, k.Name, k.Key)
+			}
+		}
+		return tw.Flush()
+	},
+}
+
+// fetchKeys returns the CD keys of a game and its DLCs.
+func fetchKeys(client *gog.Client, id int) ([]gog.CDKey, error) {
+	details, err := client.GetGameDetails(id)
+	if err != nil {
+		return nil, err
+	}
+	return details.Keys(), nil
+}
+
+func init() {
+	keysCmd.Flags().BoolVar(&keysJSON, "json", false, "Print the keys as JSON")
+	rootCmd.AddCommand(keysCmd)
+}
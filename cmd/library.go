@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/manifoldco/promptui"
+)
+
+// fetchLibrary returns all owned games sorted by title.
+func fetchLibrary(client *gog.Client) ([]gog.Product, error) {
+	fmt.Fprintln(os.Stderr, "Fetching library...")
+	ids, err := ownedGameIDs(client)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := client.GetProducts(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	sortProducts(products)
+	return products, nil
+}
+
+// fetchMovies returns all owned movies sorted by title.
+func fetchMovies(client *gog.Client) ([]gog.Product, error) {
+	fmt.Fprintln(os.Stderr, "Fetching movies...")
+	movies, err := client.GetOwnedMovies()
+	if err != nil {
+		return nil, err
+	}
+	sortProducts(movies)
+	return movies, nil
+}
+
+// ownedGameIDs returns the IDs of the owned games, leaving out movies. If
+// the movies can't be listed, every product is treated as a game rather than
+// failing commands that have nothing to do with movies.
+func ownedGameIDs(client *gog.Client) ([]int, error) {
+	ids, err := client.GetOwnedGameIDs()
+	if err != nil {
+		return nil, err
+	}
+	movies, err := client.GetOwnedMovies()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: movies may be listed as games: %v\n", err)
+		return ids, nil
+	}
+	return gog.WithoutMovies(ids, movies), nil
+}
+
+func sortProducts(products []gog.Product) {
+	sort.Slice(products, func(i, j int) bool {
+		return products[i].Title < products[j].Title
+	})
+}
+
+// selectGame shows a searchable picker over products.
+func selectGame(products []gog.Product, label string) (gog.Product, error) {
+	templates := &promptui.SelectTemplates{
+		Active:   "\u25b8 {{ .Title | cyan }}",
+		Inactive: "  {{ .Title }}",
+		Selected: "\u2714 {{ .Title | green }}",
+	}
+	searcher := func(input string, index int) bool {
+		return strings.Contains(
+			strings.ToLower(products[index].Title),
+			strings.ToLower(input),
+		)
+	}
+	prompt := promptui.Select{
+		Label:     label,
+		Items:     products,
+		Templates: templates,
+		Size:      20,
+		Searcher:  searcher,
+	}
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return gog.Product{}, err
+	}
+	return products[idx], nil
+}
+
+// matchGames resolves each query (ID or title) against products.
+func matchGames(products []gog.Product, queries []string) ([]gog.Product, error) {
+	games := make([]gog.Product, 0, len(queries))
+	for _, q := range queries {
+		p, err := gog.MatchProduct(products, q)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, p)
+	}
+	return games, nil
+}
@@ -3,26 +3,42 @@ package cmd
 import (
 	"fmt"
 	"html"
+	"os"
 	"regexp"
 	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/josh/goggle/pkg/gog"
 	"github.com/manifoldco/promptui"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listMovies bool
+)
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List your GOG library",
+	Long: `Browse the games in your GOG library and show the details of one.
+
+--locale shows descriptions in another language, given as a code such as
+"de" or "pt-BR", or a name such as "Deutsch", where GOG has a translation.
+
+--movies lists the movies and documentaries you own instead; download them
+with 'goggle download --movies'.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		client, err := gog.NewClient()
+		client, err := newClient()
 		if err != nil {
 			return err
 		}
+		if listMovies {
+			return printMovies(client)
+		}
 
 		fmt.Println("Fetching library...")
-		ids, err := client.GetOwnedGameIDs()
+		ids, err := ownedGameIDs(client)
 		if err != nil {
 			return err
 		}
@@ -107,6 +123,18 @@ var listCmd = &cobra.Command{
 			fmt.Printf("  Store Page:    https://www.gog.com%s\n", details.Links.ProductCard)
 		}
 
+		if keys, err := fetchKeys(client, selected.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to fetch CD keys: %v\n", err)
+		} else {
+			for i, k := range keys {
+				label := ""
+				if i == 0 {
+					label = "CD Keys:"
+				}
+				fmt.Printf("  %-14s %s: %s\n", label, k.Name, k.Key)
+			}
+		}
+
 		if details.Description != nil && details.Description.Lead != "" {
 			fmt.Printf("\n  %s\n", stripHTML(details.Description.Lead))
 		}
@@ -116,6 +144,27 @@ var listCmd = &cobra.Command{
 	},
 }
 
+func printMovies(client *gog.Client) error {
+	movies, err := fetchMovies(client)
+	if err != nil {
+		return err
+	}
+	if len(movies) == 0 {
+		fmt.Println("You own no movies.")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE")
+	for _, m := range movies {
+		fmt.Fprintf(tw, "%d\t%s\n", m.ID, m.Title)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d movies\n", len(movies))
+	return nil
+}
+
 var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
 
 func stripHTML(s string) string {
@@ -124,5 +173,6 @@ func stripHTML(s string) string {
 }
 
 func init() {
+	listCmd.Flags().BoolVar(&listMovies, "movies", false, "List your movies instead of games")
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/spf13/cobra"
+)
+
+var outdatedJSON bool
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List installed games and downloads with newer versions on GOG",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := gog.LoadRegistry()
+		if err != nil {
+			return err
+		}
+		if len(reg.Games) == 0 && len(reg.Downloads) == 0 {
+			fmt.Println("Nothing to check. Games installed or downloaded with goggle are tracked automatically.")
+			return nil
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		updates, err := gog.FindUpdates(reg, fetchInstallers(client), latestBuilds(client))
+		if err != nil {
+			return err
+		}
+
+		if outdatedJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if updates == nil {
+				updates = []gog.Update{}
+			}
+			return enc.Encode(updates)
+		}
+		if len(updates) == 0 {
+			fmt.Println("Everything is up to date.")
+			return nil
+		}
+		return printUpdates(updates)
+	},
+}
+
+// fetchInstallers returns a lookup of a game's current installers for
+// gog.FindUpdates.
+func fetchInstallers(client *gog.Client) func(id int) ([]gog.Installer, error) {
+	return func(id int) ([]gog.Installer, error) {
+		fmt.Fprintf(os.Stderr, "Checking game %d...\n", id)
+		details, err := client.GetGameDetails(id)
+		if err != nil {
+			return nil, err
+		}
+		return gog.ParseInstallers(details)
+	}
+}
+
+// latestBuilds returns a lookup of the newest build on the branch an
+// install follows for gog.FindUpdates.
+func latestBuilds(client *gog.Client) gog.LatestBuild {
+	cc := content.NewClient(client)
+	return func(g gog.InstalledGame) (string, string, error) {
+		fmt.Fprintf(os.Stderr, "Checking builds of game %d...\n", g.ID)
+		builds, err := cc.Builds(g.ID, g.OS, g.BranchPassword)
+		if err != nil {
+			return "", "", err
+		}
+		b, ok := content.Latest(builds, g.Branch)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s: branch %q no longer has builds\n", g.Title, g.Branch)
+			return "", "", nil
+		}
+		return b.ID, b.Version, nil
+	}
+}
+
+func printUpdates(updates []gog.Update) error {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GAME\tKIND\tCURRENT\tLATEST\tLOCATION")
+	for _, u := range updates {
+		title := u.Title
+		if u.Branch != "" {
+			title += " [" + u.Branch + "]"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", title, u.Kind, u.Current, u.Latest, u.Location)
+	}
+	return tw.Flush()
+}
+
+func init() {
+	outdatedCmd.Flags().BoolVar(&outdatedJSON, "json", false, "Print the updates as JSON")
+	rootCmd.AddCommand(outdatedCmd)
+}
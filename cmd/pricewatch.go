@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pricewatchAddBelow   float64
+	pricewatchCheckBelow float64
+	pricewatchNoWishlist bool
+	pricewatchWebhook    string
+	pricewatchCountry    string
+	pricewatchCurrency   string
+	pricewatchJSON       bool
+)
+
+// Exit codes of 'pricewatch check', so cron jobs can tell an alert from a
+// failure to deliver it.
+const (
+	exitPriceAlert    = 2 // a watch fired
+	exitWebhookFailed = 3 // a watch fired but the webhook failed
+)
+
+var pricewatchCmd = &cobra.Command{
+	Use:   "pricewatch",
+	Short: "Track store prices of wishlisted and watched games",
+	Long: `Follow the store prices of games on your wishlist and of games you watch
+explicitly, and get told when they drop.
+
+'goggle pricewatch check' records the current prices and reports any that fell
+to or below a watch's threshold or below the lowest price seen so far. It
+exits with status 2 when something fired, and can post the alerts to a
+webhook, so it can run from cron. If posting to the webhook fails it exits
+with status 3 instead.
+
+Adding a game that is already watched only changes its threshold when
+--below is given.
+
+With no subcommand the watched games are listed with their last and lowest
+recorded prices.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pw, err := gog.LoadPriceWatch()
+		if err != nil {
+			return err
+		}
+		if len(pw.Watches) == 0 {
+			fmt.Println("No watched games. Add one with 'goggle pricewatch add <id|slug>'.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTITLE\tBELOW\tLAST PRICE\tLOWEST\tCHECKED")
+		for _, w := range pw.Watches {
+			below, last, lowest, checked := "-", "-", "-", "-"
+			if w.Below > 0 {
+				below = fmt.Sprintf("%.2f", w.Below)
+			}
+			if p := pw.Last(w.ID); p != nil {
+				last = (&gog.Price{Final: p.Final, Base: p.Base, Discount: p.Discount, Currency: p.Currency}).String()
+				lowest = fmt.Sprintf("%.2f %s", pw.Low(w.ID, p.Currency), p.Currency)
+				checked = p.Time.Format("2006-01-02")
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", w.ID, w.Title, below, last, lowest, checked)
+		}
+		return tw.Flush()
+	},
+}
+
+var pricewatchAddCmd = &cobra.Command{
+	Use:   "add <id|slug|url>...",
+	Short: "Watch the price of products",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pw, err := gog.LoadPriceWatch()
+		if err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		for _, ref := range args {
+			p, err := resolveProductRef(client, ref)
+			if err != nil {
+				return err
+			}
+			if p.Title == "" {
+				products, err := client.GetProducts([]int{p.ID})
+				if err != nil {
+					return err
+				}
+				if len(products) == 0 {
+					return fmt.Errorf("no product with ID %d", p.ID)
+				}
+				p = products[0]
+			}
+			below := pricewatchAddBelow
+			if !cmd.Flags().Changed("below") {
+				if old := pw.Watched(p.ID); old != nil {
+					below = old.Below
+				}
+			}
+			pw.Add(gog.Watch{ID: p.ID, Title: p.Title, Below: below, AddedAt: time.Now()})
+			fmt.Printf("Watching %s\n", p.Title)
+		}
+		return pw.Save()
+	},
+}
+
+var pricewatchRemoveCmd = &cobra.Command{
+	Use:   "remove <game>...",
+	Short: "Stop watching products",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pw, err := gog.LoadPriceWatch()
+		if err != nil {
+			return err
+		}
+		watched := make([]gog.Product, len(pw.Watches))
+		for i, w := range pw.Watches {
+			watched[i] = gog.Product{ID: w.ID, Title: w.Title}
+		}
+		for _, q := range args {
+			p, err := gog.MatchProductIn(watched, q, "in your price watches")
+			if err != nil {
+				return err
+			}
+			pw.Remove(p.ID)
+			fmt.Printf("Stopped watching %s\n", p.Title)
+		}
+		return pw.Save()
+	},
+}
+
+var pricewatchCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Record current prices and report drops",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pw, err := gog.LoadPriceWatch()
+		if err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		watches := append([]gog.Watch(nil), pw.Watches...)
+		if !pricewatchNoWishlist {
+			wishlist, err := fetchWishlist(client)
+			if err != nil {
+				return err
+			}
+			watches = mergeWatches(watches, wishlist)
+		}
+		if len(watches) == 0 {
+			fmt.Println("Nothing to check. Wishlist games or add watches with 'goggle pricewatch add'.")
+			return nil
+		}
+
+		alerts := []gog.PriceAlert{}
+		now := time.Now()
+		for _, w := range watches {
+			fmt.Fprintf(os.Stderr, "Checking %s...\n", w.Title)
+			price, err := client.GetPrice(w.ID, pricewatchCountry, pricewatchCurrency)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				continue
+			}
+			threshold := w.Below
+			if threshold == 0 {
+				threshold = pricewatchCheckBelow
+			}
+			if alert := pw.Record(w.ID, w.Title, *price, threshold, now); alert != nil {
+				alerts = append(alerts, *alert)
+			}
+		}
+		if err := pw.Save(); err != nil {
+			return fmt.Errorf("failed to save price history: %w", err)
+		}
+
+		if pricewatchJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(alerts); err != nil {
+				return err
+			}
+		} else if len(alerts) == 0 {
+			fmt.Printf("Checked %d games, no price drops.\n", len(watches))
+		} else {
+			for _, a := range alerts {
+				fmt.Printf("%s: %s, %s\n", a.Title, a.Price.String(), a.Reason)
+			}
+		}
+		if len(alerts) == 0 {
+			return nil
+		}
+
+		cmd.SilenceUsage = true
+		if pricewatchWebhook != "" {
+			if err := postAlerts(pricewatchWebhook, alerts); err != nil {
+				return &exitError{code: exitWebhookFailed, err: err}
+			}
+		}
+		return &exitError{code: exitPriceAlert, err: fmt.Errorf("%d price watches fired", len(alerts))}
+	},
+}
+
+// mergeWatches adds the wishlisted products that aren't watched already.
+func mergeWatches(watches []gog.Watch, wishlist []gog.Product) []gog.Watch {
+	seen := make(map[int]bool, len(watches))
+	for _, w := range watches {
+		seen[w.ID] = true
+	}
+	for _, p := range wishlist {
+		if !seen[p.ID] {
+			watches = append(watches, gog.Watch{ID: p.ID, Title: p.Title})
+		}
+	}
+	return watches
+}
+
+// webhookClient posts alerts; a hung webhook fails the check instead of
+// stalling it.
+var webhookClient = &http.Client{Timeout: 30 * time.Second}
+
+// postAlerts sends the alerts to a webhook as JSON. The "text" field makes
+// the payload readable by Slack-compatible incoming webhooks.
+func postAlerts(url string, alerts []gog.PriceAlert) error {
+	lines := make([]string, len(alerts))
+	for i, a := range alerts {
+		lines[i] = fmt.Sprintf("%s: %s, %s", a.Title, a.Price.String(), a.Reason)
+	}
+	body, err := json.Marshal(struct {
+		Text   string           `json:"text"`
+		Alerts []gog.PriceAlert `json:"alerts"`
+	}{strings.Join(lines, "\n"), alerts})
+	if err != nil {
+		return err
+	}
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook failed: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func init() {
+	pricewatchAddCmd.Flags().Float64Var(&pricewatchAddBelow, "below", 0, "Alert when the price drops to or below this amount")
+	pricewatchCheckCmd.Flags().Float64Var(&pricewatchCheckBelow, "below", 0, "Threshold for games without their own, such as wishlisted ones")
+	pricewatchCheckCmd.Flags().BoolVar(&pricewatchNoWishlist, "no-wishlist", false, "Only check explicitly watched games")
+	pricewatchCheckCmd.Flags().StringVar(&pricewatchWebhook, "webhook", "", "POST alerts as JSON to this URL")
+	pricewatchCheckCmd.Flags().StringVar(&pricewatchCountry, "country", "US", "Country to check prices for (ISO code)")
+	pricewatchCheckCmd.Flags().StringVar(&pricewatchCurrency, "currency", "USD", "Currency to check prices in (ISO code)")
+	pricewatchCheckCmd.Flags().BoolVar(&pricewatchJSON, "json", false, "Print the alerts as JSON")
+	pricewatchCmd.AddCommand(pricewatchAddCmd, pricewatchRemoveCmd, pricewatchCheckCmd)
+	rootCmd.AddCommand(pricewatchCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+func TestMergeWatches(t *testing.T) {
+	watches := []gog.Watch{{ID: 1, Title: "A", Below: 5}}
+	got := mergeWatches(watches, []gog.Product{{ID: 1, Title: "A"}, {ID: 2, Title: "B"}})
+	if len(got) != 2 || got[0].Below != 5 || got[1].ID != 2 {
+		t.Errorf("mergeWatches = %+v", got)
+	}
+}
+
+func TestPostAlerts(t *testing.T) {
+	var payload struct {
+		Text   string           `json:"text"`
+		Alerts []gog.PriceAlert `json:"alerts"`
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	alerts := []gog.PriceAlert{{ID: 1, Title: "Game", Price: gog.Price{Final: 4.99, Base: 19.99, Discount: 75, Currency: "USD"}, Reason: "new low, was 9.99 USD"}}
+	if err := postAlerts(ts.URL, alerts); err != nil {
+		t.Fatalf("postAlerts: %v", err)
+	}
+	if payload.Text != "Game: 4.99 USD (-75%), new low, was 9.99 USD" || len(payload.Alerts) != 1 {
+		t.Errorf("payload = %+v", payload)
+	}
+
+	fail := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer fail.Close()
+	if err := postAlerts(fail.URL, alerts); err == nil {
+		t.Error("expected error for failing webhook")
+	}
+
+	// A webhook that never answers times out
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+	timeout := webhookClient.Timeout
+	webhookClient.Timeout = 50 * time.Millisecond
+	defer func() { webhookClient.Timeout = timeout }()
+	if err := postAlerts(hung.URL, alerts); err == nil {
+		t.Error("expected error for hung webhook")
+	}
+}
@@ -1,20 +1,57 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/josh/goggle/pkg/gog"
 	"github.com/spf13/cobra"
 )
 
+// locale is the --locale flag: the language product details are fetched in.
+var locale string
+
 var rootCmd = &cobra.Command{
 	Use:   "goggle",
 	Short: "Download games from your GOG library",
 }
 
+// exitError ends the program with a specific exit status.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// newClient returns a logged-in client that fetches product details in the
+// --locale language.
+func newClient() (*gog.Client, error) {
+	client, err := gog.NewClient()
+	if err != nil {
+		return nil, err
+	}
+	if locale != "" {
+		if client.Locale = gog.Locale(locale); client.Locale == "" {
+			return nil, fmt.Errorf("unknown locale %q", locale)
+		}
+	}
+	return client, nil
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
+		var exit *exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&locale, "locale", "", "Language of game descriptions (e.g. de, fr-FR)")
+}
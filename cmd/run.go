@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	runTask string
+	runList bool
+	runDir  string
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run <game>",
+	Short: "Launch an installed game",
+	Long: `Launch a game installed with goggle using the play tasks GOG ships in
+its goggame-<id>.info file.
+
+The primary task is started unless --task names another one, such as a
+configuration tool or manual; --list shows them all. Games packaged with
+DOSBox or ScummVM are run with the native dosbox/scummvm on Linux and macOS,
+and other Windows programs through Wine.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := gog.LoadRegistry()
+		if err != nil {
+			return err
+		}
+		game, err := reg.Find(args[0], runDir)
+		if err != nil {
+			return err
+		}
+		info, err := gog.FindGameInfo(game.Dir)
+		if err != nil {
+			return err
+		}
+
+		if runList {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "TASK\tCATEGORY\tTARGET")
+			for _, t := range info.PlayTasks {
+				name, target := t.Name, t.Path
+				if t.IsPrimary {
+					name += " (primary)"
+				}
+				if target == "" {
+					target = t.Link
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", name, t.Category, target)
+			}
+			return tw.Flush()
+		}
+
+		task, err := info.Task(runTask)
+		if err != nil {
+			return err
+		}
+		launch, err := task.Command(info.Dir, runtime.GOOS, exec.LookPath)
+		if err != nil {
+			return err
+		}
+		if launch.URL != "" {
+			fmt.Printf("Opening %s...\n", launch.URL)
+			return openURL(launch.URL)
+		}
+
+		fmt.Printf("Launching %s...\n", task.Name)
+		proc := exec.Command(launch.Path, launch.Args...)
+		proc.Dir = launch.Dir
+		proc.Stdin, proc.Stdout, proc.Stderr = os.Stdin, os.Stdout, os.Stderr
+		return proc.Run()
+	},
+}
+
+// openURL opens url in the default browser, or a document's path in the
+// application registered for it.
+func openURL(url string) error {
+	var c *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		c = exec.Command("open", url)
+	case "windows":
+		c = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		c = exec.Command("xdg-open", url)
+	}
+	return c.Start()
+}
+
+func init() {
+	runCmd.Flags().StringVar(&runTask, "task", "", "Play task to launch (e.g. a config tool). Defaults to the primary task.")
+	runCmd.Flags().BoolVar(&runList, "list", false, "List the game's play tasks")
+	runCmd.Flags().StringVar(&runDir, "dir", "", "Directory of the install to use, for games installed more than once")
+	rootCmd.AddCommand(runCmd)
+}
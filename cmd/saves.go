@@ -0,0 +1,260 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/cloud"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	savesWinePrefix string
+	savesDir        string
+	savesOnConflict string
+	savesDryRun     bool
+	savesJSON       bool
+)
+
+var savesCmd = &cobra.Command{
+	Use:   "saves",
+	Short: "List, download and upload a game's cloud saves",
+	Long: `Sync the cloud saves GOG keeps for an installed game, the ones Galaxy would
+sync, without Galaxy.
+
+The save locations the game declares are placed relative to its install
+directory and your home directory. For Windows games run through Wine, pass
+--wine-prefix so folders such as Saved Games and AppData point into the
+prefix.
+
+Files are compared by hash, then by modification time. A pull or push that
+would overwrite a newer file is a conflict: by default you are asked about
+each one, --on-conflict skip leaves them alone and --on-conflict overwrite
+replaces them. --dry-run doesn't ask; it reports conflicts as such.`,
+}
+
+var savesListCmd = &cobra.Command{
+	Use:   "list <game>",
+	Short: "Compare local saves with the cloud",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, game, comps, err := compareSaves(args[0])
+		if err != nil {
+			return err
+		}
+		if savesJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if comps == nil {
+				comps = []cloud.Comparison{}
+			}
+			return enc.Encode(comps)
+		}
+		if len(comps) == 0 {
+			fmt.Printf("%s has no saves locally or in the cloud.\n", game.Title)
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FILE\tSTATUS\tSIZE\tLOCAL\tCLOUD")
+		for _, c := range comps {
+			size, local, remote := int64(0), "-", "-"
+			if c.Local != nil {
+				size, local = c.Local.Size, c.Local.Modified.Local().Format("2006-01-02 15:04")
+			}
+			if c.Remote != nil {
+				size, remote = c.Remote.Size, c.Remote.Modified.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.Status, gog.FormatSize(size), local, remote)
+		}
+		return tw.Flush()
+	},
+}
+
+var savesPullCmd = &cobra.Command{
+	Use:   "pull <game>",
+	Short: "Download cloud saves that are missing or older locally",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return syncSaves(args[0], true)
+	},
+}
+
+var savesPushCmd = &cobra.Command{
+	Use:   "push <game>",
+	Short: "Upload local saves that are missing or older in the cloud",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return syncSaves(args[0], false)
+	},
+}
+
+// saveSession is an installed game's cloud storage and where its save
+// locations are on disk.
+type saveSession struct {
+	client *cloud.Client
+	dirs   map[string]string
+}
+
+// compareSaves opens the cloud storage of the installed game matching query
+// and compares it with the local saves.
+func compareSaves(query string) (*saveSession, *gog.InstalledGame, []cloud.Comparison, error) {
+	switch savesOnConflict {
+	case "ask", "skip", "overwrite":
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid --on-conflict %q (want ask, skip or overwrite)", savesOnConflict)
+	}
+	reg, err := gog.LoadRegistry()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	game, err := reg.Find(query, savesDir)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// The game's Galaxy client credentials come with its build manifest
+	targetOS := game.OS
+	if targetOS == "" {
+		targetOS = gog.DetectOS()
+	}
+	cc := content.NewClient(client)
+	builds, err := cc.Builds(game.ID, targetOS, game.BranchPassword)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	build, err := findBuild(builds, game.Build, game.Branch)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", game.Title, err)
+	}
+	manifest, err := cc.Manifest(build)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if manifest.ClientID == "" {
+		return nil, nil, nil, fmt.Errorf("%s has no cloud saves", game.Title)
+	}
+
+	s := &saveSession{client: cloud.NewClient(client, manifest.ClientID, manifest.ClientSecret), dirs: make(map[string]string)}
+	locations, err := s.client.Locations(targetOS)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("%s: %w", game.Title, err)
+	}
+	folders, err := cloud.DefaultFolders(game.Dir, targetOS, savesWinePrefix)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, l := range locations {
+		if s.dirs[l.Name], err = folders.Resolve(l.Path); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	remote, err := s.client.List()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	local, err := cloud.Scan(s.dirs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return s, game, cloud.Compare(local, remote), nil
+}
+
+func syncSaves(query string, pull bool) error {
+	s, game, comps, err := compareSaves(query)
+	if err != nil {
+		return err
+	}
+	plan, verb := cloud.PlanPush(comps), "Uploaded"
+	if pull {
+		plan, verb = cloud.PlanPull(comps), "Downloaded"
+	}
+	if len(plan) == 0 {
+		fmt.Printf("%s's saves are in sync.\n", game.Title)
+		return nil
+	}
+
+	done, skipped, conflicts := 0, 0, 0
+	for _, t := range plan {
+		// A dry run only reports what it would have asked about
+		if t.Conflict && savesDryRun && savesOnConflict == "ask" {
+			fmt.Printf("Would conflict %s (%s)\n", t.Name, t.Status)
+			conflicts++
+			continue
+		}
+		if t.Conflict && !overwriteConflict(t, pull) {
+			fmt.Printf("Skipped %s (%s)\n", t.Name, t.Status)
+			skipped++
+			continue
+		}
+		if savesDryRun {
+			fmt.Printf("Would transfer %s (%s)\n", t.Name, t.Status)
+			done++
+			continue
+		}
+		if pull {
+			path, err := cloud.LocalPath(s.dirs, t.Name)
+			if err != nil {
+				return err
+			}
+			err = s.client.Download(*t.Remote, path)
+		} else {
+			err = s.client.Upload(*t.Local)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("  %s\n", t.Name)
+		done++
+	}
+	if savesDryRun {
+		verb = "Would transfer"
+	}
+	fmt.Printf("%s %d files of %s", verb, done, game.Title)
+	if skipped > 0 {
+		fmt.Printf(", skipped %d conflicts", skipped)
+	}
+	if conflicts > 0 {
+		fmt.Printf(", %d would conflict", conflicts)
+	}
+	fmt.Println()
+	return nil
+}
+
+// overwriteConflict decides, following --on-conflict, whether a newer file
+// is replaced.
+func overwriteConflict(t cloud.Transfer, pull bool) bool {
+	switch savesOnConflict {
+	case "overwrite":
+		return true
+	case "skip":
+		return false
+	}
+	label := fmt.Sprintf("%s is newer locally; overwrite it with the cloud copy", t.Name)
+	if !pull {
+		label = fmt.Sprintf("%s is newer in the cloud; overwrite it with the local copy", t.Name)
+	}
+	prompt := promptui.Prompt{Label: label, IsConfirm: true}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+func init() {
+	savesCmd.PersistentFlags().StringVar(&savesDir, "dir", "", "Directory of the install to use, for games installed more than once")
+	savesCmd.PersistentFlags().StringVar(&savesWinePrefix, "wine-prefix", "", "Wine prefix a Windows game runs in")
+	savesCmd.PersistentFlags().StringVar(&savesOnConflict, "on-conflict", "ask", "What to do with newer files a transfer would overwrite (ask, skip, overwrite)")
+	savesPullCmd.Flags().BoolVarP(&savesDryRun, "dry-run", "n", false, "Show what would be transferred without doing it")
+	savesPushCmd.Flags().BoolVarP(&savesDryRun, "dry-run", "n", false, "Show what would be transferred without doing it")
+	savesListCmd.Flags().BoolVar(&savesJSON, "json", false, "Print the comparison as JSON")
+	savesCmd.AddCommand(savesListCmd, savesPullCmd, savesPushCmd)
+	rootCmd.AddCommand(savesCmd)
+}
@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	scanDryRun       bool
+	scanJSON         bool
+	scanSkipChecksum bool
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <dir>",
+	Short: "Find installers you already have and adopt them",
+	Long: `Look for GOG installers below a directory and match them to games in your
+library by file name, size and, where GOG publishes one, MD5 checksum.
+
+Current installers are registered as downloads, so 'goggle download' skips
+them. Older builds are registered too, so 'goggle outdated' and 'goggle
+upgrade' pick them up, provided their name or language shows which installer
+they belong to. Files that can't be matched, or whose size or checksum don't
+match, are only reported.
+
+Checksums mean reading every matched file; --skip-checksum compares names
+and sizes only.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, err := gog.FindInstallerFiles(args[0])
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Printf("No installers found in %s\n", args[0])
+			return nil
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		results, err := scanFiles(client, products, files)
+		if err != nil {
+			return err
+		}
+
+		if !scanDryRun {
+			reg, err := gog.LoadRegistry()
+			if err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.Status == gog.ScanCurrent || r.Status == gog.ScanOutdated {
+					d := r.Record()
+					d.DownloadedAt = modTime(r.Path)
+					reg.AddDownload(d)
+				}
+			}
+			if err := reg.Save(); err != nil {
+				return fmt.Errorf("failed to update downloads: %w", err)
+			}
+		}
+
+		if scanJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(results)
+		}
+		return printScan(results)
+	},
+}
+
+// scanFiles matches files to products, fetching the details of each game
+// once and resolving each installer's served file name at most once.
+func scanFiles(client *gog.Client, products []gog.Product, files []gog.LocalFile) ([]gog.ScanResult, error) {
+	var checksum func(string) (string, error)
+	if !scanSkipChecksum {
+		checksum = gog.FileMD5
+	}
+
+	installers := make(map[int][]gog.Installer)
+	resolved := make(map[string]gog.RemoteFile)
+	lookup := func(inst gog.Installer) (gog.RemoteFile, error) {
+		if rf, ok := resolved[inst.ManualURL]; ok {
+			return rf, nil
+		}
+		rf, err := client.RemoteFile(inst)
+		if err != nil {
+			return rf, fmt.Errorf("failed to look up %s: %w", inst.Name, err)
+		}
+		resolved[inst.ManualURL] = rf
+		return rf, nil
+	}
+
+	results := make([]gog.ScanResult, 0, len(files))
+	for _, f := range files {
+		game, version, ok := gog.MatchInstallerFile(products, filepath.Base(f.Path))
+		if !ok {
+			results = append(results, gog.ScanResult{Path: f.Path, Status: gog.ScanUnknown, Reason: "no matching game in your library"})
+			continue
+		}
+		if _, ok := installers[game.ID]; !ok {
+			fmt.Fprintf(os.Stderr, "Fetching details for %s...\n", game.Title)
+			details, err := client.GetGameDetails(game.ID)
+			if err != nil {
+				return nil, err
+			}
+			if installers[game.ID], err = gog.ParseInstallers(details); err != nil {
+				return nil, err
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Checking %s...\n", filepath.Base(f.Path))
+		res, err := gog.MatchFile(f, game, version, installers[game.ID], lookup, checksum)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// modTime is used as the download time of adopted files.
+func modTime(path string) time.Time {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime()
+	}
+	return time.Now()
+}
+
+func printScan(results []gog.ScanResult) error {
+	counts := make(map[string]int)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tGAME\tFILE\tVERSION\tLATEST\tNOTE")
+	for _, r := range results {
+		counts[r.Status]++
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Status, orDash(r.Title), r.Path, orDash(r.Version), orDash(r.Latest), r.Reason)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d current, %d outdated, %d mismatched, %d unknown\n",
+		counts[gog.ScanCurrent], counts[gog.ScanOutdated], counts[gog.ScanMismatch], counts[gog.ScanUnknown])
+	return nil
+}
+
+func init() {
+	scanCmd.Flags().BoolVar(&scanDryRun, "dry-run", false, "Report matches without registering them")
+	scanCmd.Flags().BoolVar(&scanJSON, "json", false, "Print the results as JSON")
+	scanCmd.Flags().BoolVar(&scanSkipChecksum, "skip-checksum", false, "Match by name and size only")
+	rootCmd.AddCommand(scanCmd)
+}
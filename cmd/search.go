@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	searchJSON     bool
+	searchLimit    int
+	searchCountry  string
+	searchCurrency string
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search the GOG store",
+	Long: `Search the public GOG catalog and show price, discount and supported systems
+for each match. When logged in, games you already own are marked.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		loggedIn := err == nil
+		if !loggedIn {
+			fmt.Fprintf(os.Stderr, "Warning: %v; owned games won't be marked\n", err)
+			client = &gog.Client{HTTPClient: &http.Client{}}
+		}
+
+		products, err := client.SearchCatalog(strings.Join(args, " "), gog.CatalogOptions{
+			Limit:    searchLimit,
+			Country:  searchCountry,
+			Currency: searchCurrency,
+		})
+		if err != nil {
+			return err
+		}
+		if loggedIn {
+			owned, err := client.GetOwnedGameIDs()
+			if err != nil {
+				return err
+			}
+			gog.MarkOwned(products, owned)
+		}
+
+		if searchJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(products)
+		}
+		if len(products) == 0 {
+			fmt.Println("No matches.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTITLE\tTYPE\tPRICE\tOS\tOWNED")
+		for _, p := range products {
+			owned := ""
+			if p.Owned {
+				owned = "✔"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", p.ID, p.Title, p.Type, p.Price, strings.Join(p.OS, ", "), owned)
+		}
+		return tw.Flush()
+	},
+}
+
+func init() {
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Print the results as JSON")
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 20, "Maximum number of results")
+	searchCmd.Flags().StringVar(&searchCountry, "country", "US", "Country to show prices for (ISO code)")
+	searchCmd.Flags().StringVar(&searchCurrency, "currency", "USD", "Currency to show prices in (ISO code)")
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsFormat   string
+	statsOutput   string
+	statsTop      int
+	statsPlaytime bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show statistics about your GOG library",
+	Long: `Show statistics about your GOG library: platforms, languages, release years
+and the largest games.
+
+With --playtime, show how long you have played each game instead, with your
+last session and unlocked achievements, as GOG Galaxy records them. That
+report can also be exported with --format csv.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formats := "table, json or html"
+		if statsPlaytime {
+			formats = "table, json or csv"
+		}
+		switch {
+		case statsFormat == "table", statsFormat == "json":
+		case statsFormat == "html" && !statsPlaytime, statsFormat == "csv" && statsPlaytime:
+		default:
+			return fmt.Errorf("unknown format %q (want %s)", statsFormat, formats)
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		if statsPlaytime {
+			return playtimeReport(client)
+		}
+
+		fmt.Fprintln(os.Stderr, "Fetching library...")
+		ids, err := ownedGameIDs(client)
+		if err != nil {
+			return err
+		}
+
+		games := make([]gog.GameInfo, 0, len(ids))
+		for i, id := range ids {
+			fmt.Fprintf(os.Stderr, "\r  %d / %d games", i+1, len(ids))
+			info, err := gameInfo(client, id)
+			if err != nil {
+				// One game GOG can't describe shouldn't cost the whole report
+				fmt.Fprintf(os.Stderr, "\nWarning: skipping game %d: %v\n", id, err)
+				continue
+			}
+			games = append(games, info)
+		}
+		fmt.Fprintln(os.Stderr)
+
+		stats := gog.ComputeStats(games, statsTop)
+
+		out := io.Writer(os.Stdout)
+		if statsOutput != "" {
+			f, err := os.Create(statsOutput)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			out = f
+		}
+
+		switch statsFormat {
+		case "json":
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(stats)
+		case "html":
+			return statsHTML.Execute(out, stats)
+		default:
+			return writeStatsTable(out, stats)
+		}
+	},
+}
+
+// gameInfo fetches what the library report needs to know about a game.
+func gameInfo(client *gog.Client, id int) (gog.GameInfo, error) {
+	details, err := client.GetProductDetails(id)
+	if err != nil {
+		return gog.GameInfo{}, err
+	}
+	info := gog.GameInfo{Details: details}
+	gameDetails, err := client.GetGameDetails(id)
+	if err != nil {
+		return gog.GameInfo{}, err
+	}
+	if info.Installers, err = gog.ParseInstallers(gameDetails); err != nil {
+		return gog.GameInfo{}, err
+	}
+	return info, nil
+}
+
+func playtimeReport(client *gog.Client) error {
+	user, err := client.GetUserData()
+	if err != nil {
+		return err
+	}
+	playtime, err := client.GetPlaytime(user.Username)
+	if err != nil {
+		return err
+	}
+
+	out := io.Writer(os.Stdout)
+	if statsOutput != "" {
+		f, err := os.Create(statsOutput)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	if statsFormat != "table" {
+		return gog.WritePlaytime(out, playtime, statsFormat)
+	}
+
+	total := 0
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GAME\tPLAYTIME\tLAST PLAYED\tACHIEVEMENTS")
+	for _, p := range playtime {
+		last := "-"
+		if p.LastSession != nil {
+			last = p.LastSession.Local().Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", p.Title, gog.FormatPlaytime(p.Minutes), last, p.Achievements)
+		total += p.Minutes
+	}
+	fmt.Fprintf(tw, "\nTotal (%d games)\t%s\n", len(playtime), gog.FormatPlaytime(total))
+	return tw.Flush()
+}
+
+func writeStatsTable(out io.Writer, stats *gog.LibraryStats) error {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Games\t%d\n", stats.Games)
+	fmt.Fprintf(tw, "Total size\t%s\n\n", gog.FormatSize(stats.TotalSize))
+
+	fmt.Fprintln(tw, "PLATFORM\tGAMES\tINSTALLER SIZE")
+	for _, osName := range []string{"windows", "mac", "linux"} {
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", osName, stats.Platforms[osName], gog.FormatSize(stats.SizeByOS[osName]))
+	}
+
+	fmt.Fprintln(tw, "\nLANGUAGE\tGAMES")
+	for _, lc := range stats.Languages {
+		fmt.Fprintf(tw, "%s (%s)\t%d\n", lc.Name, lc.Code, lc.Games)
+	}
+
+	fmt.Fprintln(tw, "\nYEAR\tGAMES")
+	for _, yc := range stats.ReleaseYears {
+		fmt.Fprintf(tw, "%d\t%d\t%s\n", yc.Year, yc.Games, strings.Repeat("█", yc.Games))
+	}
+
+	fmt.Fprintln(tw, "\nLARGEST\tSIZE")
+	for _, g := range stats.Largest {
+		fmt.Fprintf(tw, "%s\t%s\n", g.Title, gog.FormatSize(g.Bytes))
+	}
+
+	return tw.Flush()
+}
+
+var statsHTML = template.Must(template.New("stats").Funcs(template.FuncMap{
+	"size": gog.FormatSize,
+	// barWidth is the width in pixels of a release year's bar
+	"barWidth": func(games int) int { return games * 10 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>GOG Library Statistics</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.bar { background: #86328a; height: 1em; }
+</style>
+</head>
+<body>
+<h1>GOG Library Statistics</h1>
+<p>{{ .Games }} games, {{ size .TotalSize }} of installers.</p>
+
+<h2>Platforms</h2>
+<table>
+<tr><th>Platform</th><th>Games</th><th>Installer size</th></tr>
+{{ range $os, $n := .Platforms }}<tr><td>{{ $os }}</td><td>{{ $n }}</td><td>{{ size (index $.SizeByOS $os) }}</td></tr>
+{{ end }}</table>
+
+<h2>Languages</h2>
+<table>
+<tr><th>Language</th><th>Code</th><th>Games</th></tr>
+{{ range .Languages }}<tr><td>{{ .Name }}</td><td>{{ .Code }}</td><td>{{ .Games }}</td></tr>
+{{ end }}</table>
+
+<h2>Release years</h2>
+<table>
+<tr><th>Year</th><th>Games</th><th></th></tr>
+{{ range .ReleaseYears }}<tr><td>{{ .Year }}</td><td>{{ .Games }}</td><td><div class="bar" style="width: {{ barWidth .Games }}px"></div></td></tr>
+{{ end }}</table>
+
+<h2>Largest games</h2>
+<table>
+<tr><th>Title</th><th>Size</th></tr>
+{{ range .Largest }}<tr><td>{{ .Title }}</td><td>{{ size .Bytes }}</td></tr>
+{{ end }}</table>
+</body>
+</html>
+`))
+
+func init() {
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "Output format (table, json, html; csv with --playtime)")
+	statsCmd.Flags().StringVarP(&statsOutput, "output", "o", "", "Write the report to a file instead of stdout")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "Number of largest games to show")
+	statsCmd.Flags().BoolVar(&statsPlaytime, "playtime", false, "Report time played per game instead")
+	rootCmd.AddCommand(statsCmd)
+}
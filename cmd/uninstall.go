@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/manifoldco/promptui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	uninstallYes bool
+	uninstallDir string
+)
+
+var uninstallCmd = &cobra.Command{
+	Use:   "uninstall <game>",
+	Short: "Remove a game installed with goggle",
+	Long: `Remove a game installed with goggle, given its ID or title.
+
+Only the files goggle installed are deleted; saves or mods added to the game
+directory afterwards are left in place. Downloaded installers are kept. A game
+installed in several directories needs --dir to say which copy to remove.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := gog.LoadRegistry()
+		if err != nil {
+			return err
+		}
+		game, err := reg.Find(args[0], uninstallDir)
+		if err != nil {
+			return err
+		}
+
+		if !uninstallYes {
+			prompt := promptui.Prompt{
+				Label:     fmt.Sprintf("Remove %s from %s", game.Title, game.Dir),
+				IsConfirm: true,
+			}
+			if _, err := prompt.Run(); err != nil {
+				return fmt.Errorf("uninstall cancelled")
+			}
+		}
+
+		if err := game.RemoveFiles(); err != nil {
+			return err
+		}
+		title, count := game.Title, len(game.Files)
+		reg.Remove(*game)
+		if err := reg.Save(); err != nil {
+			return fmt.Errorf("failed to update installed games: %w", err)
+		}
+		fmt.Printf("Removed %s (%d files)\n", title, count)
+		return nil
+	},
+}
+
+func init() {
+	uninstallCmd.Flags().BoolVarP(&uninstallYes, "yes", "y", false, "Don't ask for confirmation")
+	uninstallCmd.Flags().StringVar(&uninstallDir, "dir", "", "Directory of the install to use, for games installed more than once")
+	rootCmd.AddCommand(uninstallCmd)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	updatesJSON  bool
+	updatesLines int
+)
+
+var updatesCmd = &cobra.Command{
+	Use:   "updates [game...]",
+	Short: "List library games that changed since the last check",
+	Long: `Check the installer versions of every game in your library (or just the
+games named) against the versions seen the last time this command ran, and
+list the ones GOG updated with the newest changelog entry.
+
+The first run only records the current versions.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		seen, err := gog.LoadSeenVersions()
+		if err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchLibrary(client)
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			if products, err = matchGames(products, args); err != nil {
+				return err
+			}
+		}
+
+		firstRun := len(seen.Games) == 0
+		now := time.Now()
+		changes := []gog.VersionChange{}
+		for _, p := range products {
+			fmt.Fprintf(os.Stderr, "Checking %s...\n", p.Title)
+			details, err := client.GetGameDetails(p.ID)
+			if err != nil {
+				return err
+			}
+			installers, err := gog.ParseInstallers(details)
+			if err != nil {
+				return err
+			}
+			changed := seen.Update(p, gog.InstallerVersions(installers), now)
+			excerpt := changelogExcerpt(renderChangelog(details.Changelog), updatesLines)
+			for _, c := range changed {
+				c.Changelog = excerpt
+				changes = append(changes, c)
+			}
+		}
+		if err := seen.Save(); err != nil {
+			return fmt.Errorf("failed to save seen versions: %w", err)
+		}
+
+		if updatesJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(changes)
+		}
+		switch {
+		case firstRun:
+			fmt.Printf("Recorded versions of %d games. Run again later to see what changed.\n", len(products))
+		case len(changes) == 0:
+			fmt.Println("No updates since the last check.")
+		default:
+			printVersionChanges(changes)
+		}
+		return nil
+	},
+}
+
+// printVersionChanges lists changes grouped by game, each followed by its
+// changelog excerpt.
+func printVersionChanges(changes []gog.VersionChange) {
+	for i := 0; i < len(changes); {
+		c := changes[i]
+		var versions []string
+		for ; i < len(changes) && changes[i].GameID == c.GameID; i++ {
+			versions = append(versions, fmt.Sprintf("%s %s -> %s", changes[i].OS, changes[i].Previous, changes[i].Current))
+		}
+		fmt.Printf("%s (%s)\n", c.Title, strings.Join(versions, ", "))
+		if c.Changelog != "" {
+			for _, line := range strings.Split(c.Changelog, "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+		fmt.Println()
+	}
+}
+
+func init() {
+	updatesCmd.Flags().BoolVar(&updatesJSON, "json", false, "Print the changes as JSON")
+	updatesCmd.Flags().IntVar(&updatesLines, "lines", 8, "Changelog lines to show per game")
+	rootCmd.AddCommand(updatesCmd)
+}
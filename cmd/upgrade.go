@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upgradePrune       bool
+	upgradeIgnoreSpace bool
+)
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [game...]",
+	Short: "Download and apply newer versions of installed games and downloads",
+	Long: `Download and apply newer versions of games listed by 'goggle outdated'.
+
+Installed games are re-extracted in place with the same OS and language, and
+files the new version no longer ships are removed. Downloaded installers are
+fetched again next to the old ones.
+
+Old installers are kept unless --prune is given.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, err := gog.LoadRegistry()
+		if err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		updates, err := gog.FindUpdates(reg, fetchInstallers(client), latestBuilds(client))
+		if err != nil {
+			return err
+		}
+		if updates, err = filterUpdates(updates, args); err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			fmt.Println("Everything is up to date.")
+			return nil
+		}
+
+		for _, u := range updates {
+			fmt.Printf("Upgrading %s (%s) from %s to %s...\n", u.Title, u.Kind, u.Current, u.Latest)
+			switch u.Kind {
+			case gog.UpdateInstall:
+				err = upgradeInstall(client, reg, u)
+			case gog.UpdateDownload:
+				err = upgradeDownload(client, reg, u)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to upgrade %s: %w", u.Title, err)
+			}
+			if err := reg.Save(); err != nil {
+				return fmt.Errorf("failed to update installed games: %w", err)
+			}
+		}
+		return nil
+	},
+}
+
+// filterUpdates keeps the updates for the games named in queries.
+func filterUpdates(updates []gog.Update, queries []string) ([]gog.Update, error) {
+	if len(queries) == 0 {
+		return updates, nil
+	}
+	var products []gog.Product
+	seen := make(map[int]bool)
+	for _, u := range updates {
+		if !seen[u.GameID] {
+			seen[u.GameID] = true
+			products = append(products, gog.Product{ID: u.GameID, Title: u.Title})
+		}
+	}
+	wanted := make(map[int]bool)
+	for _, q := range queries {
+		p, err := gog.MatchProduct(products, q)
+		if err != nil {
+			return nil, fmt.Errorf("%q has no update: %w", q, err)
+		}
+		wanted[p.ID] = true
+	}
+	var filtered []gog.Update
+	for _, u := range updates {
+		if wanted[u.GameID] {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered, nil
+}
+
+func upgradeInstall(client *gog.Client, reg *gog.Registry, u gog.Update) error {
+	old := reg.InDir(u.Location)
+	if old == nil {
+		return errors.New("install no longer registered")
+	}
+	prev := *old
+
+	opts := installOptions{OS: prev.OS, Dir: prev.Dir, IgnoreSpace: upgradeIgnoreSpace, Content: prev.Build != "", Previous: &prev,
+		Branch: prev.Branch, BranchPassword: prev.BranchPassword}
+	if prev.Language != "" {
+		opts.Langs = []string{prev.Language}
+	}
+	if len(prev.Installers) > 0 {
+		opts.Dest = filepath.Dir(prev.Installers[0])
+	}
+	game, err := installProduct(client, reg, gog.Product{ID: prev.ID, Title: prev.Title}, opts)
+	if err != nil {
+		return err
+	}
+
+	// Drop files the new version no longer ships
+	stale := gog.InstalledGame{Dir: prev.Dir, Files: missingFrom(prev.Files, game.Files)}
+	if err := stale.RemoveFiles(); err != nil {
+		return err
+	}
+	if err := gog.WriteManifest(game); err != nil {
+		return err
+	}
+	reg.Add(game)
+
+	if upgradePrune {
+		pruneFiles(missingFrom(prev.Installers, game.Installers))
+	}
+	fmt.Printf("Done! %s is now at %s\n", game.Title, game.Version)
+	return nil
+}
+
+func upgradeDownload(client *gog.Client, reg *gog.Registry, u gog.Update) error {
+	details, err := client.GetGameDetails(u.GameID)
+	if err != nil {
+		return err
+	}
+	installers, err := gog.ParseInstallers(details)
+	if err != nil {
+		return err
+	}
+
+	plan := gog.NewPlan(gog.PlanOptions{OS: u.OS, Dest: filepath.Dir(u.Location)})
+	if u.Language != "" {
+		plan.Languages = []string{u.Language}
+	}
+	plan.AddGame(gog.Product{ID: u.GameID, Title: u.Title}, installers)
+	pending := plan.Pending()
+	if len(pending) == 0 {
+		return fmt.Errorf("%s", plan.Items[0].Skip)
+	}
+
+	var fresh []string
+	for _, item := range pending {
+		path, err := downloadItem(client, item, upgradeIgnoreSpace)
+		if err != nil {
+			return err
+		}
+		if err := recordDownload(reg, item, path); err != nil {
+			return err
+		}
+		abs, _ := filepath.Abs(path)
+		fresh = append(fresh, abs)
+	}
+
+	if upgradePrune {
+		var old []string
+		for _, d := range reg.Downloads {
+			if d.GameID == u.GameID && d.OS == u.OS && d.Language == u.Language && d.Version == u.Current {
+				old = append(old, d.Path)
+			}
+		}
+		old = missingFrom(old, fresh)
+		pruneFiles(old)
+		for _, p := range old {
+			reg.RemoveDownload(p)
+		}
+	}
+	return nil
+}
+
+// missingFrom returns the entries of old that are not in current.
+func missingFrom(old, current []string) []string {
+	keep := make(map[string]bool, len(current))
+	for _, c := range current {
+		keep[c] = true
+	}
+	var missing []string
+	for _, o := range old {
+		if !keep[o] {
+			missing = append(missing, o)
+		}
+	}
+	return missing
+}
+
+// pruneFiles deletes superseded installers, warning about any it can't.
+func pruneFiles(paths []string) {
+	for _, p := range paths {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to remove %s: %v\n", p, err)
+			continue
+		}
+		fmt.Printf("Removed old installer %s\n", filepath.Base(p))
+	}
+}
+
+func init() {
+	upgradeCmd.Flags().BoolVar(&upgradePrune, "prune", false, "Delete the installers replaced by the upgrade")
+	upgradeCmd.Flags().BoolVar(&upgradeIgnoreSpace, "ignore-space", false, "Download even if the destination looks too small")
+	rootCmd.AddCommand(upgradeCmd)
+}
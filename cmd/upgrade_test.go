@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+func TestMissingFrom(t *testing.T) {
+	got := missingFrom([]string{"a", "b", "c"}, []string{"b", "d"})
+	if want := []string{"a", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("missingFrom() = %v, want %v", got, want)
+	}
+	if got := missingFrom(nil, []string{"a"}); got != nil {
+		t.Errorf("missingFrom(nil) = %v, want nil", got)
+	}
+}
+
+func TestFilterUpdates(t *testing.T) {
+	updates := []gog.Update{
+		{GameID: 1, Title: "Witcher", Kind: gog.UpdateInstall},
+		{GameID: 1, Title: "Witcher", Kind: gog.UpdateDownload},
+		{GameID: 2, Title: "Baldur's Gate", Kind: gog.UpdateInstall},
+	}
+
+	got, err := filterUpdates(updates, nil)
+	if err != nil || len(got) != 3 {
+		t.Errorf("no queries: got %d updates, %v", len(got), err)
+	}
+
+	got, err = filterUpdates(updates, []string{"witcher"})
+	if err != nil {
+		t.Fatalf("filterUpdates: %v", err)
+	}
+	if len(got) != 2 || got[0].GameID != 1 || got[1].GameID != 1 {
+		t.Errorf("got %+v", got)
+	}
+
+	if _, err := filterUpdates(updates, []string{"doom"}); err == nil {
+		t.Error("expected error for game without update")
+	}
+}
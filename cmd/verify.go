@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/josh/goggle/pkg/gog/content"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyJSON bool
+	verifyDir  string
+	repairDir  string
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <game>",
+	Short: "Check an installed game's files against its build",
+	Long: `Hash every file of a game installed with --content or --build and compare it
+with the manifest of the installed build. Missing and modified files are
+listed, as are extra files the build doesn't ship, such as saves and mods.
+
+Exits with status 1 when files are missing or modified; fix them with
+'goggle repair'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_, game, _, report, err := verifyGame(args[0], verifyDir)
+		if err != nil {
+			return err
+		}
+		if verifyJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(report); err != nil {
+				return err
+			}
+		} else {
+			printReport(report)
+		}
+		if report.OK() {
+			return nil
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%s has %d missing and %d modified files", game.Title, len(report.Missing), len(report.Modified))
+	},
+}
+
+var repairCmd = &cobra.Command{
+	Use:   "repair <game>",
+	Short: "Download missing and modified files of an installed game again",
+	Long: `Verify a game installed with --content or --build like 'goggle verify', then
+download just the missing and modified files again. Extra files are left
+alone.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reg, game, cc, report, err := verifyGame(args[0], repairDir)
+		if err != nil {
+			return err
+		}
+		if report.OK() {
+			fmt.Printf("All %d files of %s are intact.\n", report.Checked, game.Title)
+			return nil
+		}
+		fmt.Printf("Repairing %d missing and %d modified files...\n", len(report.Missing), len(report.Modified))
+		repaired, err := cc.Repair(report, game.Dir, content.DownloadOptions{
+			Progress: func(done, total int64) {
+				fmt.Fprintf(os.Stderr, "\r  %s / %s", gog.FormatSize(done), gog.FormatSize(total))
+			},
+		})
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return err
+		}
+		// Files lost before goggle tracked them are tracked again
+		game.Files = append(game.Files, missingFrom(repaired, game.Files)...)
+		if err := gog.WriteManifest(*game); err != nil {
+			return err
+		}
+		if err := reg.Save(); err != nil {
+			return fmt.Errorf("failed to update installed games: %w", err)
+		}
+		fmt.Printf("Done! Repaired %d files of %s\n", len(repaired), game.Title)
+		return nil
+	},
+}
+
+// verifyGame checks the install of query in dir, if given, against its
+// build.
+func verifyGame(query, dir string) (*gog.Registry, *gog.InstalledGame, *content.Client, *content.Report, error) {
+	reg, err := gog.LoadRegistry()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	game, err := reg.Find(query, dir)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if game.Build == "" {
+		return nil, nil, nil, nil, errors.New(game.Title + " was installed from an installer; reinstall it with --content to verify it")
+	}
+	client, err := newClient()
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	cc := content.NewClient(client)
+	fmt.Fprintf(os.Stderr, "Fetching build %s of %s...\n", game.Build, game.Title)
+	builds, err := cc.Builds(game.ID, game.OS, game.BranchPassword)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	entries, err := installedFiles(cc, builds, *game)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	total, checked := 0, 0
+	for _, e := range entries {
+		if e.Type != content.ItemDirectory {
+			total++
+		}
+	}
+	report, err := content.Verify(entries, game.Dir, func(string) {
+		checked++
+		fmt.Fprintf(os.Stderr, "\r  %d / %d files", checked, total)
+	})
+	fmt.Fprintln(os.Stderr)
+	return reg, game, cc, report, err
+}
+
+func printReport(r *content.Report) {
+	for _, p := range r.Missing {
+		fmt.Printf("missing   %s\n", p)
+	}
+	for _, p := range r.Modified {
+		fmt.Printf("modified  %s\n", p)
+	}
+	for _, p := range r.Extra {
+		fmt.Printf("extra     %s\n", p)
+	}
+	fmt.Printf("Checked %d files: %d missing, %d modified, %d extra.\n", r.Checked, len(r.Missing), len(r.Modified), len(r.Extra))
+}
+
+func init() {
+	verifyCmd.Flags().BoolVar(&verifyJSON, "json", false, "Print the report as JSON")
+	verifyCmd.Flags().StringVar(&verifyDir, "dir", "", "Directory of the install to use, for games installed more than once")
+	repairCmd.Flags().StringVar(&repairDir, "dir", "", "Directory of the install to use, for games installed more than once")
+	rootCmd.AddCommand(verifyCmd, repairCmd)
+}
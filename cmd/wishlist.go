@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/josh/goggle/pkg/gog"
+	"github.com/spf13/cobra"
+)
+
+var (
+	wishlistJSON   bool
+	wishlistFormat string
+	wishlistOutput string
+)
+
+var wishlistCmd = &cobra.Command{
+	Use:   "wishlist",
+	Short: "Show and manage your GOG wishlist",
+	Long: `List the products on your GOG wishlist.
+
+Use the subcommands to add or remove products by ID, slug or store page URL,
+and to export the wishlist as JSON or CSV and import it into another account.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchWishlist(client)
+		if err != nil {
+			return err
+		}
+		if wishlistJSON {
+			return gog.WriteProducts(os.Stdout, products, "json")
+		}
+		if len(products) == 0 {
+			fmt.Println("Your wishlist is empty.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tTITLE\tSLUG")
+		for _, p := range products {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", p.ID, p.Title, orDash(p.Slug))
+		}
+		return tw.Flush()
+	},
+}
+
+var wishlistAddCmd = &cobra.Command{
+	Use:   "add <id|slug|url>...",
+	Short: "Add products to your wishlist",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		for _, ref := range args {
+			p, err := resolveProductRef(client, ref)
+			if err != nil {
+				return err
+			}
+			if err := client.AddToWishlist(p.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Added %s to your wishlist\n", productName(p))
+		}
+		return nil
+	},
+}
+
+var wishlistRemoveCmd = &cobra.Command{
+	Use:   "remove <id|slug|url|title>...",
+	Short: "Remove products from your wishlist",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchWishlist(client)
+		if err != nil {
+			return err
+		}
+		for _, ref := range args {
+			p, err := matchWishlist(products, ref)
+			if err != nil {
+				return err
+			}
+			if err := client.RemoveFromWishlist(p.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Removed %s from your wishlist\n", productName(p))
+		}
+		return nil
+	},
+}
+
+var wishlistExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export your wishlist as JSON or CSV",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := listFormat(wishlistFormat, wishlistOutput)
+		if err != nil {
+			return err
+		}
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		products, err := fetchWishlist(client)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if wishlistOutput != "" {
+			f, err := os.Create(wishlistOutput)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = f.Close() }()
+			w = f
+		}
+		if err := gog.WriteProducts(w, products, format); err != nil {
+			return err
+		}
+		if wishlistOutput != "" {
+			fmt.Fprintf(os.Stderr, "Exported %d products to %s\n", len(products), wishlistOutput)
+		}
+		return nil
+	},
+}
+
+var wishlistImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Add the products of an exported wishlist to yours",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, err := listFormat(wishlistFormat, args[0])
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		entries, err := gog.ReadProducts(f, format)
+		if err != nil {
+			return err
+		}
+
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+		current, err := client.GetWishlist()
+		if err != nil {
+			return err
+		}
+		have := make(map[int]bool, len(current))
+		for _, id := range current {
+			have[id] = true
+		}
+
+		added := 0
+		for _, e := range entries {
+			p := e
+			if p.ID == 0 {
+				if p.Slug == "" {
+					continue
+				}
+				found, err := client.FindProductBySlug(p.Slug)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", productName(e), err)
+					continue
+				}
+				p = found
+			}
+			if have[p.ID] {
+				continue
+			}
+			if err := client.AddToWishlist(p.ID); err != nil {
+				return err
+			}
+			have[p.ID] = true
+			added++
+			fmt.Printf("Added %s\n", productName(p))
+		}
+		fmt.Printf("Done! Added %d of %d products\n", added, len(entries))
+		return nil
+	},
+}
+
+// fetchWishlist returns the wishlisted products sorted by title.
+func fetchWishlist(client *gog.Client) ([]gog.Product, error) {
+	fmt.Fprintln(os.Stderr, "Fetching wishlist...")
+	ids, err := client.GetWishlist()
+	if err != nil {
+		return nil, err
+	}
+	products, err := client.GetProducts(ids)
+	if err != nil {
+		return nil, err
+	}
+	sortProducts(products)
+	return products, nil
+}
+
+// resolveProductRef finds the product an ID, slug or store URL refers to.
+func resolveProductRef(client *gog.Client, ref string) (gog.Product, error) {
+	id, slug := gog.ParseProductRef(ref)
+	if id != 0 {
+		return gog.Product{ID: id}, nil
+	}
+	return client.FindProductBySlug(slug)
+}
+
+// matchWishlist finds ref among the wishlisted products by ID, slug, store
+// URL or title.
+func matchWishlist(products []gog.Product, ref string) (gog.Product, error) {
+	if _, slug := gog.ParseProductRef(ref); slug != "" {
+		for _, p := range products {
+			if strings.EqualFold(p.Slug, slug) {
+				return p, nil
+			}
+		}
+	}
+	return gog.MatchProductIn(products, ref, "on your wishlist")
+}
+
+// productName describes p for messages, whatever is known about it.
+func productName(p gog.Product) string {
+	switch {
+	case p.Title != "":
+		return p.Title
+	case p.Slug != "":
+		return p.Slug
+	default:
+		return fmt.Sprintf("product %d", p.ID)
+	}
+}
+
+// listFormat picks json or csv from the --format flag or the file
+// extension.
+func listFormat(flag, path string) (string, error) {
+	if flag == "" {
+		flag = "json"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			flag = "csv"
+		}
+	}
+	flag = strings.ToLower(flag)
+	if flag != "json" && flag != "csv" {
+		return "", fmt.Errorf("unknown format %q (use json or csv)", flag)
+	}
+	return flag, nil
+}
+
+func init() {
+	wishlistCmd.Flags().BoolVar(&wishlistJSON, "json", false, "Print the wishlist as JSON")
+	for _, c := range []*cobra.Command{wishlistExportCmd, wishlistImportCmd} {
+		c.Flags().StringVar(&wishlistFormat, "format", "", "json or csv. Defaults to the file extension, else json.")
+	}
+	wishlistExportCmd.Flags().StringVarP(&wishlistOutput, "output", "o", "", "Write to a file instead of stdout")
+	wishlistCmd.AddCommand(wishlistAddCmd, wishlistRemoveCmd, wishlistExportCmd, wishlistImportCmd)
+	rootCmd.AddCommand(wishlistCmd)
+}
@@ -0,0 +1,157 @@
+package gog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CatalogProduct is a store product from the public catalog API.
+type CatalogProduct struct {
+	ID          int      `json:"id"`
+	Title       string   `json:"title"`
+	Slug        string   `json:"slug"`
+	Type        string   `json:"type"` // game, pack or dlc
+	OS          []string `json:"os"`   // windows, mac, linux
+	ReleaseDate string   `json:"release_date,omitempty"`
+	Price       *Price   `json:"price,omitempty"` // nil if not for sale
+	StoreURL    string   `json:"store_url,omitempty"`
+	Owned       bool     `json:"owned"`
+}
+
+// Price is a store price in the currency the catalog was asked for.
+type Price struct {
+	Final    float64 `json:"final"`
+	Base     float64 `json:"base"`
+	Discount int     `json:"discount"` // percent off Base
+	Currency string  `json:"currency"`
+}
+
+// String formats p as "9.99 USD (-75%)".
+func (p *Price) String() string {
+	if p == nil {
+		return "-"
+	}
+	if p.Final == 0 {
+		return "free"
+	}
+	s := fmt.Sprintf("%.2f %s", p.Final, p.Currency)
+	if p.Discount > 0 {
+		s += fmt.Sprintf(" (-%d%%)", p.Discount)
+	}
+	return s
+}
+
+// CatalogOptions sets the region prices are given for.
+type CatalogOptions struct {
+	Limit    int    // default 20
+	Country  string // ISO country code, default US
+	Currency string // ISO currency code, default USD
+}
+
+type catalogResponse struct {
+	Products []catalogProduct `json:"products"`
+}
+
+type catalogProduct struct {
+	ID               string   `json:"id"`
+	Title            string   `json:"title"`
+	Slug             string   `json:"slug"`
+	ProductType      string   `json:"productType"`
+	OperatingSystems []string `json:"operatingSystems"`
+	ReleaseDate      string   `json:"releaseDate"`
+	StoreLink        string   `json:"storeLink"`
+	Price            *struct {
+		Discount   string `json:"discount"` // e.g. "-75%"
+		FinalMoney struct {
+			Amount   string `json:"amount"`
+			Currency string `json:"currency"`
+		} `json:"finalMoney"`
+		BaseMoney struct {
+			Amount string `json:"amount"`
+		} `json:"baseMoney"`
+	} `json:"price"`
+}
+
+// SearchCatalog searches the public store catalog. It doesn't need a login;
+// Owned is left false.
+func (c *Client) SearchCatalog(query string, opts CatalogOptions) ([]CatalogProduct, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.Country == "" {
+		opts.Country = "US"
+	}
+	if opts.Currency == "" {
+		opts.Currency = "USD"
+	}
+	q := url.Values{
+		"query":        {"like:" + query},
+		"limit":        {strconv.Itoa(opts.Limit)},
+		"order":        {"desc:score"},
+		"productType":  {"in:game,pack,dlc"},
+		"countryCode":  {strings.ToUpper(opts.Country)},
+		"currencyCode": {strings.ToUpper(opts.Currency)},
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Get(c.catalogURL() + "/v1/catalog?" + q.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("catalog search failed (%d): %s", resp.StatusCode, body)
+	}
+	var result catalogResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to parse catalog response: %w", err)
+	}
+
+	products := make([]CatalogProduct, 0, len(result.Products))
+	for _, raw := range result.Products {
+		p := CatalogProduct{
+			Title:       raw.Title,
+			Slug:        raw.Slug,
+			Type:        raw.ProductType,
+			ReleaseDate: raw.ReleaseDate,
+			StoreURL:    raw.StoreLink,
+		}
+		p.ID, _ = strconv.Atoi(raw.ID)
+		for _, osName := range raw.OperatingSystems {
+			if osName == "osx" {
+				osName = "mac"
+			}
+			p.OS = append(p.OS, osName)
+		}
+		if raw.Price != nil {
+			price := &Price{Currency: raw.Price.FinalMoney.Currency}
+			price.Final, _ = strconv.ParseFloat(raw.Price.FinalMoney.Amount, 64)
+			price.Base, _ = strconv.ParseFloat(raw.Price.BaseMoney.Amount, 64)
+			price.Discount, _ = strconv.Atoi(strings.Trim(raw.Price.Discount, "-% "))
+			p.Price = price
+		}
+		products = append(products, p)
+	}
+	return products, nil
+}
+
+// MarkOwned sets Owned on the products whose ID is in owned.
+func MarkOwned(products []CatalogProduct, owned []int) {
+	set := make(map[int]bool, len(owned))
+	for _, id := range owned {
+		set[id] = true
+	}
+	for i := range products {
+		products[i].Owned = set[products[i].ID]
+	}
+}
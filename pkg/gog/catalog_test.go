@@ -0,0 +1,71 @@
+package gog
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSearchCatalog(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/v1/catalog" || q.Get("query") != "like:witcher" || q.Get("countryCode") != "DE" || q.Get("currencyCode") != "EUR" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("catalog search should not send credentials")
+		}
+		fmt.Fprint(w, `{"pages": 1, "productCount": 2, "products": [
+			{"id": "1207664643", "title": "The Witcher 3", "slug": "the_witcher_3", "productType": "game",
+			 "operatingSystems": ["windows", "osx"], "releaseDate": "2015.05.19",
+			 "storeLink": "https://www.gog.com/en/game/the_witcher_3",
+			 "price": {"final": "€9.99", "base": "€39.99", "discount": "-75%",
+			           "finalMoney": {"amount": "9.99", "currency": "EUR"}, "baseMoney": {"amount": "39.99", "currency": "EUR"}}},
+			{"id": "1", "title": "Witcher Soundtrack", "slug": "ost", "productType": "dlc", "operatingSystems": [], "price": null}
+		]}`)
+	}))
+	defer ts.Close()
+
+	c := &Client{HTTPClient: ts.Client(), CatalogURL: ts.URL}
+	products, err := c.SearchCatalog("witcher", CatalogOptions{Country: "de", Currency: "eur"})
+	if err != nil {
+		t.Fatalf("SearchCatalog: %v", err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("got %d products, want 2", len(products))
+	}
+	w3 := products[0]
+	if w3.ID != 1207664643 || w3.Type != "game" || len(w3.OS) != 2 || w3.OS[1] != "mac" {
+		t.Errorf("product = %+v", w3)
+	}
+	want := Price{Final: 9.99, Base: 39.99, Discount: 75, Currency: "EUR"}
+	if w3.Price == nil || *w3.Price != want {
+		t.Errorf("price = %+v, want %+v", w3.Price, want)
+	}
+	if products[1].Price != nil {
+		t.Errorf("unpriced product has price %+v", products[1].Price)
+	}
+
+	MarkOwned(products, []int{1, 5})
+	if products[0].Owned || !products[1].Owned {
+		t.Errorf("owned = %v, %v", products[0].Owned, products[1].Owned)
+	}
+}
+
+func TestPriceString(t *testing.T) {
+	tests := []struct {
+		price *Price
+		want  string
+	}{
+		{nil, "-"},
+		{&Price{Final: 0, Base: 0, Currency: "USD"}, "free"},
+		{&Price{Final: 19.99, Base: 19.99, Currency: "USD"}, "19.99 USD"},
+		{&Price{Final: 4.99, Base: 19.99, Discount: 75, Currency: "EUR"}, "4.99 EUR (-75%)"},
+	}
+	for _, tt := range tests {
+		if got := tt.price.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
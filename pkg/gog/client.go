@@ -35,6 +35,9 @@ type Client struct {
 	Token        *Token
 	EmbedBaseURL string // default: "https://embed.gog.com"
 	APIBaseURL   string // default: "https://api.gog.com"
+	CatalogURL   string // default: "https://catalog.gog.com"
+	GameplayURL  string // default: "https://gameplay.gog.com"
+	Locale       string // language of product details, e.g. "de-DE"; default: English
 	TokenURL     string // default: TokenURL constant
 	TokenPath    string // default: ~/.config/goggle/token.json
 }
@@ -108,6 +111,20 @@ func (c *Client) apiBaseURL() string {
 	return "https://api.gog.com"
 }
 
+func (c *Client) catalogURL() string {
+	if c.CatalogURL != "" {
+		return c.CatalogURL
+	}
+	return "https://catalog.gog.com"
+}
+
+func (c *Client) gameplayURL() string {
+	if c.GameplayURL != "" {
+		return c.GameplayURL
+	}
+	return "https://gameplay.gog.com"
+}
+
 func (c *Client) tokenURL() string {
 	if c.TokenURL != "" {
 		return c.TokenURL
@@ -0,0 +1,238 @@
+// Package cloud reads and writes GOG cloud saves, which Galaxy keeps per
+// user and per game client ID.
+package cloud
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+// Client accesses the cloud storage of one game on behalf of a logged-in
+// gog.Client. Cloud storage needs a token issued to the game's own Galaxy
+// client, which Client obtains from the user's refresh token.
+type Client struct {
+	*gog.Client
+	GameClientID     string
+	GameClientSecret string
+	StorageURL       string // default: "https://cloudstorage.gog.com"
+	RemoteConfigURL  string // default: "https://remote-config.gog.com"
+
+	mu     sync.Mutex
+	token  string
+	userID string
+}
+
+func NewClient(c *gog.Client, clientID, clientSecret string) *Client {
+	return &Client{Client: c, GameClientID: clientID, GameClientSecret: clientSecret}
+}
+
+func (c *Client) storageURL() string {
+	if c.StorageURL != "" {
+		return c.StorageURL
+	}
+	return "https://cloudstorage.gog.com"
+}
+
+func (c *Client) remoteConfigURL() string {
+	if c.RemoteConfigURL != "" {
+		return c.RemoteConfigURL
+	}
+	return "https://remote-config.gog.com"
+}
+
+// File is a save file, named by its location and its path inside it, e.g.
+// "saves/slot1.sav".
+type File struct {
+	Name     string    `json:"name"`
+	Hash     string    `json:"hash"` // MD5
+	Size     int64     `json:"bytes"`
+	Modified time.Time `json:"last_modified"`
+}
+
+// Location is a directory a game keeps cloud-synced saves in. Path holds
+// placeholders such as <?SAVED_GAMES?>; see Folders.
+type Location struct {
+	Name string `json:"name"`
+	Path string `json:"location"`
+}
+
+// platformKeys are the names remote-config uses for each OS.
+var platformKeys = map[string]string{"windows": "Windows", "mac": "MacOSX"}
+
+// Locations returns where the game keeps its cloud saves on goos. It fails
+// if the game has no cloud saves there.
+func (c *Client) Locations(goos string) ([]Location, error) {
+	key, ok := platformKeys[goos]
+	if !ok {
+		return nil, fmt.Errorf("GOG has no cloud saves for %s games", goos)
+	}
+	var config struct {
+		Content map[string]struct {
+			CloudStorage struct {
+				Enabled   bool       `json:"enabled"`
+				Locations []Location `json:"locations"`
+			} `json:"cloudStorage"`
+		} `json:"content"`
+	}
+	u := fmt.Sprintf("%s/components/galaxy_client/clients/%s?component_version=2.0.45", c.remoteConfigURL(), c.GameClientID)
+	resp, err := c.HTTPClient.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to get cloud save settings (%d)", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, err
+	}
+	storage := config.Content[key].CloudStorage
+	if !storage.Enabled {
+		return nil, errors.New("the game has no cloud saves")
+	}
+	if len(storage.Locations) == 0 {
+		// Games without declared locations use Galaxy's own folder
+		var path string
+		if goos == "mac" {
+			path = "<?APPLICATION_SUPPORT?>/GOG.com/Galaxy/Applications/" + c.GameClientID + "/Storage"
+		} else {
+			path = "<?LOCAL_APPDATA?>/GOG.com/Galaxy/Applications/" + c.GameClientID + "/Storage/Shared/Files"
+		}
+		return []Location{{Name: "saves", Path: path}}, nil
+	}
+	return storage.Locations, nil
+}
+
+// auth returns a token for the game's client and the user's Galaxy ID.
+func (c *Client) auth() (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" {
+		return c.token, c.userID, nil
+	}
+	if c.Token.Expired() {
+		if err := c.RefreshAuth(); err != nil {
+			return "", "", err
+		}
+	}
+	tokenURL := c.TokenURL
+	if tokenURL == "" {
+		tokenURL = gog.TokenURL
+	}
+	q := url.Values{
+		"client_id":           {c.GameClientID},
+		"client_secret":       {c.GameClientSecret},
+		"grant_type":          {"refresh_token"},
+		"refresh_token":       {c.Token.RefreshToken},
+		"without_new_session": {"1"},
+	}
+	resp, err := c.HTTPClient.Get(tokenURL + "?" + q.Encode())
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", "", fmt.Errorf("failed to authorize cloud saves (%d): %s", resp.StatusCode, body)
+	}
+	var t struct {
+		AccessToken string `json:"access_token"`
+		UserID      string `json:"user_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", "", err
+	}
+	c.token, c.userID = t.AccessToken, t.UserID
+	return c.token, c.userID, nil
+}
+
+// do sends an authorized request for the object at name, or the listing
+// for "".
+func (c *Client) do(method, name string, body []byte, header http.Header) (*http.Response, error) {
+	token, userID, err := c.auth()
+	if err != nil {
+		return nil, err
+	}
+	u := fmt.Sprintf("%s/v1/%s/%s", c.storageURL(), userID, c.GameClientID)
+	if name != "" {
+		u += "/" + escapePath(name)
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("User-Agent", "GOGGalaxyCommunicationService/2.0.4.2 (Windows_32bit)")
+	return c.HTTPClient.Do(req)
+}
+
+func escapePath(name string) string {
+	parts := strings.Split(name, "/")
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// List returns the game's files in the cloud.
+func (c *Client) List() ([]File, error) {
+	resp, err := c.do("GET", "", nil, http.Header{"Accept": {"application/json"}})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list cloud saves (%d)", resp.StatusCode)
+	}
+	var files []File
+	if err := json.NewDecoder(resp.Body).Decode(&files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Get downloads a file.
+func (c *Client) Get(name string) ([]byte, error) {
+	resp, err := c.do("GET", name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s (%d)", name, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Put uploads a file. GOG checks the contents against hash and keeps
+// modified as the file's time.
+func (c *Client) Put(name string, data []byte, hash string, modified time.Time) error {
+	resp, err := c.do("PUT", name, data, http.Header{
+		"Etag":                            {hash},
+		"X-Object-Meta-Locallastmodified": {modified.UTC().Format(time.RFC3339)},
+	})
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to upload %s (%d)", name, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package cloud
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+// fakeStorage stands in for GOG's token, remote-config and cloud storage
+// services.
+type fakeStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	times map[string]time.Time
+}
+
+func newFakeStorage(t *testing.T) (*fakeStorage, *Client) {
+	fs := &fakeStorage{files: map[string][]byte{}, times: map[string]time.Time{}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		const prefix = "/v1/4242/game-client/"
+		switch {
+		case r.URL.Path == "/token":
+			q := r.URL.Query()
+			if q.Get("client_id") != "game-client" || q.Get("client_secret") != "game-secret" || q.Get("refresh_token") != "refresh" {
+				http.Error(w, "bad credentials", http.StatusUnauthorized)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token": "game-token", "user_id": "4242"}`)
+		case r.URL.Path == "/components/galaxy_client/clients/game-client":
+			_, _ = io.WriteString(w, `{"content": {"Windows": {"cloudStorage": {"enabled": true,
+				"locations": [{"name": "saves", "location": "<?INSTALL?>/saves"}]}}}}`)
+		case r.Header.Get("Authorization") != "Bearer game-token":
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case r.URL.Path == "/v1/4242/game-client" && r.Method == "GET":
+			var list []File
+			for name, data := range fs.files {
+				sum := md5.Sum(data)
+				list = append(list, File{Name: name, Hash: hex.EncodeToString(sum[:]), Size: int64(len(data)), Modified: fs.times[name]})
+			}
+			_ = json.NewEncoder(w).Encode(list)
+		case strings.HasPrefix(r.URL.Path, prefix) && r.Method == "GET":
+			data, ok := fs.files[strings.TrimPrefix(r.URL.Path, prefix)]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(data)
+		case strings.HasPrefix(r.URL.Path, prefix) && r.Method == "PUT":
+			data, _ := io.ReadAll(r.Body)
+			sum := md5.Sum(data)
+			if hex.EncodeToString(sum[:]) != r.Header.Get("Etag") {
+				http.Error(w, "checksum mismatch", http.StatusUnprocessableEntity)
+				return
+			}
+			modified, err := time.Parse(time.RFC3339, r.Header.Get("X-Object-Meta-LocalLastModified"))
+			if err != nil {
+				http.Error(w, "bad time", http.StatusBadRequest)
+				return
+			}
+			name := strings.TrimPrefix(r.URL.Path, prefix)
+			fs.files[name], fs.times[name] = data, modified
+			w.WriteHeader(http.StatusCreated)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	c := NewClient(&gog.Client{
+		HTTPClient: srv.Client(),
+		Token:      &gog.Token{AccessToken: "user-token", RefreshToken: "refresh", ExpiresIn: 3600, SavedAt: time.Now()},
+		TokenURL:   srv.URL + "/token",
+	}, "game-client", "game-secret")
+	c.StorageURL = srv.URL
+	c.RemoteConfigURL = srv.URL
+	return fs, c
+}
+
+func TestPullAndPush(t *testing.T) {
+	fs, c := newFakeStorage(t)
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fs.files["saves/slot1.sav"], fs.times["saves/slot1.sav"] = []byte("cloud slot 1"), old
+	fs.files["saves/sub/slot2.sav"], fs.times["saves/sub/slot2.sav"] = []byte("cloud slot 2"), old
+
+	install := t.TempDir()
+	locs, err := c.Locations("windows")
+	if err != nil {
+		t.Fatalf("Locations: %v", err)
+	}
+	folders, err := DefaultFolders(install, "windows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs := map[string]string{}
+	for _, l := range locs {
+		if dirs[l.Name], err = folders.Resolve(l.Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := filepath.Join(install, "saves"); dirs["saves"] != want {
+		t.Fatalf("saves resolved to %s, want %s", dirs["saves"], want)
+	}
+
+	// A newer local slot 1 and a local-only slot 3
+	must(t, os.MkdirAll(dirs["saves"], 0755))
+	must(t, os.WriteFile(filepath.Join(dirs["saves"], "slot1.sav"), []byte("local slot 1"), 0644))
+	must(t, os.WriteFile(filepath.Join(dirs["saves"], "slot3.sav"), []byte("local slot 3"), 0644))
+
+	remote, err := c.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	local, err := Scan(dirs)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	comps := Compare(local, remote)
+	statuses := map[string]string{}
+	for _, cmp := range comps {
+		statuses[cmp.Name] = cmp.Status
+	}
+	want := map[string]string{"saves/slot1.sav": StatusLocalNewer, "saves/sub/slot2.sav": StatusRemoteOnly, "saves/slot3.sav": StatusLocalOnly}
+	for name, status := range want {
+		if statuses[name] != status {
+			t.Errorf("%s: status %q, want %q", name, statuses[name], status)
+		}
+	}
+
+	pull := PlanPull(comps)
+	if len(pull) != 2 || pull[0].Name != "saves/slot1.sav" || !pull[0].Conflict || pull[1].Conflict {
+		t.Fatalf("PlanPull = %+v", pull)
+	}
+	p, err := LocalPath(dirs, pull[1].Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Download(*pull[1].Remote, p); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dirs["saves"], "sub", "slot2.sav")); string(data) != "cloud slot 2" {
+		t.Errorf("slot2 = %q", data)
+	}
+
+	push := PlanPush(comps)
+	if len(push) != 2 {
+		t.Fatalf("PlanPush = %+v", push)
+	}
+	for _, tr := range push {
+		if err := c.Upload(*tr.Local); err != nil {
+			t.Fatalf("Upload: %v", err)
+		}
+	}
+	if string(fs.files["saves/slot1.sav"]) != "local slot 1" || string(fs.files["saves/slot3.sav"]) != "local slot 3" {
+		t.Errorf("cloud after push: %q", fs.files)
+	}
+
+	// Everything is in sync now
+	remote, _ = c.List()
+	local, _ = Scan(dirs)
+	for _, cmp := range Compare(local, remote) {
+		if cmp.Status != StatusSame {
+			t.Errorf("%s: %s after sync", cmp.Name, cmp.Status)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	folders := Folders{"INSTALL": "/games/x", "SAVED_GAMES": "/home/u/Saved Games"}
+	tests := []struct {
+		location, want string
+		err            bool
+	}{
+		{"<?INSTALL?>/saves", "/games/x/saves", false},
+		{`<?SAVED_GAMES?>\Studio\Game`, "/home/u/Saved Games/Studio/Game", false},
+		{"<?DOCUMENTS?>/Game", "", true},
+	}
+	for _, tt := range tests {
+		got, err := folders.Resolve(tt.location)
+		if (err != nil) != tt.err || filepath.ToSlash(got) != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v", tt.location, got, err)
+		}
+	}
+}
+
+func TestLocalPathRejectsEscapes(t *testing.T) {
+	dirs := map[string]string{"saves": "/games/x/saves"}
+	for _, name := range []string{"saves/../../etc/passwd", "other/slot.sav", "saves"} {
+		if _, err := LocalPath(dirs, name); err == nil {
+			t.Errorf("LocalPath(%q) should fail", name)
+		}
+	}
+}
+
+func TestLocationsUnsupportedOS(t *testing.T) {
+	_, c := newFakeStorage(t)
+	if _, err := c.Locations("linux"); err == nil {
+		t.Error("expected error for linux")
+	}
+	if _, err := c.Locations("mac"); err == nil {
+		t.Error("expected error for a platform without cloud storage")
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,278 @@
+package cloud
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Folders maps the placeholders of save locations, without the <? ?>, to
+// local directories.
+type Folders map[string]string
+
+// DefaultFolders returns the folders of a game installed in installDir for
+// goos, as seen from the user's home directory. For a Windows game run
+// through Wine, winePrefix points the Windows folders into the prefix.
+func DefaultFolders(installDir, goos, winePrefix string) (Folders, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	f := Folders{"INSTALL": installDir}
+	switch goos {
+	case "mac":
+		f["APPLICATION_SUPPORT"] = filepath.Join(home, "Library", "Application Support")
+		f["DOCUMENTS"] = filepath.Join(home, "Documents")
+	case "windows":
+		if winePrefix != "" {
+			user := os.Getenv("USER")
+			if user == "" {
+				user = filepath.Base(home)
+			}
+			home = filepath.Join(winePrefix, "drive_c", "users", user)
+		}
+		appData := filepath.Join(home, "AppData")
+		for _, names := range []struct {
+			dir  string
+			keys []string
+		}{
+			{filepath.Join(home, "Saved Games"), []string{"SAVED_GAMES", "FOLDERID_SavedGames"}},
+			{filepath.Join(home, "Documents"), []string{"DOCUMENTS", "FOLDERID_Documents"}},
+			{filepath.Join(appData, "Roaming"), []string{"APPLICATION_DATA_ROAMING", "ROAMING_APPDATA", "FOLDERID_RoamingAppData"}},
+			{filepath.Join(appData, "Local"), []string{"APPLICATION_DATA_LOCAL", "LOCAL_APPDATA", "FOLDERID_LocalAppData"}},
+			{filepath.Join(appData, "LocalLow"), []string{"APPLICATION_DATA_LOCAL_LOW", "FOLDERID_LocalAppDataLow"}},
+		} {
+			for _, k := range names.keys {
+				f[k] = names.dir
+			}
+		}
+	}
+	return f, nil
+}
+
+var placeholderRe = regexp.MustCompile(`<\?([A-Za-z_]+)\?>`)
+
+// Resolve turns a location path into a local directory.
+func (f Folders) Resolve(location string) (string, error) {
+	var missing string
+	path := placeholderRe.ReplaceAllStringFunc(location, func(m string) string {
+		key := placeholderRe.FindStringSubmatch(m)[1]
+		dir, ok := f[key]
+		if !ok || dir == "" {
+			missing = key
+		}
+		return filepath.ToSlash(dir)
+	})
+	if missing != "" {
+		return "", fmt.Errorf("can't place save location %s: unknown folder %s", location, missing)
+	}
+	return filepath.Clean(filepath.FromSlash(strings.ReplaceAll(path, `\`, "/"))), nil
+}
+
+// LocalFile is a save file on disk.
+type LocalFile struct {
+	File
+	Path string `json:"path"`
+}
+
+// Scan hashes the files in each location's directory in dirs (keyed by
+// location name), naming them like the cloud does.
+func Scan(dirs map[string]string) ([]LocalFile, error) {
+	var files []LocalFile
+	for name, dir := range dirs {
+		err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) && p == dir {
+				return filepath.SkipDir
+			}
+			if err != nil || d.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			f, err := localFile(p)
+			if err != nil {
+				return err
+			}
+			f.Name = name + "/" + filepath.ToSlash(rel)
+			files = append(files, f)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func localFile(p string) (LocalFile, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return LocalFile{}, err
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return LocalFile{}, err
+	}
+	defer func() { _ = file.Close() }()
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return LocalFile{}, err
+	}
+	return LocalFile{
+		File: File{Hash: hex.EncodeToString(h.Sum(nil)), Size: info.Size(), Modified: info.ModTime()},
+		Path: p,
+	}, nil
+}
+
+// LocalPath returns where the cloud file name belongs given the location
+// directories.
+func LocalPath(dirs map[string]string, name string) (string, error) {
+	loc, rel, _ := strings.Cut(name, "/")
+	dir, ok := dirs[loc]
+	if !ok || rel == "" {
+		return "", fmt.Errorf("%s is outside the game's save locations", name)
+	}
+	p := filepath.Join(dir, filepath.FromSlash(rel))
+	if !strings.HasPrefix(p, filepath.Clean(dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid save file name %s", name)
+	}
+	return p, nil
+}
+
+// Status of a file compared between the local disk and the cloud.
+const (
+	StatusSame        = "same"
+	StatusLocalOnly   = "local only"
+	StatusRemoteOnly  = "cloud only"
+	StatusLocalNewer  = "local newer"
+	StatusRemoteNewer = "cloud newer"
+)
+
+// Comparison is the state of one file.
+type Comparison struct {
+	Name   string     `json:"name"`
+	Status string     `json:"status"`
+	Local  *LocalFile `json:"local,omitempty"`
+	Remote *File      `json:"remote,omitempty"`
+}
+
+// Compare pairs local and remote files by name. Files with equal hashes
+// are the same whatever their times.
+func Compare(local []LocalFile, remote []File) []Comparison {
+	byName := make(map[string]*Comparison)
+	for i := range local {
+		byName[local[i].Name] = &Comparison{Name: local[i].Name, Local: &local[i]}
+	}
+	for i := range remote {
+		c, ok := byName[remote[i].Name]
+		if !ok {
+			c = &Comparison{Name: remote[i].Name}
+			byName[remote[i].Name] = c
+		}
+		c.Remote = &remote[i]
+	}
+
+	comps := make([]Comparison, 0, len(byName))
+	for _, c := range byName {
+		switch {
+		case c.Remote == nil:
+			c.Status = StatusLocalOnly
+		case c.Local == nil:
+			c.Status = StatusRemoteOnly
+		case strings.EqualFold(c.Local.Hash, c.Remote.Hash):
+			c.Status = StatusSame
+		case c.Local.Modified.After(c.Remote.Modified):
+			c.Status = StatusLocalNewer
+		default:
+			c.Status = StatusRemoteNewer
+		}
+		comps = append(comps, *c)
+	}
+	sort.Slice(comps, func(i, j int) bool { return comps[i].Name < comps[j].Name })
+	return comps
+}
+
+// Transfer says what a pull or push does with a file: copy it, leave it,
+// or ask because it would overwrite newer data.
+type Transfer struct {
+	Comparison
+	Conflict bool
+}
+
+// PlanPull returns the files to download: those only in the cloud or newer
+// there, and as conflicts those newer locally.
+func PlanPull(comps []Comparison) []Transfer {
+	var plan []Transfer
+	for _, c := range comps {
+		switch c.Status {
+		case StatusRemoteOnly, StatusRemoteNewer:
+			plan = append(plan, Transfer{Comparison: c})
+		case StatusLocalNewer:
+			plan = append(plan, Transfer{Comparison: c, Conflict: true})
+		}
+	}
+	return plan
+}
+
+// PlanPush returns the files to upload: those only local or newer locally,
+// and as conflicts those newer in the cloud.
+func PlanPush(comps []Comparison) []Transfer {
+	var plan []Transfer
+	for _, c := range comps {
+		switch c.Status {
+		case StatusLocalOnly, StatusLocalNewer:
+			plan = append(plan, Transfer{Comparison: c})
+		case StatusRemoteNewer:
+			plan = append(plan, Transfer{Comparison: c, Conflict: true})
+		}
+	}
+	return plan
+}
+
+// Download writes the cloud file name to path, keeping its time so later
+// comparisons see it as unchanged.
+func (c *Client) Download(f File, path string) error {
+	data, err := c.Get(f.Name)
+	if err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	if f.Hash != "" && !strings.EqualFold(hex.EncodeToString(sum[:]), f.Hash) {
+		return fmt.Errorf("%s: download does not match its checksum", f.Name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".goggle-part"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	if !f.Modified.IsZero() {
+		return os.Chtimes(path, time.Now(), f.Modified)
+	}
+	return nil
+}
+
+// Upload sends a local file to the cloud.
+func (c *Client) Upload(f LocalFile) error {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return err
+	}
+	return c.Put(f.Name, data, f.Hash, f.Modified)
+}
@@ -0,0 +1,372 @@
+// Package content reads GOG's content system, the build and depot service
+// behind GOG Galaxy: builds list depot manifests, which list the files of a
+// game as zlib-compressed chunks on the CDN. Games can be installed from it
+// file by file, without an installer.
+package content
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+// Client talks to the content system on behalf of a logged-in gog.Client.
+type Client struct {
+	*gog.Client
+	ContentURL string // default: "https://content-system.gog.com"
+	MetaURL    string // default: "https://gog-cdn-fastly.gog.com/content-system/v2/meta"
+}
+
+func NewClient(c *gog.Client) *Client {
+	return &Client{Client: c}
+}
+
+func (c *Client) contentURL() string {
+	if c.ContentURL != "" {
+		return c.ContentURL
+	}
+	return "https://content-system.gog.com"
+}
+
+func (c *Client) metaURL() string {
+	if c.MetaURL != "" {
+		return c.MetaURL
+	}
+	return "https://gog-cdn-fastly.gog.com/content-system/v2/meta"
+}
+
+// Timestamp reads the dates of the content system, which use a numeric
+// zone offset without a colon.
+type Timestamp struct {
+	time.Time
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil || s == "" {
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05-0700", time.RFC3339} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time %q", s)
+}
+
+// Build is a published build of a product for one OS.
+type Build struct {
+	ID         string    `json:"build_id"`
+	ProductID  string    `json:"product_id"`
+	OS         string    `json:"os"`
+	Branch     string    `json:"branch"` // "" for the default branch
+	Version    string    `json:"version_name"`
+	Tags       []string  `json:"tags"`
+	Public     bool      `json:"public"`
+	Published  Timestamp `json:"date_published"`
+	Generation int       `json:"generation"`
+	Link       string    `json:"link"` // build manifest
+}
+
+type buildsResponse struct {
+	Items []Build `json:"items"`
+}
+
+// Manifest is a build manifest.
+type Manifest struct {
+	BaseProductID    string    `json:"baseProductId"`
+	BuildID          string    `json:"buildId"`
+	InstallDirectory string    `json:"installDirectory"`
+	Platform         string    `json:"platform"`
+	Depots           []Depot   `json:"depots"`
+	OfflineDepot     *Depot    `json:"offlineDepot"` // goggame-*.info and similar files
+	Products         []Product `json:"products"`
+	Dependencies     []string  `json:"dependencies"`
+	ClientID         string    `json:"clientId"` // Galaxy client of the game, e.g. for cloud saves
+	ClientSecret     string    `json:"clientSecret"`
+}
+
+// Product is the game or a DLC whose files are in a build.
+type Product struct {
+	ProductID string `json:"productId"`
+	Name      string `json:"name"`
+}
+
+// Depot is a set of files for one product and a set of languages.
+type Depot struct {
+	ProductID      string   `json:"productId"`
+	Languages      []string `json:"languages"` // codes such as "en-US", or "*" for all
+	Manifest       string   `json:"manifest"`
+	Size           int64    `json:"size"`
+	CompressedSize int64    `json:"compressedSize"`
+	IsGogDepot     bool     `json:"isGogDepot"` // Galaxy components, not game files
+}
+
+// DepotManifest lists the files of a depot.
+type DepotManifest struct {
+	Depot struct {
+		Items               []Item `json:"items"`
+		SmallFilesContainer *Item  `json:"smallFilesContainer"`
+	} `json:"depot"`
+}
+
+const (
+	ItemFile      = "DepotFile"
+	ItemDirectory = "DepotDirectory"
+	ItemLink      = "DepotLink"
+)
+
+// Item is a file, directory or symlink in a depot.
+type Item struct {
+	Type   string   `json:"type"`
+	Path   string   `json:"path"`
+	Target string   `json:"target"` // of links
+	Chunks []Chunk  `json:"chunks"`
+	Flags  []string `json:"flags"`
+	MD5    string   `json:"md5"` // of the whole file, set for multi-chunk files
+	SHA256 string   `json:"sha256"`
+	SFCRef *SFCRef  `json:"sfcRef"` // set when stored in the small files container
+}
+
+// SFCRef locates a small file inside the depot's small files container.
+type SFCRef struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+// Chunk is a zlib-compressed piece of a file, stored on the CDN under the
+// MD5 of its compressed data.
+type Chunk struct {
+	MD5            string `json:"md5"`
+	Size           int64  `json:"size"`
+	CompressedMD5  string `json:"compressedMd5"`
+	CompressedSize int64  `json:"compressedSize"`
+}
+
+// RelPath returns the item's path relative to the install directory,
+// slash separated.
+func (it *Item) RelPath() string {
+	return strings.Trim(strings.ReplaceAll(it.Path, `\`, "/"), "/")
+}
+
+// Size is the size of the file once assembled.
+func (it *Item) Size() int64 {
+	if it.SFCRef != nil {
+		return it.SFCRef.Size
+	}
+	var n int64
+	for _, ch := range it.Chunks {
+		n += ch.Size
+	}
+	return n
+}
+
+// FileMD5 returns the MD5 of the assembled file, "" when the manifest has
+// none.
+func (it *Item) FileMD5() string {
+	if it.MD5 != "" {
+		return strings.ToLower(it.MD5)
+	}
+	if len(it.Chunks) == 1 {
+		return strings.ToLower(it.Chunks[0].MD5)
+	}
+	return ""
+}
+
+func (it *Item) Executable() bool {
+	for _, f := range it.Flags {
+		if f == "executable" {
+			return true
+		}
+	}
+	return false
+}
+
+// galaxyPath is where the CDN keeps an object: "ab/cd/abcd...".
+func galaxyPath(hash string) string {
+	if len(hash) < 4 {
+		return hash
+	}
+	return hash[:2] + "/" + hash[2:4] + "/" + hash
+}
+
+// contentOS converts a gog OS name to the one the content system uses.
+func contentOS(goos string) string {
+	if goos == "mac" {
+		return "osx"
+	}
+	return goos
+}
+
+// Builds lists the published builds of a product for os (windows, mac or
+// linux) on all branches, newest first. password also lists the builds of
+// the branch it unlocks; pass "" for the public ones.
+func (c *Client) Builds(productID int, os, password string) ([]Build, error) {
+	q := url.Values{"generation": {"2"}}
+	if password != "" {
+		q.Set("password", password)
+	}
+	u := fmt.Sprintf("%s/products/%d/os/%s/builds?%s", c.contentURL(), productID, contentOS(os), q.Encode())
+	var result buildsResponse
+	if err := c.getJSON(u, true, &result); err != nil {
+		return nil, fmt.Errorf("failed to list builds: %w", err)
+	}
+	return result.Items, nil
+}
+
+// Latest returns the newest of builds on branch, "" being the default
+// branch.
+func Latest(builds []Build, branch string) (Build, bool) {
+	for _, b := range builds {
+		if strings.EqualFold(b.Branch, branch) {
+			return b, true
+		}
+	}
+	return Build{}, false
+}
+
+// Branches lists the branches among builds, the default one as "".
+func Branches(builds []Build) []string {
+	var branches []string
+	seen := make(map[string]bool)
+	for _, b := range builds {
+		if !seen[b.Branch] {
+			seen[b.Branch] = true
+			branches = append(branches, b.Branch)
+		}
+	}
+	return branches
+}
+
+// Manifest fetches the manifest of b.
+func (c *Client) Manifest(b Build) (*Manifest, error) {
+	if b.Generation != 0 && b.Generation != 2 {
+		return nil, fmt.Errorf("build %s uses the unsupported generation %d format", b.ID, b.Generation)
+	}
+	var m Manifest
+	if err := c.getJSON(b.Link, false, &m); err != nil {
+		return nil, fmt.Errorf("failed to get build manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// DepotManifest fetches the file list of a depot.
+func (c *Client) DepotManifest(d Depot) (*DepotManifest, error) {
+	var dm DepotManifest
+	if err := c.getJSON(c.metaURL()+"/"+galaxyPath(d.Manifest), false, &dm); err != nil {
+		return nil, fmt.Errorf("failed to get depot manifest: %w", err)
+	}
+	return &dm, nil
+}
+
+// getJSON fetches a JSON document, which the content system may serve
+// zlib-compressed.
+func (c *Client) getJSON(rawURL string, auth bool, v any) error {
+	var resp *http.Response
+	var err error
+	if auth {
+		resp, err = c.AuthGet(rawURL)
+	} else {
+		resp, err = c.HTTPClient.Get(rawURL)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("request failed (%d): %s", resp.StatusCode, body)
+	}
+	if len(body) > 0 && body[0] == 0x78 {
+		if body, err = inflate(body); err != nil {
+			return err
+		}
+	}
+	return json.Unmarshal(body, v)
+}
+
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+	return io.ReadAll(zr)
+}
+
+// Endpoint is a CDN location chunks of a product can be fetched from.
+type Endpoint struct {
+	Format string            // URL template with {name} placeholders
+	Params map[string]string // values for the placeholders
+}
+
+// URL returns the location of the object stored under hash.
+func (e Endpoint) URL(hash string) string {
+	params := make(map[string]string, len(e.Params))
+	for k, v := range e.Params {
+		params[k] = v
+	}
+	params["path"] = strings.TrimSuffix(params["path"], "/") + "/" + galaxyPath(hash)
+	u := e.Format
+	for k, v := range params {
+		u = strings.ReplaceAll(u, "{"+k+"}", v)
+	}
+	return u
+}
+
+type linkResponse struct {
+	URLs []struct {
+		URLFormat    string                     `json:"url_format"`
+		Parameters   map[string]json.RawMessage `json:"parameters"`
+		FallbackOnly bool                       `json:"fallback_only"`
+	} `json:"urls"`
+}
+
+// SecureLink returns a signed CDN endpoint for the chunks of productID. The
+// links expire after a while; ask again when fetching starts failing.
+func (c *Client) SecureLink(productID string) (Endpoint, error) {
+	q := url.Values{"generation": {"2"}, "_version": {"2"}, "path": {"/"}}
+	u := fmt.Sprintf("%s/products/%s/secure_link?%s", c.contentURL(), productID, q.Encode())
+	var result linkResponse
+	if err := c.getJSON(u, true, &result); err != nil {
+		return Endpoint{}, fmt.Errorf("failed to get download link: %w", err)
+	}
+	if len(result.URLs) == 0 {
+		return Endpoint{}, fmt.Errorf("no download link for product %s", productID)
+	}
+	return result.endpoint(), nil
+}
+
+// endpoint picks the first link that isn't a fallback.
+func (result *linkResponse) endpoint() Endpoint {
+	chosen := result.URLs[0]
+	for _, link := range result.URLs {
+		if !link.FallbackOnly {
+			chosen = link
+			break
+		}
+	}
+
+	e := Endpoint{Format: chosen.URLFormat, Params: make(map[string]string)}
+	for k, raw := range chosen.Parameters {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw) // numbers such as expires_at
+		}
+		e.Params[k] = s
+	}
+	return e
+}
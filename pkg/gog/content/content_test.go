@@ -0,0 +1,357 @@
+package content
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+func deflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func md5hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fakeCDN serves zlib-compressed objects by galaxy path.
+type fakeCDN struct {
+	t       *testing.T
+	objects map[string][]byte // galaxy path -> body
+}
+
+// chunk stores data as a chunk and describes it.
+func (c *fakeCDN) chunk(data []byte) Chunk {
+	z := deflate(c.t, data)
+	h := md5hex(z)
+	c.objects[galaxyPath(h)] = z
+	return Chunk{MD5: md5hex(data), Size: int64(len(data)), CompressedMD5: h, CompressedSize: int64(len(z))}
+}
+
+// meta stores v as a compressed JSON document and returns its hash.
+func (c *fakeCDN) meta(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	z := deflate(c.t, data)
+	h := md5hex(data)
+	c.objects[galaxyPath(h)] = z
+	return h
+}
+
+type testServer struct {
+	*httptest.Server
+	client      *Client
+	cdn         *fakeCDN
+	linkFetches atomic.Int32
+	expireOnce  atomic.Bool
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ts := &testServer{cdn: &fakeCDN{t: t, objects: make(map[string][]byte)}}
+	ts.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/products/42/os/windows/builds":
+			if r.Header.Get("Authorization") == "" {
+				t.Error("builds request without credentials")
+			}
+			secret := ""
+			if r.URL.Query().Get("password") == "letmein" {
+				secret = `{"build_id": "300", "product_id": "42", "os": "windows", "branch": "closed", "version_name": "2.0-rc1",
+				 "public": false, "generation": 2, "link": ""},`
+			}
+			fmt.Fprintf(w, `{"total_count": 2, "count": 2, "items": [`+secret+`
+				{"build_id": "200", "product_id": "42", "os": "windows", "branch": null, "version_name": "1.1",
+				 "tags": [], "public": true, "date_published": "2021-03-30T13:01:13+0000", "generation": 2,
+				 "link": "%[1]s/meta/%[2]s"},
+				{"build_id": "100", "product_id": "42", "os": "windows", "branch": "beta", "version_name": "1.0",
+				 "public": true, "date_published": "2020-01-02T03:04:05+0000", "generation": 2, "link": "%[1]s/meta/x"}]}`,
+				ts.URL, galaxyPath("build"))
+		case strings.HasPrefix(r.URL.Path, "/products/") && strings.HasSuffix(r.URL.Path, "/secure_link"):
+			ts.linkFetches.Add(1)
+			product := strings.Split(r.URL.Path, "/")[2]
+			fmt.Fprintf(w, `{"product_id": %s, "urls": [
+				{"endpoint_name": "fallback", "url_format": "http://invalid/{path}", "parameters": {"path": "/"}, "fallback_only": true},
+				{"endpoint_name": "test", "url_format": "{base_url}/token=nva={expires_at}{path}",
+				 "parameters": {"base_url": "%s/cdn", "path": "/content-system/v2/store/%s", "expires_at": 1700000000},
+				 "fallback_only": false}]}`, product, ts.URL, product)
+		case r.URL.Path == "/dependencies/repository":
+			fmt.Fprintf(w, `{"repository_manifest": "%s/dependencies/meta/%s"}`, ts.URL, galaxyPath("repository"))
+		case r.URL.Path == "/open_link":
+			ts.linkFetches.Add(1)
+			fmt.Fprintf(w, `{"urls": [{"url_format": "{base_url}{path}",
+				"parameters": {"base_url": "%s/open", "path": "%s"}, "fallback_only": false}]}`, ts.URL, r.URL.Query().Get("path"))
+		case strings.HasPrefix(r.URL.Path, "/open/dependencies/store/"):
+			body, ok := ts.cdn.objects[strings.TrimPrefix(r.URL.Path, "/open/dependencies/store/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(body)
+		case strings.HasPrefix(r.URL.Path, "/meta/") || strings.HasPrefix(r.URL.Path, "/dependencies/meta/"):
+			body, ok := ts.cdn.objects[strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/dependencies"), "/meta/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(body)
+		case strings.HasPrefix(r.URL.Path, "/cdn/token=nva=1700000000/content-system/v2/store/"):
+			if ts.expireOnce.CompareAndSwap(true, false) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			rest := strings.TrimPrefix(r.URL.Path, "/cdn/token=nva=1700000000/content-system/v2/store/")
+			body, ok := ts.cdn.objects[rest[strings.Index(rest, "/")+1:]]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write(body)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(ts.Close)
+
+	ts.client = NewClient(&gog.Client{
+		HTTPClient: ts.Client(),
+		Token:      &gog.Token{AccessToken: "tok", ExpiresIn: 3600, SavedAt: time.Now()},
+	})
+	ts.client.ContentURL = ts.URL
+	ts.client.MetaURL = ts.URL + "/meta"
+	return ts
+}
+
+// addBuild publishes a build with a game depot, a German depot, a DLC depot
+// and an offline depot, and returns the expected file contents.
+func (ts *testServer) addBuild(t *testing.T) map[string]string {
+	cdn := ts.cdn
+	big := bytes.Repeat([]byte("0123456789"), 1000)
+	small1, small2 := []byte("small one"), []byte("second small file")
+	sfcData := append(append([]byte(nil), small1...), small2...)
+
+	gameDepot := map[string]any{"version": 2, "depot": map[string]any{
+		"items": []Item{
+			{Type: ItemDirectory, Path: `saves`},
+			{Type: ItemFile, Path: `bin\game.exe`, Flags: []string{"executable"}, MD5: md5hex(big),
+				Chunks: []Chunk{cdn.chunk(big[:4000]), cdn.chunk(big[4000:])}},
+			{Type: ItemFile, Path: `data\a.txt`, SFCRef: &SFCRef{Offset: 0, Size: int64(len(small1))}},
+			{Type: ItemFile, Path: `data\b.txt`, SFCRef: &SFCRef{Offset: int64(len(small1)), Size: int64(len(small2))}},
+			{Type: ItemFile, Path: `empty.txt`},
+			{Type: ItemLink, Path: `game`, Target: `bin/game.exe`},
+		},
+		"smallFilesContainer": Item{Chunks: []Chunk{cdn.chunk(sfcData)}},
+	}}
+	germanDepot := map[string]any{"depot": map[string]any{"items": []Item{
+		{Type: ItemFile, Path: `lang\de.txt`, Chunks: []Chunk{cdn.chunk([]byte("hallo"))}},
+	}}}
+	dlcDepot := map[string]any{"depot": map[string]any{"items": []Item{
+		{Type: ItemFile, Path: `dlc\dlc.dat`, Chunks: []Chunk{cdn.chunk([]byte("dlc"))}},
+	}}}
+	offlineDepot := map[string]any{"depot": map[string]any{"items": []Item{
+		{Type: ItemFile, Path: `goggame-42.info`, Chunks: []Chunk{cdn.chunk([]byte(`{"gameId": "42"}`))}},
+	}}}
+
+	manifest := Manifest{
+		BaseProductID: "42",
+		BuildID:       "200",
+		Platform:      "windows",
+		Depots: []Depot{
+			{ProductID: "42", Languages: []string{"*"}, Manifest: cdn.meta(gameDepot)},
+			{ProductID: "42", Languages: []string{"de-DE"}, Manifest: cdn.meta(germanDepot)},
+			{ProductID: "43", Languages: []string{"*"}, Manifest: cdn.meta(dlcDepot)},
+			{ProductID: "42", Languages: []string{"*"}, Manifest: "missing", IsGogDepot: true},
+		},
+		OfflineDepot: &Depot{ProductID: "42", Languages: []string{"*"}, Manifest: cdn.meta(offlineDepot)},
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdn.objects[galaxyPath("build")] = deflate(t, data)
+
+	return map[string]string{
+		"bin/game.exe":    string(big),
+		"data/a.txt":      string(small1),
+		"data/b.txt":      string(small2),
+		"empty.txt":       "",
+		"goggame-42.info": `{"gameId": "42"}`,
+	}
+}
+
+func TestBuilds(t *testing.T) {
+	ts := newTestServer(t)
+	builds, err := ts.client.Builds(42, "windows", "")
+	if err != nil {
+		t.Fatalf("Builds: %v", err)
+	}
+	if len(builds) != 2 {
+		t.Fatalf("got %d builds, want 2", len(builds))
+	}
+	b := builds[0]
+	if b.ID != "200" || b.Version != "1.1" || b.Branch != "" || b.Generation != 2 {
+		t.Errorf("build = %+v", b)
+	}
+	if want := time.Date(2021, 3, 30, 13, 1, 13, 0, time.UTC); !b.Published.Equal(want) {
+		t.Errorf("Published = %v, want %v", b.Published, want)
+	}
+	if builds[1].Branch != "beta" {
+		t.Errorf("Branch = %q, want beta", builds[1].Branch)
+	}
+}
+
+func TestBuildsPasswordAndBranches(t *testing.T) {
+	ts := newTestServer(t)
+	builds, err := ts.client.Builds(42, "windows", "letmein")
+	if err != nil {
+		t.Fatalf("Builds: %v", err)
+	}
+	if len(builds) != 3 {
+		t.Fatalf("got %d builds, want 3", len(builds))
+	}
+	if got := Branches(builds); len(got) != 3 || got[0] != "closed" || got[1] != "" || got[2] != "beta" {
+		t.Errorf("Branches = %q", got)
+	}
+	if b, ok := Latest(builds, ""); !ok || b.ID != "200" {
+		t.Errorf("Latest default = %+v, %v", b, ok)
+	}
+	if b, ok := Latest(builds, "Beta"); !ok || b.ID != "100" {
+		t.Errorf("Latest beta = %+v, %v", b, ok)
+	}
+	if _, ok := Latest(builds, "nightly"); ok {
+		t.Error("Latest found a build on an unknown branch")
+	}
+}
+
+func TestDownload(t *testing.T) {
+	ts := newTestServer(t)
+	want := ts.addBuild(t)
+	builds, err := ts.client.Builds(42, "windows", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ts.client.Manifest(builds[0])
+	if err != nil {
+		t.Fatalf("Manifest: %v", err)
+	}
+	if m.BuildID != "200" || len(m.Depots) != 4 {
+		t.Fatalf("manifest = %+v", m)
+	}
+
+	dir := filepath.Join(t.TempDir(), "game")
+	ts.expireOnce.Store(true)
+	var lastDone, lastTotal int64
+	files, err := ts.client.Download(m, dir, DownloadOptions{
+		Languages: []string{"en-US"},
+		Workers:   2,
+		Progress:  func(done, total int64) { lastDone, lastTotal = done, total },
+	})
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+
+	wantFiles := []string{"bin/game.exe", "data/a.txt", "data/b.txt", "empty.txt", "game", "goggame-42.info"}
+	if strings.Join(files, ",") != strings.Join(wantFiles, ",") {
+		t.Errorf("files = %v, want %v", files, wantFiles)
+	}
+	for rel, content := range want {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Errorf("%s: %v", rel, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s has wrong content (%d bytes)", rel, len(data))
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "lang", "de.txt")); !os.IsNotExist(err) {
+		t.Error("German depot should not be installed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dlc")); !os.IsNotExist(err) {
+		t.Error("DLC depot should not be installed")
+	}
+	if info, err := os.Stat(filepath.Join(dir, "saves")); err != nil || !info.IsDir() {
+		t.Error("saves directory not created")
+	}
+	if runtime.GOOS != "windows" {
+		if info, _ := os.Stat(filepath.Join(dir, "bin", "game.exe")); info.Mode().Perm()&0100 == 0 {
+			t.Error("executable flag not applied")
+		}
+		if target, err := os.Readlink(filepath.Join(dir, "game")); err != nil || target != "bin/game.exe" {
+			t.Errorf("link target = %q, %v", target, err)
+		}
+	}
+	if lastDone != lastTotal || lastTotal == 0 {
+		t.Errorf("progress ended at %d/%d", lastDone, lastTotal)
+	}
+	if n := ts.linkFetches.Load(); n < 2 {
+		t.Errorf("secure link fetched %d times, want a refresh after expiry", n)
+	}
+
+	// German and the DLC on request
+	files, err = ts.client.Download(m, dir, DownloadOptions{Languages: []string{"de"}, ProductIDs: []string{"42", "43"}})
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if len(files) != 8 {
+		t.Errorf("files = %v, want 8", files)
+	}
+}
+
+func TestDownloadRejectsBadPaths(t *testing.T) {
+	ts := newTestServer(t)
+	dir := t.TempDir()
+	for _, e := range []FileEntry{
+		{Item: Item{Type: ItemFile, Path: `..\evil.txt`}},
+		{Item: Item{Type: ItemLink, Path: `link`, Target: `../../etc/passwd`}},
+	} {
+		if _, err := ts.client.Write([]FileEntry{e}, dir, DownloadOptions{}); err == nil {
+			t.Errorf("%s: expected error", e.Path)
+		}
+	}
+}
+
+func TestMatchesLanguage(t *testing.T) {
+	tests := []struct {
+		depot []string
+		want  []string
+		match bool
+	}{
+		{[]string{"*"}, []string{"de-DE"}, true},
+		{nil, []string{"de-DE"}, true},
+		{[]string{"en-US"}, []string{"en-US"}, true},
+		{[]string{"en-US"}, []string{"en"}, true},
+		{[]string{"en"}, []string{"en-US"}, true},
+		{[]string{"de-DE"}, []string{"en-US"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchesLanguage(tt.depot, tt.want); got != tt.match {
+			t.Errorf("matchesLanguage(%v, %v) = %v, want %v", tt.depot, tt.want, got, tt.match)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package content
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Dependency is a redistributable from GOG's dependency repository, such as
+// a DirectX or Visual C++ runtime, that builds can declare they need.
+type Dependency struct {
+	ID             string   `json:"dependencyId"`
+	Name           string   `json:"readableName"`
+	Languages      []string `json:"languages"`
+	Manifest       string   `json:"manifest"`
+	Size           int64    `json:"size"`
+	CompressedSize int64    `json:"compressedSize"`
+	Internal       bool     `json:"internal"` // used by Galaxy itself, not games
+	Executable     struct {
+		Path      string `json:"path"`
+		Arguments string `json:"arguments"`
+	} `json:"executable"` // the redistributable's own installer, relative to its files
+}
+
+// DependencyRepository lists the dependencies GOG publishes.
+type DependencyRepository struct {
+	BuildID string       `json:"build_id"`
+	Depots  []Dependency `json:"depots"`
+}
+
+// Find returns the dependencies with the given IDs, and the IDs the
+// repository doesn't have.
+func (r *DependencyRepository) Find(ids []string) ([]Dependency, []string) {
+	var found []Dependency
+	var missing []string
+	for _, id := range ids {
+		ok := false
+		for _, d := range r.Depots {
+			if strings.EqualFold(d.ID, id) {
+				found = append(found, d)
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			missing = append(missing, id)
+		}
+	}
+	return found, missing
+}
+
+func (c *Client) dependencyMetaURL() string {
+	return strings.TrimSuffix(c.metaURL(), "/meta") + "/dependencies/meta"
+}
+
+// Dependencies fetches the current dependency repository.
+func (c *Client) Dependencies() (*DependencyRepository, error) {
+	var index struct {
+		Manifest string `json:"repository_manifest"`
+	}
+	if err := c.getJSON(c.contentURL()+"/dependencies/repository?generation=2", false, &index); err != nil {
+		return nil, fmt.Errorf("failed to get dependency repository: %w", err)
+	}
+	if index.Manifest == "" {
+		return nil, errors.New("dependency repository has no manifest")
+	}
+	var repo DependencyRepository
+	if err := c.getJSON(index.Manifest, false, &repo); err != nil {
+		return nil, fmt.Errorf("failed to get dependency repository: %w", err)
+	}
+	return &repo, nil
+}
+
+// OpenLink returns the CDN endpoint for dependency chunks, which need no
+// signature.
+func (c *Client) OpenLink() (Endpoint, error) {
+	q := url.Values{"generation": {"2"}, "_version": {"2"}, "path": {"/dependencies/store/"}}
+	var result linkResponse
+	if err := c.getJSON(c.contentURL()+"/open_link?"+q.Encode(), false, &result); err != nil {
+		return Endpoint{}, fmt.Errorf("failed to get dependency link: %w", err)
+	}
+	if len(result.URLs) == 0 {
+		return Endpoint{}, errors.New("no download link for dependencies")
+	}
+	return result.endpoint(), nil
+}
+
+// DependencyCache keeps downloaded dependencies, one directory each, so
+// games needing the same runtime share a single copy.
+type DependencyCache struct {
+	Dir string
+}
+
+// cacheMarker records which manifest a cached dependency was built from.
+const cacheMarker = ".goggle-dependency"
+
+// DefaultDependencyCache returns the cache in the user's cache directory,
+// e.g. ~/.cache/goggle/dependencies.
+func DefaultDependencyCache() (*DependencyCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &DependencyCache{Dir: filepath.Join(dir, "goggle", "dependencies")}, nil
+}
+
+// Path is the directory holding d's files. IDs come from GOG's repository,
+// so one that isn't a plain directory name is rejected rather than joined.
+func (dc *DependencyCache) Path(d Dependency) (string, error) {
+	if d.ID == "" || d.ID == "." || strings.Contains(d.ID, "..") || strings.ContainsAny(d.ID, `/\`) {
+		return "", fmt.Errorf("invalid dependency ID %q", d.ID)
+	}
+	return filepath.Join(dc.Dir, d.ID), nil
+}
+
+// Has reports whether the current version of d is cached.
+func (dc *DependencyCache) Has(d Dependency) bool {
+	dir, err := dc.Path(d)
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(dir, cacheMarker))
+	return err == nil && strings.TrimSpace(string(data)) == d.Manifest
+}
+
+// FetchDependency downloads d into the cache unless the current version is
+// there already, and returns its directory and whether it was downloaded.
+// The files are fetched into a directory of their own and moved into place
+// once complete, so concurrent installs needing d don't write over each
+// other.
+func (c *Client) FetchDependency(d Dependency, dc *DependencyCache, opts DownloadOptions) (string, bool, error) {
+	dir, err := dc.Path(d)
+	if err != nil {
+		return "", false, err
+	}
+	if dc.Has(d) {
+		return dir, false, nil
+	}
+	var dm DepotManifest
+	if err := c.getJSON(c.dependencyMetaURL()+"/"+galaxyPath(d.Manifest), false, &dm); err != nil {
+		return "", false, fmt.Errorf("failed to get %s manifest: %w", d.ID, err)
+	}
+	entries := make([]FileEntry, len(dm.Depot.Items))
+	for i, it := range dm.Depot.Items {
+		entries[i] = FileEntry{Item: it, sfc: dm.Depot.SmallFilesContainer}
+	}
+
+	if err := os.MkdirAll(dc.Dir, 0755); err != nil {
+		return "", false, err
+	}
+	tmp, err := os.MkdirTemp(dc.Dir, "."+d.ID+"-")
+	if err != nil {
+		return "", false, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+	if err := os.Chmod(tmp, 0755); err != nil {
+		return "", false, err
+	}
+	f := newFetcher(c)
+	f.link = func(string) (Endpoint, error) { return c.OpenLink() }
+	if _, err := f.write(entries, tmp, opts); err != nil {
+		return "", false, fmt.Errorf("%s: %w", d.ID, err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, cacheMarker), []byte(d.Manifest+"\n"), 0644); err != nil {
+		return "", false, err
+	}
+	if err := dc.replace(d, tmp, dir); err != nil {
+		return "", false, fmt.Errorf("failed to cache %s: %w", d.ID, err)
+	}
+	return dir, true, nil
+}
+
+// replace moves the complete copy of d in tmp to dir. An outdated copy is
+// moved aside and removed as a whole. If a concurrent fetch put the current
+// version in place first, that copy is kept and tmp is left to the caller.
+func (dc *DependencyCache) replace(d Dependency, tmp, dir string) error {
+	if os.Rename(tmp, dir) == nil || dc.Has(d) {
+		return nil
+	}
+	old := tmp + ".old"
+	if err := os.Rename(dir, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(old) }()
+	if err := os.Rename(tmp, dir); err != nil && !dc.Has(d) {
+		return err
+	}
+	return nil
+}
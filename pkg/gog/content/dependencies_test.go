@@ -0,0 +1,131 @@
+package content
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFetchDependency(t *testing.T) {
+	ts := newTestServer(t)
+	cdn := ts.cdn
+	depot := map[string]any{"depot": map[string]any{"items": []Item{
+		{Type: ItemFile, Path: `MSVC2017\VC_redist.x64.exe`, Chunks: []Chunk{cdn.chunk([]byte("redist"))}},
+	}}}
+	repo := DependencyRepository{BuildID: "1", Depots: []Dependency{
+		{ID: "DirectX", Name: "DirectX", Manifest: "missing"},
+		{ID: "MSVC2017_x64", Name: "Microsoft Visual C++ 2017 Redistributable (x64)", Manifest: cdn.meta(depot)},
+	}}
+	data, err := json.Marshal(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cdn.objects[galaxyPath("repository")] = deflate(t, data)
+
+	got, err := ts.client.Dependencies()
+	if err != nil {
+		t.Fatalf("Dependencies: %v", err)
+	}
+	deps, missing := got.Find([]string{"msvc2017_x64", "DOSBox"})
+	if len(deps) != 1 || deps[0].ID != "MSVC2017_x64" {
+		t.Fatalf("Find = %+v", deps)
+	}
+	if len(missing) != 1 || missing[0] != "DOSBox" {
+		t.Errorf("missing = %v", missing)
+	}
+
+	cache := &DependencyCache{Dir: t.TempDir()}
+	dir, fetched, err := ts.client.FetchDependency(deps[0], cache, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("FetchDependency: %v", err)
+	}
+	if !fetched || dir != filepath.Join(cache.Dir, "MSVC2017_x64") {
+		t.Errorf("FetchDependency = %s, %v", dir, fetched)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "MSVC2017", "VC_redist.x64.exe")); err != nil || string(b) != "redist" {
+		t.Errorf("redistributable = %q, %v", b, err)
+	}
+
+	// Cached now: nothing is downloaded, even with the CDN gone
+	cdn.objects = map[string][]byte{}
+	if _, fetched, err := ts.client.FetchDependency(deps[0], cache, DownloadOptions{}); err != nil || fetched {
+		t.Errorf("second fetch = %v, %v", fetched, err)
+	}
+	// A new version of the dependency is fetched again
+	deps[0].Manifest = "changed"
+	if cache.Has(deps[0]) {
+		t.Error("cache should not have the new version")
+	}
+}
+
+func TestFetchDependencyConcurrent(t *testing.T) {
+	ts := newTestServer(t)
+	depot := map[string]any{"depot": map[string]any{"items": []Item{
+		{Type: ItemFile, Path: `DirectX\DXSETUP.exe`, Chunks: []Chunk{ts.cdn.chunk([]byte("dxsetup"))}},
+	}}}
+	d := Dependency{ID: "DirectX", Manifest: ts.cdn.meta(depot)}
+
+	// An outdated copy is replaced as a whole
+	cache := &DependencyCache{Dir: t.TempDir()}
+	stale := filepath.Join(cache.Dir, "DirectX")
+	if err := os.MkdirAll(stale, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, "stale.dll"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(stale, cacheMarker), []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, errs[i] = ts.client.FetchDependency(d, cache, DownloadOptions{})
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("fetch %d: %v", i, err)
+		}
+	}
+	if !cache.Has(d) {
+		t.Error("cache should have the dependency")
+	}
+	if b, err := os.ReadFile(filepath.Join(stale, "DirectX", "DXSETUP.exe")); err != nil || string(b) != "dxsetup" {
+		t.Errorf("DXSETUP.exe = %q, %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(stale, "stale.dll")); !os.IsNotExist(err) {
+		t.Errorf("outdated file kept: %v", err)
+	}
+	// Nothing but the dependency itself is left in the cache
+	if entries, err := os.ReadDir(cache.Dir); err != nil || len(entries) != 1 {
+		t.Errorf("cache entries = %v, %v", entries, err)
+	}
+}
+
+func TestDependencyCachePath(t *testing.T) {
+	cache := &DependencyCache{Dir: filepath.Join(t.TempDir(), "dependencies")}
+	if p, err := cache.Path(Dependency{ID: "MSVC2017_x64"}); err != nil || p != filepath.Join(cache.Dir, "MSVC2017_x64") {
+		t.Errorf("Path = %s, %v", p, err)
+	}
+	for _, id := range []string{"", ".", "..", "../goggle", `..\goggle`, "a/b", `a\b`} {
+		if _, err := cache.Path(Dependency{ID: id}); err == nil {
+			t.Errorf("Path(%q) should fail", id)
+		}
+	}
+
+	ts := newTestServer(t)
+	if _, _, err := ts.client.FetchDependency(Dependency{ID: "..", Manifest: "x"}, cache, DownloadOptions{}); err == nil {
+		t.Error("FetchDependency should reject an ID outside the cache")
+	}
+	if _, err := os.Stat(filepath.Dir(cache.Dir)); err != nil {
+		t.Errorf("parent of the cache removed: %v", err)
+	}
+}
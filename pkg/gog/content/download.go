@@ -0,0 +1,507 @@
+package content
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DownloadOptions chooses what Download installs.
+type DownloadOptions struct {
+	Languages  []string // language codes such as "en-US"; empty means en-US
+	ProductIDs []string // products whose depots to install; empty means the base game
+	Workers    int      // files fetched in parallel, default 4
+
+	// Progress is called with the bytes written so far and the total. Calls
+	// don't overlap.
+	Progress func(done, total int64)
+}
+
+// FileEntry is an item of a depot together with the product it belongs to,
+// which decides where its chunks are fetched from.
+type FileEntry struct {
+	Item
+	ProductID string
+	sfc       *Item // small files container of the item's depot
+}
+
+// SelectDepots returns the depots of m for languages and products, including
+// the offline depot.
+func (m *Manifest) SelectDepots(languages, productIDs []string) []Depot {
+	if len(languages) == 0 {
+		languages = []string{"en-US"}
+	}
+	if len(productIDs) == 0 {
+		productIDs = []string{m.BaseProductID}
+	}
+	wanted := make(map[string]bool, len(productIDs))
+	for _, id := range productIDs {
+		wanted[id] = true
+	}
+
+	var depots []Depot
+	for _, d := range m.Depots {
+		if !d.IsGogDepot && wanted[d.ProductID] && matchesLanguage(d.Languages, languages) {
+			depots = append(depots, d)
+		}
+	}
+	if m.OfflineDepot != nil && m.OfflineDepot.Manifest != "" && wanted[m.OfflineDepot.ProductID] {
+		depots = append(depots, *m.OfflineDepot)
+	}
+	return depots
+}
+
+// Languages lists the language codes the build's depots come in, in
+// manifest order, leaving out language-independent depots.
+func (m *Manifest) Languages() []string {
+	var langs []string
+	seen := make(map[string]bool)
+	for _, d := range m.Depots {
+		for _, l := range d.Languages {
+			if l != "*" && !seen[strings.ToLower(l)] {
+				seen[strings.ToLower(l)] = true
+				langs = append(langs, l)
+			}
+		}
+	}
+	return langs
+}
+
+// matchesLanguage reports whether a depot for depotLangs is needed for any of
+// want. "*" depots are language independent, and "en" matches "en-US".
+func matchesLanguage(depotLangs, want []string) bool {
+	if len(depotLangs) == 0 {
+		return true
+	}
+	for _, dl := range depotLangs {
+		if dl == "*" {
+			return true
+		}
+		for _, w := range want {
+			if strings.EqualFold(dl, w) || strings.EqualFold(strings.SplitN(dl, "-", 2)[0], w) || strings.EqualFold(dl, strings.SplitN(w, "-", 2)[0]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Files fetches the depot manifests of depots and returns their items,
+// sorted by path. Later depots win when several list the same path.
+func (c *Client) Files(depots []Depot) ([]FileEntry, error) {
+	byPath := make(map[string]FileEntry)
+	for _, d := range depots {
+		dm, err := c.DepotManifest(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, it := range dm.Depot.Items {
+			byPath[strings.ToLower(it.RelPath())] = FileEntry{Item: it, ProductID: d.ProductID, sfc: dm.Depot.SmallFilesContainer}
+		}
+	}
+	entries := make([]FileEntry, 0, len(byPath))
+	for _, e := range byPath {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].RelPath() < entries[j].RelPath() })
+	return entries, nil
+}
+
+// Download installs the files of m into dir and returns the paths written,
+// relative to dir and slash separated.
+func (c *Client) Download(m *Manifest, dir string, opts DownloadOptions) ([]string, error) {
+	depots := m.SelectDepots(opts.Languages, opts.ProductIDs)
+	if len(depots) == 0 {
+		return nil, fmt.Errorf("build %s has no depots for %s", m.BuildID, strings.Join(opts.Languages, ", "))
+	}
+	entries, err := c.Files(depots)
+	if err != nil {
+		return nil, err
+	}
+	return c.Write(entries, dir, opts)
+}
+
+// Write assembles entries into dir. Files are written next to their final
+// name first and renamed once complete and verified, so an interrupted run
+// never leaves a truncated file in place.
+func (c *Client) Write(entries []FileEntry, dir string, opts DownloadOptions) ([]string, error) {
+	return newFetcher(c).write(entries, dir, opts)
+}
+
+func (f *fetcher) write(entries []FileEntry, dir string, opts DownloadOptions) ([]string, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	var files, links []FileEntry
+	var total int64
+	for _, e := range entries {
+		switch e.Type {
+		case ItemDirectory:
+			p, err := safeJoin(root, e.RelPath())
+			if err != nil {
+				return nil, err
+			}
+			if err := os.MkdirAll(p, 0755); err != nil {
+				return nil, err
+			}
+		case ItemLink:
+			links = append(links, e)
+		case ItemFile:
+			files = append(files, e)
+			total += e.Size()
+		}
+	}
+
+	progress := progressFunc(opts, total)
+	err = f.each(files, opts.Workers, func(e FileEntry) error {
+		p, err := safeJoin(root, e.RelPath())
+		if err != nil {
+			return err
+		}
+		return f.writeFile(p, e, progress)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeLinks(root, links); err != nil {
+		return nil, err
+	}
+	return relPaths(files, links), nil
+}
+
+// progressFunc returns a callback adding to the bytes done so far and
+// reporting them to opts.Progress, one call at a time.
+func progressFunc(opts DownloadOptions, total int64) func(int64) {
+	var mu sync.Mutex
+	var done int64
+	return func(n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		done += n
+		if opts.Progress != nil {
+			opts.Progress(done, total)
+		}
+	}
+}
+
+// relPaths returns the sorted paths of entries.
+func relPaths(lists ...[]FileEntry) []string {
+	var paths []string
+	for _, l := range lists {
+		for _, e := range l {
+			paths = append(paths, e.RelPath())
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// writeLinks creates the symlinks among entries, refusing targets outside
+// root.
+func writeLinks(root string, links []FileEntry) error {
+	for _, e := range links {
+		p, err := safeJoin(root, e.RelPath())
+		if err != nil {
+			return err
+		}
+		target := strings.ReplaceAll(e.Target, `\`, "/")
+		if t := filepath.Join(filepath.Dir(p), filepath.FromSlash(target)); filepath.IsAbs(target) || !within(root, t) {
+			return fmt.Errorf("link %s points outside the install directory", e.RelPath())
+		}
+		_ = os.Remove(p)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return err
+		}
+		if err := os.Symlink(filepath.FromSlash(target), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetcher downloads chunks, caching secure links per product and small
+// files containers per depot. Chunks found in local are read from disk
+// instead.
+type fetcher struct {
+	client     *Client
+	link       func(productID string) (Endpoint, error) // CDN endpoint for a product's chunks
+	local      map[string]localChunk                    // by chunk MD5
+	downloaded atomic.Int64                             // compressed bytes fetched
+
+	mu         sync.Mutex
+	links      map[string]Endpoint
+	containers map[*Item]*container
+}
+
+// localChunk is a chunk already present in an installed file.
+type localChunk struct {
+	path   string
+	offset int64
+	size   int64
+}
+
+func newFetcher(c *Client) *fetcher {
+	return &fetcher{client: c, link: c.SecureLink, links: make(map[string]Endpoint), containers: make(map[*Item]*container)}
+}
+
+// each calls write for every entry, from workers goroutines, and stops at
+// the first error.
+func (f *fetcher) each(entries []FileEntry, workers int, write func(FileEntry) error) error {
+	if workers <= 0 {
+		workers = 4
+	}
+	jobs := make(chan FileEntry)
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	var failed atomic.Bool
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range jobs {
+				if failed.Load() {
+					continue
+				}
+				if err := write(e); err != nil {
+					failed.Store(true)
+					errs <- fmt.Errorf("%s: %w", e.RelPath(), err)
+				}
+			}
+		}()
+	}
+	for _, e := range entries {
+		jobs <- e
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+	return <-errs
+}
+
+type container struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// writeFile assembles e at p.
+func (f *fetcher) writeFile(p string, e FileEntry, progress func(int64)) error {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	tmp := p + ".goggle-part"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp) }()
+
+	sum := md5.New()
+	w := io.MultiWriter(out, sum)
+	if err := f.fill(w, e, progress); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if want := e.FileMD5(); want != "" && hex.EncodeToString(sum.Sum(nil)) != want {
+		return errors.New("checksum mismatch")
+	}
+	mode := os.FileMode(0644)
+	if e.Executable() {
+		mode = 0755
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p)
+}
+
+// fill writes the contents of e to w.
+func (f *fetcher) fill(w io.Writer, e FileEntry, progress func(int64)) error {
+	if e.SFCRef != nil && !f.haveLocally(e.Chunks) {
+		if e.sfc == nil {
+			return errors.New("small files container missing")
+		}
+		data, err := f.container(e.ProductID, e.sfc)
+		if err != nil {
+			return err
+		}
+		end := e.SFCRef.Offset + e.SFCRef.Size
+		if e.SFCRef.Offset < 0 || end > int64(len(data)) {
+			return errors.New("invalid small files container reference")
+		}
+		if _, err := w.Write(data[e.SFCRef.Offset:end]); err != nil {
+			return err
+		}
+		progress(e.SFCRef.Size)
+		return nil
+	}
+
+	for _, ch := range e.Chunks {
+		data, err := f.chunk(e.ProductID, ch)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		progress(int64(len(data)))
+	}
+	return nil
+}
+
+// container returns the assembled small files container, fetching it once.
+func (f *fetcher) container(productID string, sfc *Item) ([]byte, error) {
+	f.mu.Lock()
+	c, ok := f.containers[sfc]
+	if !ok {
+		c = &container{}
+		f.containers[sfc] = c
+	}
+	f.mu.Unlock()
+
+	c.once.Do(func() {
+		for _, ch := range sfc.Chunks {
+			b, err := f.chunk(productID, ch)
+			if err != nil {
+				c.err = err
+				return
+			}
+			c.data = append(c.data, b...)
+		}
+	})
+	return c.data, c.err
+}
+
+// haveLocally reports whether all of chunks can be read from disk.
+func (f *fetcher) haveLocally(chunks []Chunk) bool {
+	for _, ch := range chunks {
+		if _, ok := f.local[ch.MD5]; !ok {
+			return false
+		}
+	}
+	return len(chunks) > 0
+}
+
+func (f *fetcher) chunk(productID string, ch Chunk) ([]byte, error) {
+	if loc, ok := f.local[ch.MD5]; ok {
+		if data, err := readLocal(loc, ch); err == nil {
+			return data, nil
+		}
+		// Modified or missing since installed; download it instead
+	}
+
+	f.mu.Lock()
+	e, ok := f.links[productID]
+	f.mu.Unlock()
+	if !ok {
+		var err error
+		if e, err = f.refreshLink(productID); err != nil {
+			return nil, err
+		}
+	}
+	data, status, err := f.fetchChunk(e, ch)
+	if status == http.StatusForbidden || status == http.StatusGone {
+		// The signed link expired; get a new one and retry once
+		if e, err = f.refreshLink(productID); err != nil {
+			return nil, err
+		}
+		data, _, err = f.fetchChunk(e, ch)
+	}
+	return data, err
+}
+
+func (f *fetcher) refreshLink(productID string) (Endpoint, error) {
+	e, err := f.link(productID)
+	if err != nil {
+		return Endpoint{}, err
+	}
+	f.mu.Lock()
+	f.links[productID] = e
+	f.mu.Unlock()
+	return e, nil
+}
+
+// fetchChunk downloads and decompresses a chunk, verifying both checksums.
+func (f *fetcher) fetchChunk(e Endpoint, ch Chunk) ([]byte, int, error) {
+	resp, err := f.client.HTTPClient.Get(e.URL(ch.CompressedMD5))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != 200 {
+		return nil, resp.StatusCode, fmt.Errorf("chunk download failed with status %d", resp.StatusCode)
+	}
+	compressed, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, resp.StatusCode, err
+	}
+	f.downloaded.Add(int64(len(compressed)))
+	if !matchesMD5(md5.New(), compressed, ch.CompressedMD5) {
+		return nil, resp.StatusCode, fmt.Errorf("chunk %s is corrupt", ch.CompressedMD5)
+	}
+	data, err := inflate(compressed)
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("chunk %s: %w", ch.CompressedMD5, err)
+	}
+	if !matchesMD5(md5.New(), data, ch.MD5) {
+		return nil, resp.StatusCode, fmt.Errorf("chunk %s does not match its checksum", ch.MD5)
+	}
+	return data, resp.StatusCode, nil
+}
+
+// readLocal reads a chunk from an installed file, verifying it.
+func readLocal(loc localChunk, ch Chunk) ([]byte, error) {
+	file, err := os.Open(loc.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	data := make([]byte, loc.size)
+	if _, err := file.ReadAt(data, loc.offset); err != nil {
+		return nil, err
+	}
+	if !matchesMD5(md5.New(), data, ch.MD5) {
+		return nil, errors.New("local chunk does not match")
+	}
+	return data, nil
+}
+
+// matchesMD5 reports whether data hashes to want; an empty want matches.
+func matchesMD5(h hash.Hash, data []byte, want string) bool {
+	if want == "" {
+		return true
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)) == strings.ToLower(want)
+}
+
+// safeJoin joins a manifest path onto root, refusing paths that leave it.
+func safeJoin(root, rel string) (string, error) {
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if !within(root, p) || p == root {
+		return "", fmt.Errorf("invalid path %q in manifest", rel)
+	}
+	return p, nil
+}
+
+func within(root, p string) bool {
+	return p == root || strings.HasPrefix(p, root+string(filepath.Separator))
+}
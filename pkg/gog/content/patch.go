@@ -0,0 +1,322 @@
+package content
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stagingDir is where Patch assembles changed files inside the install
+// directory before moving them into place.
+const stagingDir = ".goggle-staging"
+
+// journalFile marks a fully staged patch; its presence means the staged
+// files only need moving into place.
+const journalFile = ".goggle-patch.json"
+
+// PatchResult describes an update applied by Patch.
+type PatchResult struct {
+	Files      []string `json:"files"`      // all files of the target build, relative to the install directory
+	Changed    []string `json:"changed"`    // files written
+	Removed    []string `json:"removed"`    // files of the installed build the target no longer ships
+	Downloaded int64    `json:"downloaded"` // compressed bytes fetched
+	FullSize   int64    `json:"full_size"`  // compressed bytes a full download would have fetched
+}
+
+// Saved is the number of bytes not downloaded thanks to the patch.
+func (r *PatchResult) Saved() int64 {
+	if r.Downloaded >= r.FullSize {
+		return 0
+	}
+	return r.FullSize - r.Downloaded
+}
+
+// patchPlan is what changes between two builds.
+type patchPlan struct {
+	changed []FileEntry
+	removed []string
+	links   []FileEntry
+	dirs    []FileEntry
+	files   []FileEntry // every file of the target
+}
+
+type journal struct {
+	Key     string   `json:"key"`
+	Changed []string `json:"changed"`
+	Removed []string `json:"removed"`
+}
+
+// fileKey identifies the contents of a file.
+func fileKey(it *Item) string {
+	key := it.FileMD5()
+	if key == "" {
+		hashes := make([]string, len(it.Chunks))
+		for i, ch := range it.Chunks {
+			hashes[i] = ch.MD5
+		}
+		key = strings.Join(hashes, ",")
+	}
+	if it.Executable() {
+		key += "+x"
+	}
+	return key
+}
+
+func planPatch(installed, target []FileEntry) patchPlan {
+	old := make(map[string]*Item, len(installed))
+	for i := range installed {
+		if installed[i].Type == ItemFile {
+			old[strings.ToLower(installed[i].RelPath())] = &installed[i].Item
+		}
+	}
+
+	var p patchPlan
+	kept := make(map[string]bool, len(target))
+	for _, e := range target {
+		kept[strings.ToLower(e.RelPath())] = true
+		switch e.Type {
+		case ItemDirectory:
+			p.dirs = append(p.dirs, e)
+		case ItemLink:
+			p.links = append(p.links, e)
+		case ItemFile:
+			p.files = append(p.files, e)
+			prev, ok := old[strings.ToLower(e.RelPath())]
+			if !ok || prev.RelPath() != e.RelPath() || fileKey(prev) != fileKey(&e.Item) {
+				p.changed = append(p.changed, e)
+			}
+		}
+	}
+	for _, e := range installed {
+		if e.Type != ItemDirectory && !kept[strings.ToLower(e.RelPath())] {
+			p.removed = append(p.removed, e.RelPath())
+		}
+	}
+	sort.Strings(p.removed)
+	return p
+}
+
+// key identifies the plan, so an interrupted run is only resumed by the
+// same update.
+func (p *patchPlan) key() string {
+	h := sha256.New()
+	for _, e := range p.changed {
+		fmt.Fprintf(h, "%s\x00%s\n", e.RelPath(), fileKey(&e.Item))
+	}
+	for _, r := range p.removed {
+		fmt.Fprintf(h, "-%s\n", r)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// fullSize is the compressed size of every chunk of files.
+func fullSize(files []FileEntry) int64 {
+	var n int64
+	containers := make(map[*Item]bool)
+	for _, e := range files {
+		if e.SFCRef != nil && e.sfc != nil {
+			if !containers[e.sfc] {
+				containers[e.sfc] = true
+				for _, ch := range e.sfc.Chunks {
+					n += ch.CompressedSize
+				}
+			}
+			continue
+		}
+		for _, ch := range e.Chunks {
+			n += ch.CompressedSize
+		}
+	}
+	return n
+}
+
+// localChunks indexes the chunks of the installed files in root.
+func localChunks(root string, installed []FileEntry) map[string]localChunk {
+	local := make(map[string]localChunk)
+	for _, e := range installed {
+		if e.Type != ItemFile {
+			continue
+		}
+		p, err := safeJoin(root, e.RelPath())
+		if err != nil {
+			continue
+		}
+		var offset int64
+		for _, ch := range e.Chunks {
+			if _, ok := local[ch.MD5]; !ok {
+				local[ch.MD5] = localChunk{path: p, offset: offset, size: ch.Size}
+			}
+			offset += ch.Size
+		}
+	}
+	return local
+}
+
+// Patch updates the install in dir from the installed build's files to the
+// target's. Only changed files are written, from chunks already on disk
+// where possible and the CDN otherwise.
+//
+// Changed files are assembled in a staging directory first, reading from
+// the untouched install, and moved into place once all of them are
+// complete. An interrupted run picks up where it stopped: staged files are
+// kept, and a fully staged update is finished without downloading again.
+func (c *Client) Patch(installed, target []FileEntry, dir string, opts DownloadOptions) (*PatchResult, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	plan := planPatch(installed, target)
+	staging := filepath.Join(root, stagingDir)
+	result := &PatchResult{
+		Files:    relPaths(plan.files, plan.links),
+		Changed:  relPaths(plan.changed),
+		Removed:  plan.removed,
+		FullSize: fullSize(plan.files),
+	}
+
+	j, err := readJournal(staging)
+	if err != nil || (j != nil && j.Key != plan.key()) {
+		// Left over from a different update
+		if err := os.RemoveAll(staging); err != nil {
+			return nil, err
+		}
+		j = nil
+	}
+	if j == nil {
+		var total int64
+		for _, e := range plan.changed {
+			total += e.Size()
+		}
+		f := newFetcher(c)
+		f.local = localChunks(root, installed)
+		progress := progressFunc(opts, total)
+		err := f.each(plan.changed, opts.Workers, func(e FileEntry) error {
+			p, err := safeJoin(staging, e.RelPath())
+			if err != nil {
+				return err
+			}
+			if stagedAlready(p, &e.Item) {
+				progress(e.Size())
+				return nil
+			}
+			return f.writeFile(p, e, progress)
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Downloaded = f.downloaded.Load()
+
+		j = &journal{Key: plan.key(), Changed: result.Changed, Removed: plan.removed}
+		if err := writeJournal(staging, j); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := commit(root, staging, j); err != nil {
+		return nil, err
+	}
+	for _, e := range plan.dirs {
+		p, err := safeJoin(root, e.RelPath())
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(p, 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeLinks(root, plan.links); err != nil {
+		return nil, err
+	}
+	return result, os.RemoveAll(staging)
+}
+
+// stagedAlready reports whether p holds the complete contents of it.
+func stagedAlready(p string, it *Item) bool {
+	want := it.FileMD5()
+	if want == "" {
+		return false
+	}
+	file, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer func() { _ = file.Close() }()
+	h := md5.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return false
+	}
+	return hex.EncodeToString(h.Sum(nil)) == want
+}
+
+// commit moves the staged files of j into root and deletes the removed
+// ones. Files already moved by an earlier, interrupted commit are skipped.
+func commit(root, staging string, j *journal) error {
+	for _, rel := range j.Changed {
+		src, err := safeJoin(staging, rel)
+		if err != nil {
+			return err
+		}
+		dst, err := safeJoin(root, rel)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(src); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(src, dst); err != nil {
+			return err
+		}
+	}
+	for _, rel := range j.Removed {
+		p, err := safeJoin(root, rel)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func readJournal(staging string) (*journal, error) {
+	data, err := os.ReadFile(filepath.Join(staging, journalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+func writeJournal(staging string, j *journal) error {
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(staging, journalFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
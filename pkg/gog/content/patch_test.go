@@ -0,0 +1,127 @@
+package content
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func file(path string, chunks ...Chunk) FileEntry {
+	return FileEntry{Item: Item{Type: ItemFile, Path: path, Chunks: chunks}, ProductID: "42"}
+}
+
+func TestPatch(t *testing.T) {
+	ts := newTestServer(t)
+	cdn := ts.cdn
+	part1 := bytes.Repeat([]byte("a"), 5000)
+	part2 := bytes.Repeat([]byte("b"), 5000)
+	part2New := bytes.Repeat([]byte("c"), 5000)
+
+	v1 := []FileEntry{
+		file(`data\big.pak`, cdn.chunk(part1), cdn.chunk(part2)),
+		file(`same.txt`, cdn.chunk([]byte("unchanged"))),
+		file(`old.txt`, cdn.chunk([]byte("going away"))),
+	}
+	v2 := []FileEntry{
+		file(`data\big.pak`, cdn.chunk(part1), cdn.chunk(part2New)),
+		file(`same.txt`, cdn.chunk([]byte("unchanged"))),
+		file(`new.txt`, cdn.chunk([]byte("brand new"))),
+		{Item: Item{Type: ItemDirectory, Path: `saves`}},
+	}
+
+	dir := t.TempDir()
+	if _, err := ts.client.Write(v1, dir, DownloadOptions{}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// The patch must read part1 from disk, so make it unavailable remotely
+	delete(cdn.objects, galaxyPath(v1[0].Chunks[0].CompressedMD5))
+
+	res, err := ts.client.Patch(v1, v2, dir, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if got := strings.Join(res.Changed, ","); got != "data/big.pak,new.txt" {
+		t.Errorf("Changed = %s", got)
+	}
+	if got := strings.Join(res.Removed, ","); got != "old.txt" {
+		t.Errorf("Removed = %s", got)
+	}
+	if got := strings.Join(res.Files, ","); got != "data/big.pak,new.txt,same.txt" {
+		t.Errorf("Files = %s", got)
+	}
+	want := v2[0].Chunks[1].CompressedSize + v2[2].Chunks[0].CompressedSize
+	if res.Downloaded != want {
+		t.Errorf("Downloaded = %d, want %d", res.Downloaded, want)
+	}
+	if res.Saved() != res.FullSize-want || res.Saved() == 0 {
+		t.Errorf("Saved = %d of %d", res.Saved(), res.FullSize)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "data", "big.pak"))
+	if err != nil || !bytes.Equal(data, append(append([]byte(nil), part1...), part2New...)) {
+		t.Errorf("big.pak not patched: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.txt")); !os.IsNotExist(err) {
+		t.Error("old.txt should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "saves")); err != nil {
+		t.Error("saves directory not created")
+	}
+	if _, err := os.Stat(filepath.Join(dir, stagingDir)); !os.IsNotExist(err) {
+		t.Error("staging directory left behind")
+	}
+}
+
+func TestPatchResumesStaged(t *testing.T) {
+	ts := newTestServer(t)
+	cdn := ts.cdn
+	v1 := []FileEntry{file(`a.txt`, cdn.chunk([]byte("one"))), file(`b.txt`, cdn.chunk([]byte("bee")))}
+	v2 := []FileEntry{file(`a.txt`, cdn.chunk([]byte("two"))), file(`b.txt`, cdn.chunk([]byte("bee")))}
+
+	dir := t.TempDir()
+	if _, err := ts.client.Write(v1, dir, DownloadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Interrupted after staging: a.txt is staged and the journal written
+	plan := planPatch(v1, v2)
+	staging := filepath.Join(dir, stagingDir)
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(staging, "a.txt"), []byte("two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJournal(staging, &journal{Key: plan.key(), Changed: []string{"a.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+	cdn.objects = map[string][]byte{} // nothing can be downloaded
+
+	res, err := ts.client.Patch(v1, v2, dir, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("Patch: %v", err)
+	}
+	if res.Downloaded != 0 {
+		t.Errorf("Downloaded = %d, want 0", res.Downloaded)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "two" {
+		t.Errorf("a.txt = %q", data)
+	}
+	if _, err := os.Stat(staging); !os.IsNotExist(err) {
+		t.Error("staging directory left behind")
+	}
+}
+
+func TestPlanPatchExecutableFlag(t *testing.T) {
+	a := file("run.sh", Chunk{MD5: "x"})
+	b := a
+	b.Flags = []string{"executable"}
+	if p := planPatch([]FileEntry{a}, []FileEntry{b}); len(p.changed) != 1 {
+		t.Errorf("flag change not detected: %+v", p.changed)
+	}
+	if p := planPatch([]FileEntry{a}, []FileEntry{a}); len(p.changed) != 0 {
+		t.Errorf("unchanged file planned: %+v", p.changed)
+	}
+}
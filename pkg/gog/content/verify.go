@@ -0,0 +1,148 @@
+package content
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/josh/goggle/pkg/gog"
+)
+
+// Report is the result of checking an install against its build.
+type Report struct {
+	Checked  int      `json:"checked"`
+	Missing  []string `json:"missing"`
+	Modified []string `json:"modified"`
+	Extra    []string `json:"extra"` // files the build doesn't ship, such as saves and mods
+
+	broken []FileEntry
+}
+
+// OK reports whether every file of the build is intact. Extra files don't
+// count.
+func (r *Report) OK() bool {
+	return len(r.Missing) == 0 && len(r.Modified) == 0
+}
+
+// Verify hashes the files in dir against entries. progress, if set, is
+// called with each path before it is checked.
+func Verify(entries []FileEntry, dir string, progress func(path string)) (*Report, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Missing: []string{}, Modified: []string{}, Extra: []string{}}
+	known := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		known[e.RelPath()] = true
+		if e.Type == ItemDirectory {
+			continue
+		}
+		if progress != nil {
+			progress(e.RelPath())
+		}
+		p, err := safeJoin(root, e.RelPath())
+		if err != nil {
+			return nil, err
+		}
+		r.Checked++
+		ok, err := intact(p, e)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			r.Missing = append(r.Missing, e.RelPath())
+			r.broken = append(r.broken, e)
+		case err != nil:
+			return nil, err
+		case !ok:
+			r.Modified = append(r.Modified, e.RelPath())
+			r.broken = append(r.broken, e)
+		}
+	}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel == stagingDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !known[rel] && rel != gog.ManifestName && !strings.HasSuffix(rel, ".goggle-part") {
+			r.Extra = append(r.Extra, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(r.Extra)
+	return r, nil
+}
+
+// intact reports whether the file at p matches e.
+func intact(p string, e FileEntry) (bool, error) {
+	info, err := os.Lstat(p)
+	if err != nil {
+		return false, err
+	}
+	if e.Type == ItemLink {
+		if runtime.GOOS == "windows" {
+			return true, nil
+		}
+		target, err := os.Readlink(p)
+		return err == nil && filepath.ToSlash(target) == strings.ReplaceAll(e.Target, `\`, "/"), nil
+	}
+	if !info.Mode().IsRegular() || info.Size() != e.Size() {
+		return false, nil
+	}
+	if runtime.GOOS != "windows" && e.Executable() && info.Mode().Perm()&0100 == 0 {
+		return false, nil
+	}
+
+	file, err := os.Open(p)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = file.Close() }()
+	if want := e.FileMD5(); want != "" {
+		h := md5.New()
+		if _, err := io.Copy(h, file); err != nil {
+			return false, err
+		}
+		return hex.EncodeToString(h.Sum(nil)) == want, nil
+	}
+	// No whole-file hash: check chunk by chunk
+	for _, ch := range e.Chunks {
+		h := md5.New()
+		if _, err := io.CopyN(h, file, ch.Size); err != nil {
+			return false, err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != strings.ToLower(ch.MD5) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Repair downloads the missing and modified files of r again and returns
+// their paths.
+func (c *Client) Repair(r *Report, dir string, opts DownloadOptions) ([]string, error) {
+	if len(r.broken) == 0 {
+		return nil, nil
+	}
+	return c.Write(r.broken, dir, opts)
+}
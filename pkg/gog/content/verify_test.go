@@ -0,0 +1,87 @@
+package content
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyAndRepair(t *testing.T) {
+	ts := newTestServer(t)
+	cdn := ts.cdn
+	big := bytes.Repeat([]byte("xyz"), 3000)
+	entries := []FileEntry{
+		file(`bin\game`, cdn.chunk(big[:4000]), cdn.chunk(big[4000:])),
+		file(`data\one.txt`, cdn.chunk([]byte("one"))),
+		file(`data\two.txt`, cdn.chunk([]byte("two"))),
+		file(`data\three.txt`, cdn.chunk([]byte("three"))),
+		{Item: Item{Type: ItemDirectory, Path: `saves`}},
+	}
+	dir := t.TempDir()
+	if _, err := ts.client.Write(entries, dir, DownloadOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Verify(entries, dir, nil)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !report.OK() || report.Checked != 4 || len(report.Extra) != 0 {
+		t.Fatalf("fresh install: %+v", report)
+	}
+
+	// Break things: a missing file, same-size and different-size edits, a
+	// corrupted chunk of a multi-chunk file and a save the user added
+	must(t, os.Remove(filepath.Join(dir, "data", "one.txt")))
+	must(t, os.WriteFile(filepath.Join(dir, "data", "two.txt"), []byte("TWO"), 0644))
+	must(t, os.WriteFile(filepath.Join(dir, "data", "three.txt"), []byte("3"), 0644))
+	corrupt := append([]byte(nil), big...)
+	corrupt[5000] = '!'
+	must(t, os.WriteFile(filepath.Join(dir, "bin", "game"), corrupt, 0644))
+	must(t, os.WriteFile(filepath.Join(dir, "saves", "slot1.sav"), []byte("save"), 0644))
+
+	var seen []string
+	report, err = Verify(entries, dir, func(p string) { seen = append(seen, p) })
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if len(seen) != 4 {
+		t.Errorf("progress called for %v", seen)
+	}
+	if got := strings.Join(report.Missing, ","); got != "data/one.txt" {
+		t.Errorf("Missing = %s", got)
+	}
+	if got := strings.Join(report.Modified, ","); got != "bin/game,data/two.txt,data/three.txt" {
+		t.Errorf("Modified = %s", got)
+	}
+	if got := strings.Join(report.Extra, ","); got != "saves/slot1.sav" {
+		t.Errorf("Extra = %s", got)
+	}
+
+	repaired, err := ts.client.Repair(report, dir, DownloadOptions{})
+	if err != nil {
+		t.Fatalf("Repair: %v", err)
+	}
+	if len(repaired) != 4 {
+		t.Errorf("repaired %v", repaired)
+	}
+	report, err = Verify(entries, dir, nil)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("after repair: %+v", report)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "saves", "slot1.sav")); string(data) != "save" {
+		t.Error("repair touched an extra file")
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,42 @@
+package gog
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FreeSpace reports the bytes available to the current user on the
+// filesystem holding path. path doesn't need to exist yet; the nearest
+// existing parent is used.
+func FreeSpace(path string) (uint64, error) {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return 0, fmt.Errorf("no existing parent directory for %s", path)
+		}
+		dir = parent
+	}
+	return freeSpace(dir)
+}
+
+// CheckFreeSpace returns an error if the filesystem holding dir has less than
+// need bytes available.
+func CheckFreeSpace(dir string, need int64) error {
+	free, err := FreeSpace(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check free space: %w", err)
+	}
+	if need > 0 && uint64(need) > free {
+		return fmt.Errorf("not enough free space in %s: need %s, have %s",
+			dir, FormatSize(need), FormatSize(int64(free)))
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !darwin && !linux && !windows
+
+package gog
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func freeSpace(dir string) (uint64, error) {
+	return 0, fmt.Errorf("free space check not supported on %s", runtime.GOOS)
+}
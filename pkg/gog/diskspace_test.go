@@ -0,0 +1,38 @@
+package gog
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	free, err := FreeSpace(dir)
+	if err != nil {
+		t.Fatalf("FreeSpace: %v", err)
+	}
+	if free == 0 {
+		t.Error("FreeSpace returned 0 for temp dir")
+	}
+
+	// Missing directories resolve to their nearest existing parent
+	missing, err := FreeSpace(filepath.Join(dir, "not", "yet", "created"))
+	if err != nil {
+		t.Fatalf("FreeSpace on missing dir: %v", err)
+	}
+	if missing == 0 {
+		t.Error("FreeSpace returned 0 for missing dir")
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := CheckFreeSpace(dir, 1); err != nil {
+		t.Errorf("CheckFreeSpace(1 byte): %v", err)
+	}
+	if err := CheckFreeSpace(dir, 1<<62); err == nil {
+		t.Error("CheckFreeSpace(4 EB) should fail")
+	}
+}
@@ -17,7 +17,20 @@ import (
 
 type GameDetails struct {
 	Title     string          `json:"title"`
+	Changelog string          `json:"changelog"` // HTML, newest entry first
+	CDKey     string          `json:"cdKey"`     // plain or HTML, see Keys
 	Downloads json.RawMessage `json:"downloads"`
+	Extras    []Extra         `json:"extras"`
+	DLCs      []GameDetails   `json:"dlcs"`
+}
+
+// Extra is a bonus file such as a soundtrack, manual or, for movies, a
+// behind-the-scenes video.
+type Extra struct {
+	ManualURL string `json:"manualUrl"`
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Size      string `json:"size"`
 }
 
 type Installer struct {
@@ -25,8 +38,12 @@ type Installer struct {
 	Name      string `json:"name"`
 	Version   string `json:"version"`
 	Size      string `json:"size"`
+	Bytes     int64  `json:"bytes"` // filled in during parsing from Size
 	OS        string // filled in during parsing
-	Language  string // filled in during parsing
+	Language  string // filled in during parsing, as GOG names it, e.g. "français"
+	// LanguageCode is Language as a code such as "fr-FR", or "" for
+	// languages LanguageCode doesn't know.
+	LanguageCode string
 }
 
 type DownlinkResponse struct {
@@ -77,15 +94,22 @@ func ParseInstallers(details *GameDetails) ([]Installer, error) {
 			return nil, err
 		}
 
+		// Movies list their files directly instead of per OS
 		var osSets map[string][]Installer
 		if err := json.Unmarshal(pair[1], &osSets); err != nil {
-			return nil, err
+			var files []Installer
+			if json.Unmarshal(pair[1], &files) != nil {
+				return nil, err
+			}
+			osSets = map[string][]Installer{"": files}
 		}
 
 		for osName, osInstallers := range osSets {
 			for _, inst := range osInstallers {
 				inst.OS = osName
 				inst.Language = language
+				inst.LanguageCode = LanguageCode(language)
+				inst.Bytes, _ = ParseSize(inst.Size)
 				installers = append(installers, inst)
 			}
 		}
@@ -115,29 +139,38 @@ func FilterInstallersByOS(installers []Installer, targetOS string) []Installer {
 }
 
 func (c *Client) ResolveDownloadURL(manualURL string) (string, error) {
+	dl, err := c.ResolveDownload(manualURL)
+	if err != nil {
+		return "", err
+	}
+	return dl.Downlink, nil
+}
+
+// ResolveDownload resolves manualURL to a CDN link. Checksum is the URL of
+// the file's checksum XML when GOG answers with JSON rather than a redirect.
+func (c *Client) ResolveDownload(manualURL string) (*DownlinkResponse, error) {
 	rawURL := c.embedBaseURL() + manualURL
 
 	// Don't follow redirects — we want the Location header
-	noRedirectClient := &http.Client{
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
-		},
+	noRedirectClient := *c.HTTPClient
+	noRedirectClient.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
 	}
 
 	req, err := http.NewRequest("GET", rawURL, nil)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if c.Token.Expired() {
 		if err := c.RefreshAuth(); err != nil {
-			return "", err
+			return nil, err
 		}
 	}
 	req.Header.Set("Authorization", "Bearer "+c.Token.AccessToken)
 
 	resp, err := noRedirectClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
@@ -145,21 +178,40 @@ func (c *Client) ResolveDownloadURL(manualURL string) (string, error) {
 	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
 		loc := resp.Header.Get("Location")
 		if loc == "" {
-			return "", fmt.Errorf("redirect with no Location header")
+			return nil, fmt.Errorf("redirect with no Location header")
 		}
-		return loc, nil
+		return &DownlinkResponse{Downlink: loc}, nil
 	}
 
 	if resp.StatusCode != 200 {
 		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("failed to resolve download URL (%d): %s", resp.StatusCode, body)
+		return nil, fmt.Errorf("failed to resolve download URL (%d): %s", resp.StatusCode, body)
 	}
 
 	var dl DownlinkResponse
 	if err := json.NewDecoder(resp.Body).Decode(&dl); err != nil {
-		return "", err
+		return nil, err
 	}
-	return dl.Downlink, nil
+	return &dl, nil
+}
+
+// ContentLength asks the CDN for the exact size of a resolved download URL.
+// It returns -1 if the server doesn't report one.
+func (c *Client) ContentLength(downloadURL string) (int64, error) {
+	req, err := http.NewRequest(http.MethodHead, downloadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return 0, fmt.Errorf("size request failed with status %d", resp.StatusCode)
+	}
+	return resp.ContentLength, nil
 }
 
 type ProgressWriter struct {
@@ -181,12 +233,18 @@ func (pw *ProgressWriter) Write(p []byte) (int, error) {
 }
 
 func (c *Client) DownloadFile(downloadURL, destDir string) (string, error) {
+	return c.DownloadFileAs(downloadURL, destDir, "")
+}
+
+// DownloadFileAs is DownloadFile saving to name plus the extension of the
+// server's file name. An empty name keeps the server's name.
+func (c *Client) DownloadFileAs(downloadURL, destDir, name string) (string, error) {
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create directory: %w", err)
 	}
 
 	// The CDN URL from ResolveDownloadURL is a direct download, no auth needed
-	resp, err := http.Get(downloadURL)
+	resp, err := c.HTTPClient.Get(downloadURL)
 	if err != nil {
 		return "", fmt.Errorf("download request failed: %w", err)
 	}
@@ -206,6 +264,9 @@ func (c *Client) DownloadFile(downloadURL, destDir string) (string, error) {
 		filename = filename[:idx]
 	}
 
+	if name != "" {
+		filename = name + filepath.Ext(filename)
+	}
 	destPath := filepath.Join(destDir, filename)
 	f, err := os.Create(destPath)
 	if err != nil {
@@ -31,6 +31,9 @@ func TestParseInstallers(t *testing.T) {
 			if inst.ManualURL == "" {
 				t.Error("ManualURL should not be empty")
 			}
+			if want := map[string]string{"English": "en-US", "French": "fr-FR"}[inst.Language]; inst.LanguageCode != want {
+				t.Errorf("%s: LanguageCode = %q, want %q", inst.Language, inst.LanguageCode, want)
+			}
 		}
 		if !found["windows/English"] {
 			t.Error("missing windows/English installer")
@@ -41,6 +44,11 @@ func TestParseInstallers(t *testing.T) {
 		if !found["windows/French"] {
 			t.Error("missing windows/French installer")
 		}
+		for _, inst := range installers {
+			if inst.OS == "mac" && inst.Bytes != 900<<20 {
+				t.Errorf("mac Bytes = %d, want %d", inst.Bytes, 900<<20)
+			}
+		}
 	})
 
 	t.Run("empty downloads", func(t *testing.T) {
@@ -174,3 +182,23 @@ func TestResolveDownloadURL(t *testing.T) {
 		}
 	})
 }
+
+func TestContentLength(t *testing.T) {
+	// TLS, so the request only succeeds through the client's own transport
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("method = %s, want HEAD", r.Method)
+		}
+		w.Header().Set("Content-Length", "123456")
+	}))
+	defer ts.Close()
+
+	c := &Client{HTTPClient: ts.Client()}
+	got, err := c.ContentLength(ts.URL + "/setup.exe")
+	if err != nil {
+		t.Fatalf("ContentLength: %v", err)
+	}
+	if got != 123456 {
+		t.Errorf("ContentLength = %d, want 123456", got)
+	}
+}
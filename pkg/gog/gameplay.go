@@ -0,0 +1,242 @@
+package gog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// UserData is the logged-in user's account.
+type UserData struct {
+	Username     string `json:"username"`
+	GalaxyUserID string `json:"galaxyUserId"`
+}
+
+func (c *Client) GetUserData() (*UserData, error) {
+	resp, err := c.AuthGet(c.embedBaseURL() + "/userData.json")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get user data (%d): %s", resp.StatusCode, body)
+	}
+	var data UserData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	if data.GalaxyUserID == "" {
+		return nil, errors.New("not logged in")
+	}
+	return &data, nil
+}
+
+// Achievement is one achievement of a game and whether the user has it.
+type Achievement struct {
+	Key         string     `json:"key"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Visible     bool       `json:"visible"` // hidden achievements stay secret until unlocked
+	UnlockedAt  *time.Time `json:"unlocked_at,omitempty"`
+	Rarity      float64    `json:"rarity"`       // percentage of players who have it
+	RarityLevel string     `json:"rarity_level"` // e.g. "Common", "Rare"
+}
+
+type achievementsResponse struct {
+	Items []struct {
+		Key         string  `json:"achievement_key"`
+		Name        string  `json:"name"`
+		Description string  `json:"description"`
+		Visible     bool    `json:"visible"`
+		Unlocked    string  `json:"date_unlocked"`
+		Rarity      float64 `json:"rarity"`
+		RarityLevel string  `json:"rarity_level_description"`
+	} `json:"items"`
+}
+
+// gameplayTime parses the gameplay API's times, which lack the colon in
+// their zone offset ("2021-05-01T18:30:00+0000").
+func gameplayTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02T15:04:05-0700", s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// GetAchievements returns the achievements of the game with the Galaxy
+// client ID clientID for the user with the Galaxy user ID userID, in the
+// game's order.
+func (c *Client) GetAchievements(clientID, userID string) ([]Achievement, error) {
+	u := fmt.Sprintf("%s/clients/%s/users/%s/achievements", c.gameplayURL(), url.PathEscape(clientID), url.PathEscape(userID))
+	resp, err := c.AuthGet(u)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get achievements (%d): %s", resp.StatusCode, body)
+	}
+	var result achievementsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	achievements := make([]Achievement, len(result.Items))
+	for i, it := range result.Items {
+		achievements[i] = Achievement{
+			Key:         it.Key,
+			Name:        it.Name,
+			Description: it.Description,
+			Visible:     it.Visible,
+			Rarity:      it.Rarity,
+			RarityLevel: it.RarityLevel,
+		}
+		if it.Unlocked != "" {
+			t, err := gameplayTime(it.Unlocked)
+			if err != nil {
+				return nil, fmt.Errorf("achievement %s: %w", it.Key, err)
+			}
+			achievements[i].UnlockedAt = &t
+		}
+	}
+	return achievements, nil
+}
+
+// Playtime is how much the user has played one game.
+type Playtime struct {
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Minutes      int        `json:"minutes"`
+	LastSession  *time.Time `json:"last_session,omitempty"`
+	Achievements int        `json:"achievements"` // unlocked ones
+}
+
+type gameStatsResponse struct {
+	Pages    int `json:"pages"`
+	Embedded struct {
+		Items []struct {
+			Game struct {
+				ID    json.Number `json:"id"`
+				Title string      `json:"title"`
+			} `json:"game"`
+			Stats map[string]struct {
+				Playtime     int    `json:"playtime"`
+				LastSession  string `json:"lastSession"`
+				Achievements int    `json:"achievements"`
+			} `json:"stats"`
+		} `json:"items"`
+	} `json:"_embedded"`
+}
+
+// GetPlaytime returns the play statistics GOG keeps for username's games,
+// most played first. Games never played are left out.
+func (c *Client) GetPlaytime(username string) ([]Playtime, error) {
+	var all []Playtime
+	for page, pages := 1, 1; page <= pages; page++ {
+		q := url.Values{"sort": {"total_playtime"}, "order": {"desc"}, "page": {strconv.Itoa(page)}}
+		u := fmt.Sprintf("%s/u/%s/games/stats?%s", c.embedBaseURL(), url.PathEscape(username), q.Encode())
+		resp, err := c.AuthGet(u)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("failed to get play statistics (%d): %s", resp.StatusCode, body)
+		}
+		var result gameStatsResponse
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		pages = result.Pages
+
+		for _, it := range result.Embedded.Items {
+			id, _ := strconv.Atoi(it.Game.ID.String())
+			p := Playtime{ID: id, Title: it.Game.Title}
+			// Stats are keyed by user ID; a user's own page has just theirs
+			for _, s := range it.Stats {
+				p.Minutes, p.Achievements = s.Playtime, s.Achievements
+				if t, err := gameplayTime(s.LastSession); err == nil {
+					p.LastSession = &t
+				}
+			}
+			if p.Minutes > 0 {
+				all = append(all, p)
+			}
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Minutes > all[j].Minutes })
+	return all, nil
+}
+
+// FormatPlaytime formats minutes as e.g. "12h 05m".
+func FormatPlaytime(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("%dh %02dm", minutes/60, minutes%60)
+}
+
+// WriteAchievements writes achievements as "json" or "csv".
+func WriteAchievements(w io.Writer, achievements []Achievement, format string) error {
+	switch format {
+	case "json":
+		if achievements == nil {
+			achievements = []Achievement{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(achievements)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"key", "name", "description", "unlocked_at", "rarity", "rarity_level"})
+		for _, a := range achievements {
+			_ = cw.Write([]string{a.Key, a.Name, a.Description, csvTime(a.UnlockedAt), strconv.FormatFloat(a.Rarity, 'f', -1, 64), a.RarityLevel})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q (use json or csv)", format)
+	}
+}
+
+// WritePlaytime writes play statistics as "json" or "csv".
+func WritePlaytime(w io.Writer, playtime []Playtime, format string) error {
+	switch format {
+	case "json":
+		if playtime == nil {
+			playtime = []Playtime{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(playtime)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"id", "title", "minutes", "last_session", "achievements"})
+		for _, p := range playtime {
+			_ = cw.Write([]string{strconv.Itoa(p.ID), p.Title, strconv.Itoa(p.Minutes), csvTime(p.LastSession), strconv.Itoa(p.Achievements)})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q (use json or csv)", format)
+	}
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,118 @@
+package gog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetAchievements(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/clients/5017/users/4242/achievements" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test_token" {
+			t.Errorf("missing auth header")
+		}
+		fmt.Fprint(w, `{"total_count": 2, "items": [
+			{"achievement_key": "first_blood", "name": "First Blood", "description": "Win a fight", "visible": true,
+			 "date_unlocked": "2021-05-01T18:30:00+0000", "rarity": 71.2, "rarity_level_description": "Common"},
+			{"achievement_key": "secret", "name": "Secret", "description": "", "visible": false,
+			 "date_unlocked": null, "rarity": 1.5, "rarity_level_description": "Ultra rare"}]}`)
+	}))
+	defer ts.Close()
+	c := newTestClient(ts)
+	c.GameplayURL = ts.URL
+
+	achievements, err := c.GetAchievements("5017", "4242")
+	if err != nil {
+		t.Fatalf("GetAchievements: %v", err)
+	}
+	if len(achievements) != 2 {
+		t.Fatalf("got %d achievements, want 2", len(achievements))
+	}
+	first := achievements[0]
+	want := time.Date(2021, 5, 1, 18, 30, 0, 0, time.UTC)
+	if first.UnlockedAt == nil || !first.UnlockedAt.Equal(want) || first.Rarity != 71.2 || first.RarityLevel != "Common" {
+		t.Errorf("first = %+v", first)
+	}
+	if achievements[1].UnlockedAt != nil || achievements[1].Visible {
+		t.Errorf("secret = %+v", achievements[1])
+	}
+
+	var buf bytes.Buffer
+	if err := WriteAchievements(&buf, achievements, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "key,name,description,unlocked_at,rarity,rarity_level\n" +
+		"first_blood,First Blood,Win a fight,2021-05-01T18:30:00Z,71.2,Common\n" +
+		"secret,Secret,,,1.5,Ultra rare\n"
+	if buf.String() != wantCSV {
+		t.Errorf("csv =\n%s\nwant\n%s", buf.String(), wantCSV)
+	}
+}
+
+func TestGetPlaytime(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/userData.json":
+			fmt.Fprint(w, `{"username": "jo", "galaxyUserId": "4242"}`)
+		case r.URL.Path == "/u/jo/games/stats" && r.URL.Query().Get("page") == "1":
+			fmt.Fprint(w, `{"page": 1, "pages": 2, "_embedded": {"items": [
+				{"game": {"id": "1", "title": "Short"}, "stats": {"4242": {"playtime": 45, "lastSession": "2024-02-03T10:00:00+00:00", "achievements": 3}}},
+				{"game": {"id": "2", "title": "Unplayed"}, "stats": {"4242": {"playtime": 0}}}]}}`)
+		case r.URL.Path == "/u/jo/games/stats" && r.URL.Query().Get("page") == "2":
+			fmt.Fprint(w, `{"page": 2, "pages": 2, "_embedded": {"items": [
+				{"game": {"id": 3, "title": "Long"}, "stats": {"4242": {"playtime": 725}}}]}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	c := newTestClient(ts)
+
+	user, err := c.GetUserData()
+	if err != nil {
+		t.Fatalf("GetUserData: %v", err)
+	}
+	playtime, err := c.GetPlaytime(user.Username)
+	if err != nil {
+		t.Fatalf("GetPlaytime: %v", err)
+	}
+	if len(playtime) != 2 || playtime[0].Title != "Long" || playtime[0].ID != 3 || playtime[1].Minutes != 45 {
+		t.Fatalf("playtime = %+v", playtime)
+	}
+	if playtime[1].LastSession == nil || playtime[1].Achievements != 3 || playtime[0].LastSession != nil {
+		t.Errorf("sessions = %+v", playtime)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePlaytime(&buf, playtime, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "3,Long,725,,0\n") || !strings.Contains(buf.String(), "1,Short,45,2024-02-03T10:00:00Z,3\n") {
+		t.Errorf("csv =\n%s", buf.String())
+	}
+}
+
+func TestFormatPlaytime(t *testing.T) {
+	tests := []struct {
+		minutes int
+		want    string
+	}{
+		{0, "0m"},
+		{59, "59m"},
+		{60, "1h 00m"},
+		{725, "12h 05m"},
+	}
+	for _, tt := range tests {
+		if got := FormatPlaytime(tt.minutes); got != tt.want {
+			t.Errorf("FormatPlaytime(%d) = %q, want %q", tt.minutes, got, tt.want)
+		}
+	}
+}
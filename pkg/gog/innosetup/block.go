@@ -0,0 +1,170 @@
+package innosetup
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// Header data is stored in compressed blocks: a CRC-protected size/flag
+// record followed by the payload split into 4 KiB pieces, each prefixed with
+// its own CRC32.
+const blockChunkSize = 4096
+
+// readBlock reads one compressed block from r and returns its contents.
+func readBlock(r io.Reader) ([]byte, error) {
+	var hdr [9]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read block header: %w", err)
+	}
+	le := binary.LittleEndian
+	if crc32.ChecksumIEEE(hdr[4:]) != le.Uint32(hdr[0:]) {
+		return nil, errors.New("block header checksum mismatch")
+	}
+	stored := le.Uint32(hdr[4:])
+	compressed := hdr[8] != 0
+
+	var payload bytes.Buffer
+	remaining := int64(stored)
+	buf := make([]byte, 4+blockChunkSize)
+	for remaining > 0 {
+		n := int64(len(buf))
+		if remaining < n {
+			n = remaining
+		}
+		if n <= 4 {
+			return nil, errors.New("truncated block")
+		}
+		if _, err := io.ReadFull(r, buf[:n]); err != nil {
+			return nil, fmt.Errorf("failed to read block: %w", err)
+		}
+		if crc32.ChecksumIEEE(buf[4:n]) != le.Uint32(buf) {
+			return nil, errors.New("block data checksum mismatch")
+		}
+		payload.Write(buf[4:n])
+		remaining -= n
+	}
+
+	if !compressed {
+		return payload.Bytes(), nil
+	}
+	return decodeLZMA1(&payload)
+}
+
+// decodeLZMA1 decompresses a raw LZMA stream as written by Inno Setup: the
+// 5 byte property header with no size and no end marker.
+func decodeLZMA1(r io.Reader) ([]byte, error) {
+	lr, err := newLZMA1Reader(r)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if _, err := io.Copy(&out, lr); err != nil {
+		return nil, fmt.Errorf("failed to decompress header: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+func newLZMA1Reader(r io.Reader) (io.Reader, error) {
+	var props [5]byte
+	if _, err := io.ReadFull(r, props[:]); err != nil {
+		return nil, err
+	}
+	// Rebuild the classic .lzma header with an unknown size
+	hdr := append(props[:], 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+	lr, err := lzma.NewReader(io.MultiReader(bytes.NewReader(hdr), r))
+	if err != nil {
+		return nil, err
+	}
+	return &drainReader{r: lr}, nil
+}
+
+// drainReader turns the decoder's "unexpected EOF" at the end of an
+// unterminated stream into a clean EOF. The decoder keeps the bytes it
+// produced before running out of input, so they are still returned.
+type drainReader struct {
+	r io.Reader
+}
+
+func (d *drainReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		if n > 0 {
+			return n, nil
+		}
+		return d.r.Read(p)
+	}
+	return n, err
+}
+
+// cursor decodes the little-endian records of a decompressed header.
+type cursor struct {
+	b   []byte
+	pos int
+	err error
+}
+
+func (c *cursor) take(n int) []byte {
+	if c.err != nil {
+		return nil
+	}
+	if n < 0 || c.pos+n > len(c.b) {
+		c.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := c.b[c.pos : c.pos+n]
+	c.pos += n
+	return b
+}
+
+func (c *cursor) u16() uint16 {
+	if b := c.take(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (c *cursor) u32() uint32 {
+	if b := c.take(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (c *cursor) u64() uint64 {
+	if b := c.take(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+// raw reads a length-prefixed byte string (ANSI and binary fields).
+func (c *cursor) raw() []byte {
+	return c.take(int(c.u32()))
+}
+
+// str reads a length-prefixed UTF-16LE string.
+func (c *cursor) str() string {
+	return decodeUTF16(c.raw())
+}
+
+// skipStrings skips n length-prefixed fields.
+func (c *cursor) skipStrings(n int) {
+	for i := 0; i < n; i++ {
+		c.raw()
+	}
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
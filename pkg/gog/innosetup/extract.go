@@ -0,0 +1,396 @@
+package innosetup
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/zlib"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+var (
+	chunkMagic  = []byte("zlb\x1a")
+	sliceMagics = [][]byte{[]byte("idska32\x1a"), []byte("idska16\x1a")}
+)
+
+const sliceHeaderSize = 12
+
+// Extract writes the game files into dir and returns the paths it created
+// relative to dir. langs limits language-specific files as in FilesFor.
+// Entries sharing a destination, such as the language variants of a file
+// when langs is empty, are written once: the first entry wins.
+func (s *Setup) Extract(dir string, langs []string) ([]string, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	// Files are packed into solid chunks, so they have to be read in the
+	// order they appear in each chunk.
+	type chunkKey struct{ slice, offset uint32 }
+	chunks := make(map[chunkKey][]File)
+	var keys []chunkKey
+	dests := make(map[string]bool)
+	for _, f := range s.FilesFor(langs) {
+		// Windows paths ignore case
+		dest := strings.ToLower(f.Path)
+		if dests[dest] {
+			continue
+		}
+		dests[dest] = true
+		k := chunkKey{f.location.firstSlice, f.location.chunkOffset}
+		if _, ok := chunks[k]; !ok {
+			keys = append(keys, k)
+		}
+		chunks[k] = append(chunks[k], f)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].slice != keys[j].slice {
+			return keys[i].slice < keys[j].slice
+		}
+		return keys[i].offset < keys[j].offset
+	})
+
+	var written []string
+	for _, k := range keys {
+		files := chunks[k]
+		sort.SliceStable(files, func(i, j int) bool {
+			return files[i].location.fileOffset < files[j].location.fileOffset
+		})
+		paths, err := s.extractChunk(root, files)
+		written = append(written, paths...)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+func (s *Setup) extractChunk(root string, files []File) ([]string, error) {
+	loc := files[0].location
+	if loc.flags&flagChunkEncrypted != 0 {
+		return nil, fmt.Errorf("%s is encrypted", files[0].Path)
+	}
+
+	sr, err := s.openSlices(loc.firstSlice, loc.chunkOffset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = sr.Close() }()
+
+	var magic [4]byte
+	if _, err := io.ReadFull(sr, magic[:]); err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if !bytes.Equal(magic[:], chunkMagic) {
+		return nil, errors.New("bad chunk signature")
+	}
+	var chunk io.Reader = io.LimitReader(sr, int64(loc.chunkSize))
+	if loc.flags&flagChunkCompressed != 0 {
+		if chunk, err = decompressor(s.compression, chunk); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		written []string
+		pos     uint64
+		last    *File
+		lastDst string
+	)
+	for i := range files {
+		f := &files[i]
+		dest := filepath.Join(root, filepath.FromSlash(f.Path))
+		if !strings.HasPrefix(dest, root+string(filepath.Separator)) {
+			return written, fmt.Errorf("refusing to extract %s outside %s", f.Path, root)
+		}
+
+		// Several entries can share one stored copy of a file
+		if last != nil && last.location.fileOffset == f.location.fileOffset {
+			if err := copyFile(lastDst, dest, f); err != nil {
+				return written, fmt.Errorf("failed to extract %s: %w", f.Path, err)
+			}
+			written = append(written, f.Path)
+			continue
+		}
+
+		if skip := f.location.fileOffset - pos; skip > 0 {
+			if _, err := io.CopyN(io.Discard, chunk, int64(skip)); err != nil {
+				return written, fmt.Errorf("failed to read chunk: %w", err)
+			}
+		}
+		var data io.Reader = io.LimitReader(chunk, int64(f.location.size))
+		if f.location.flags&flagCallInstructionOptimized != 0 {
+			data = &callReader{r: data}
+		}
+		if err := writeFile(dest, data, f); err != nil {
+			return written, fmt.Errorf("failed to extract %s: %w", f.Path, err)
+		}
+		pos = f.location.fileOffset + f.location.size
+		last, lastDst = f, dest
+		written = append(written, f.Path)
+	}
+	return written, nil
+}
+
+func writeFile(dest string, r io.Reader, f *File) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+
+	var h hash.Hash
+	switch len(f.Checksum) {
+	case sha1.Size:
+		h = sha1.New()
+	case sha256.Size:
+		h = sha256.New()
+	}
+	w := io.Writer(out)
+	if h != nil {
+		w = io.MultiWriter(out, h)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if n != f.Size {
+		return fmt.Errorf("truncated: got %d of %d bytes", n, f.Size)
+	}
+	if h != nil && !bytes.Equal(h.Sum(nil), f.Checksum) {
+		return errors.New("checksum mismatch")
+	}
+	if !f.ModTime.IsZero() {
+		return os.Chtimes(dest, f.ModTime, f.ModTime)
+	}
+	return nil
+}
+
+func copyFile(src, dest string, f *File) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+	return writeFile(dest, in, f)
+}
+
+// decompressor returns a reader for a chunk compressed with method.
+func decompressor(method compression, r io.Reader) (io.Reader, error) {
+	switch method {
+	case compressStored:
+		return r, nil
+	case compressZlib:
+		return zlib.NewReader(r)
+	case compressBzip2:
+		return bzip2.NewReader(r), nil
+	case compressLZMA1:
+		return newLZMA1Reader(r)
+	case compressLZMA2:
+		// One byte of dictionary size, then the chunked stream
+		var p [1]byte
+		if _, err := io.ReadFull(r, p[:]); err != nil {
+			return nil, fmt.Errorf("failed to read chunk: %w", err)
+		}
+		cfg := lzma.Reader2Config{DictCap: lzma2DictSize(p[0])}
+		return cfg.NewReader2(r)
+	default:
+		return nil, fmt.Errorf("unknown compression method %d", method)
+	}
+}
+
+func lzma2DictSize(p byte) int {
+	if p >= 40 {
+		return 0xffffffff
+	}
+	return (2 | int(p&1)) << (p/2 + 11)
+}
+
+// openSlices returns a reader positioned at offset in the given data slice
+// that continues into the following slices.
+func (s *Setup) openSlices(slice, offset uint32) (io.ReadCloser, error) {
+	if s.dataOffset != 0 {
+		// Data embedded in the executable is a single slice
+		return io.NopCloser(io.NewSectionReader(s.f, s.dataOffset+int64(offset), 1<<62)), nil
+	}
+	sr := &sliceReader{s: s, slice: slice}
+	if err := sr.open(int64(offset)); err != nil {
+		return nil, err
+	}
+	return sr, nil
+}
+
+// sliceReader reads across the setup-N.bin files next to the installer.
+type sliceReader struct {
+	s     *Setup
+	slice uint32
+	f     *os.File
+	r     io.Reader
+}
+
+func (sr *sliceReader) open(offset int64) error {
+	path, err := sr.s.slicePath(sr.slice)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	var hdr [sliceHeaderSize]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	known := false
+	for _, magic := range sliceMagics {
+		if bytes.Equal(hdr[:8], magic) {
+			known = true
+		}
+	}
+	if !known {
+		_ = f.Close()
+		return fmt.Errorf("%s is not an Inno Setup data slice", filepath.Base(path))
+	}
+	size := int64(binary.LittleEndian.Uint32(hdr[8:]))
+	if offset < sliceHeaderSize {
+		offset = sliceHeaderSize
+	}
+	if offset > size {
+		_ = f.Close()
+		return fmt.Errorf("offset %d beyond end of %s", offset, filepath.Base(path))
+	}
+	if sr.f != nil {
+		_ = sr.f.Close()
+	}
+	sr.f = f
+	sr.r = io.NewSectionReader(f, offset, size-offset)
+	return nil
+}
+
+func (sr *sliceReader) Read(p []byte) (int, error) {
+	n, err := sr.r.Read(p)
+	if err == io.EOF && n == 0 {
+		sr.slice++
+		if err := sr.open(0); err != nil {
+			return 0, err
+		}
+		return sr.r.Read(p)
+	}
+	return n, err
+}
+
+func (sr *sliceReader) Close() error {
+	if sr.f == nil {
+		return nil
+	}
+	return sr.f.Close()
+}
+
+// slicePath finds the file holding a data slice: <installer>-N.bin, or the
+// name recorded in the setup header if the installer was renamed.
+func (s *Setup) slicePath(slice uint32) (string, error) {
+	dir := filepath.Dir(s.path)
+	stem := strings.TrimSuffix(filepath.Base(s.path), filepath.Ext(s.path))
+	candidates := []string{stem}
+	if s.baseName != "" && s.baseName != stem {
+		candidates = append(candidates, s.baseName)
+	}
+	for _, base := range candidates {
+		path := filepath.Join(dir, fmt.Sprintf("%s-%d.bin", base, slice+1))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("missing data file %s-%d.bin", stem, slice+1)
+}
+
+// callBlockSize is the buffer size Inno Setup applies the call instruction
+// filter to; instructions spanning two blocks are left untouched.
+const callBlockSize = 0x10000
+
+// callReader undoes the x86 CALL/JMP address transform applied to
+// executables before compression.
+type callReader struct {
+	r      io.Reader
+	buf    []byte
+	offset uint32
+	err    error
+}
+
+func (c *callReader) Read(p []byte) (int, error) {
+	if len(c.buf) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		block := make([]byte, callBlockSize)
+		n, err := io.ReadFull(c.r, block)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		c.err = err
+		transformCalls(block[:n], c.offset, false)
+		c.offset += uint32(n)
+		c.buf = block[:n]
+		if n == 0 {
+			return 0, c.err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// transformCalls converts the relative addresses of E8/E9 instructions to
+// absolute ones (encode) or back.
+func transformCalls(b []byte, offset uint32, encode bool) {
+	if len(b) < 5 {
+		return
+	}
+	end := len(b) - 4
+	for i := 0; i < end; {
+		if b[i] != 0xe8 && b[i] != 0xe9 {
+			i++
+			continue
+		}
+		i++
+		if b[i+3] == 0x00 || b[i+3] == 0xff {
+			addr := (offset + uint32(i) + 4) & 0xffffff
+			rel := uint32(b[i]) | uint32(b[i+1])<<8 | uint32(b[i+2])<<16
+			if !encode {
+				rel -= addr
+			}
+			if rel&0x800000 != 0 {
+				b[i+3] = ^b[i+3]
+			}
+			if encode {
+				rel += addr
+			}
+			b[i] = byte(rel)
+			b[i+1] = byte(rel >> 8)
+			b[i+2] = byte(rel >> 16)
+		}
+		i += 4
+	}
+}
@@ -0,0 +1,350 @@
+package innosetup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var versionRe = regexp.MustCompile(`\((\d+)\.(\d+)\.(\d+)(?:\.\d+)?[a-z]?\)`)
+
+// Version is the Inno Setup release that compiled an installer.
+type Version struct {
+	Major, Minor, Patch int
+	Unicode             bool
+}
+
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Unicode {
+		s += " (u)"
+	}
+	return s
+}
+
+func (v Version) atLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// parseVersion decodes the 64 byte setup data ID, e.g.
+// "Inno Setup Setup Data (5.5.7) (u)".
+func parseVersion(id []byte) (Version, error) {
+	s := strings.TrimRight(string(id), "\x00")
+	if !strings.Contains(s, "Setup Data") {
+		return Version{}, errNotInno
+	}
+	m := versionRe.FindStringSubmatch(s)
+	if m == nil {
+		return Version{}, fmt.Errorf("unrecognised setup data version %q", s)
+	}
+	v := Version{Unicode: strings.Contains(s, "(u)") || strings.Contains(s, "(U)")}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	v.Patch, _ = strconv.Atoi(m[3])
+	return v, nil
+}
+
+// supported reports whether installers built by v can be decoded. The record
+// layouts below are those of the Unicode releases from 5.5.0 up to 6.3; 6.4
+// switched to SHA-256 and a new encryption scheme.
+func (v Version) supported() bool {
+	return v.Unicode && v.atLeast(5, 5, 0) && !v.atLeast(6, 4, 0)
+}
+
+// compression is TSetupHeader.CompressMethod, the codec of the file chunks.
+type compression uint8
+
+const (
+	compressStored compression = iota
+	compressZlib
+	compressBzip2
+	compressLZMA1
+	compressLZMA2
+)
+
+type setupHeader struct {
+	appName      string
+	appVersioned string
+	appID        string
+	appPublisher string
+	appVersion   string
+	defaultDir   string
+	baseFilename string
+	compression  compression
+	dataCount    int
+	files        []fileEntry
+}
+
+// entryCounts are the NumXxxEntries fields of TSetupHeader, in order.
+type entryCounts struct {
+	languages, messages, permissions, types, components, tasks, dirs, files,
+	data, icons, inis, registry, installDeletes, uninstallDeletes, runs,
+	uninstallRuns uint32
+}
+
+// parseSetupHeader decodes TSetupHeader and the entry tables in front of
+// [Files] from the first header block, then the file entries themselves.
+func parseSetupHeader(v Version, b []byte) (*setupHeader, error) {
+	c := &cursor{b: b}
+	h := &setupHeader{}
+	h.appName = c.str()
+	h.appVersioned = c.str()
+	h.appID = c.str()
+	c.raw() // AppCopyright
+	h.appPublisher = c.str()
+	c.skipStrings(4) // AppPublisherURL, AppSupportPhone, AppSupportURL, AppUpdatesURL
+	h.appVersion = c.str()
+	h.defaultDir = c.str()
+	c.raw() // DefaultGroupName
+	h.baseFilename = c.str()
+	// UninstallFilesDir, UninstallDisplayName, UninstallDisplayIcon,
+	// AppMutex, DefaultUserInfoName/Org/Serial, AppReadmeFile, AppContact,
+	// AppComments, AppModifyPath, CreateUninstallRegKey, Uninstallable,
+	// CloseApplicationsFilter
+	c.skipStrings(14)
+	if v.atLeast(5, 5, 6) {
+		c.raw() // SetupMutex
+	}
+	if v.atLeast(5, 6, 1) {
+		c.skipStrings(2) // ChangesEnvironment, ChangesAssociations
+	}
+	if v.atLeast(6, 3, 0) {
+		c.skipStrings(2) // ArchitecturesAllowed, ArchitecturesInstallIn64BitMode
+	}
+	c.skipStrings(4) // LicenseText, InfoBeforeText, InfoAfterText, CompiledCodeText
+
+	var n entryCounts
+	for _, p := range []*uint32{
+		&n.languages, &n.messages, &n.permissions, &n.types, &n.components,
+		&n.tasks, &n.dirs, &n.files, &n.data, &n.icons, &n.inis, &n.registry,
+		&n.installDeletes, &n.uninstallDeletes, &n.runs, &n.uninstallRuns,
+	} {
+		*p = c.u32()
+	}
+	h.dataCount = int(n.data)
+
+	c.take(versionDataSize * 2) // MinVersion, OnlyBelowVersion
+	c.take(8)                   // BackColor, BackColor2
+	if !v.atLeast(5, 5, 7) {
+		c.take(4) // WizardImageBackColor
+	}
+	if v.atLeast(6, 0, 0) {
+		c.take(9) // WizardStyle, WizardSizePercentX, WizardSizePercentY
+	}
+	if v.atLeast(5, 5, 7) {
+		c.take(1) // WizardImageAlphaFormat
+	}
+	c.take(20 + 8) // PasswordHash (SHA-1), PasswordSalt
+	c.take(8 + 4)  // ExtraDiskSpaceRequired, SlicesPerDisk
+	c.take(3)      // UninstallLogMode, DirExistsWarning, PrivilegesRequired
+	if v.atLeast(6, 0, 0) {
+		c.take(1) // PrivilegesRequiredOverridesAllowed
+	}
+	c.take(2) // ShowLanguageDialog, LanguageDetectionMethod
+	if m := c.take(1); m != nil {
+		h.compression = compression(m[0])
+	}
+	if !v.atLeast(6, 3, 0) {
+		c.take(2) // ArchitecturesAllowed, ArchitecturesInstallIn64BitMode
+	}
+	c.take(2) // DisableDirPage, DisableProgramGroupPage
+	c.u64()   // UninstallDisplaySize
+	c.take(setSize(headerOptions(v)))
+	if c.err != nil {
+		return nil, fmt.Errorf("truncated setup header: %w", c.err)
+	}
+	if h.compression > compressLZMA2 {
+		return nil, fmt.Errorf("unknown compression method %d", h.compression)
+	}
+
+	for _, t := range []struct {
+		name   string
+		count  uint32
+		layout entryLayout
+	}{
+		{"language", n.languages, languageEntry},
+		{"custom message", n.messages, messageEntry},
+		{"permission", n.permissions, permissionEntry},
+		{"type", n.types, typeEntry},
+		{"component", n.components, componentEntry},
+		{"task", n.tasks, taskEntry},
+		{"directory", n.dirs, dirEntry},
+	} {
+		for i := uint32(0); i < t.count; i++ {
+			c.skipStrings(t.layout.strings)
+			c.take(t.layout.fixed)
+		}
+		if c.err != nil {
+			return nil, fmt.Errorf("truncated %s entries: %w", t.name, c.err)
+		}
+	}
+
+	h.files = make([]fileEntry, n.files)
+	for i := range h.files {
+		e := parseFileEntry(c)
+		if c.err != nil {
+			return nil, fmt.Errorf("truncated file entries: %w", c.err)
+		}
+		if e.location != noLocation && e.location >= n.data {
+			return nil, fmt.Errorf("file entry %d refers to missing data entry %d", i, e.location)
+		}
+		h.files[i] = e
+	}
+	return h, nil
+}
+
+// versionDataSize is the size of a TSetupVersionData: Windows and NT
+// versions (build, minor, major) and the NT service pack.
+const versionDataSize = 4 + 4 + 2
+
+// headerOptions is the number of TSetupHeaderOption values in v.
+func headerOptions(v Version) int {
+	n := 46
+	if v.atLeast(5, 5, 7) {
+		n++ // ForceCloseApplications
+	}
+	if v.atLeast(5, 6, 1) {
+		n -= 2 // ChangesAssociations and ChangesEnvironment became strings
+	}
+	if v.atLeast(6, 0, 0) {
+		n += 3 // AppNameHasConsts, UsePreviousPrivileges, WizardResizable
+	}
+	if v.atLeast(6, 3, 0) {
+		n++ // UninstallLogging
+	}
+	return n
+}
+
+// setSize is the size Delphi gives a set of n elements: one bit each,
+// rounded up to whole bytes, with 3 byte sets padded to 4.
+func setSize(n int) int {
+	size := (n + 7) / 8
+	if size == 3 {
+		size = 4
+	}
+	return size
+}
+
+// entryLayout is the shape of an entry goggle skips over: its
+// length-prefixed strings followed by its fixed-size fields.
+type entryLayout struct {
+	strings int
+	fixed   int
+}
+
+var (
+	// Name, LanguageName, four font names, Data, LicenseText,
+	// InfoBeforeText, InfoAfterText; LanguageID, four font sizes,
+	// RightToLeft
+	languageEntry = entryLayout{10, 4 + 4*4 + 1}
+	// Name, Value; LangIndex
+	messageEntry = entryLayout{2, 4}
+	// Permissions
+	permissionEntry = entryLayout{1, 0}
+	// Name, Description, Languages, Check; versions, Options, Typ, Size
+	typeEntry = entryLayout{4, versionDataSize*2 + 1 + 1 + 8}
+	// Name, Description, Types, Languages, Check; ExtraDiskSpaceRequired,
+	// Level, Used, versions, Options, Size
+	componentEntry = entryLayout{5, 8 + 4 + 1 + versionDataSize*2 + 1 + 8}
+	// Name, Description, GroupDescription, Components, Languages, Check;
+	// Level, Used, versions, Options
+	taskEntry = entryLayout{6, 4 + 1 + versionDataSize*2 + 1}
+	// DirName and the six condition strings; Attribs, versions,
+	// PermissionsEntry, Options
+	dirEntry = entryLayout{7, 4 + versionDataSize*2 + 2 + 1}
+)
+
+// dataEntry is a TSetupFileLocationEntry: where a file's bytes live.
+type dataEntry struct {
+	firstSlice  uint32
+	lastSlice   uint32
+	chunkOffset uint32
+	fileOffset  uint64
+	size        uint64
+	chunkSize   uint64
+	checksum    []byte
+	modTime     time.Time
+	flags       uint32
+}
+
+const (
+	flagCallInstructionOptimized = 1 << 4
+	flagChunkEncrypted           = 1 << 6
+	flagChunkCompressed          = 1 << 7
+)
+
+// dataEntrySize is the size of a TSetupFileLocationEntry in the supported
+// releases: slices and offsets, sizes, SHA-1, time stamp, file version and
+// a two byte set of flags.
+const dataEntrySize = 4*3 + 8*3 + 20 + 8 + 8 + 2
+
+// filetimeEpoch is the Windows FILETIME of the Unix epoch.
+const filetimeEpoch = 116444736000000000
+
+// parseDataEntries decodes the second header block, which holds the count
+// data entries announced in the setup header.
+func parseDataEntries(b []byte, count int) ([]dataEntry, error) {
+	if len(b) < count*dataEntrySize {
+		return nil, fmt.Errorf("file location table too short for %d entries (%d bytes)", count, len(b))
+	}
+	entries := make([]dataEntry, count)
+	c := &cursor{b: b}
+	for i := range entries {
+		e := &entries[i]
+		e.firstSlice = c.u32()
+		e.lastSlice = c.u32()
+		e.chunkOffset = c.u32()
+		e.fileOffset = c.u64()
+		e.size = c.u64()
+		e.chunkSize = c.u64()
+		e.checksum = c.take(20)
+		ft := int64(c.u64())
+		c.u64() // file version
+		e.flags = uint32(c.u16())
+		if ft > filetimeEpoch {
+			e.modTime = time.Unix(0, (ft-filetimeEpoch)*100).UTC()
+		}
+	}
+	return entries, nil
+}
+
+// fileEntry is the part of a TSetupFileEntry goggle needs.
+type fileEntry struct {
+	destination string
+	languages   string
+	location    uint32
+}
+
+// noLocation marks entries for files that are not stored in the installer.
+const noLocation = 0xffffffff
+
+// fileOptions is the number of TSetupFileOption values in the supported
+// releases.
+const fileOptions = 32
+
+// parseFileEntry decodes one TSetupFileEntry.
+func parseFileEntry(c *cursor) fileEntry {
+	var e fileEntry
+	c.raw() // SourceFilename
+	e.destination = c.str()
+	c.skipStrings(2) // InstallFontName, StrongAssemblyName
+	c.skipStrings(2) // Components, Tasks
+	e.languages = c.str()
+	c.skipStrings(3) // Check, AfterInstall, BeforeInstall
+	c.take(versionDataSize * 2)
+	e.location = c.u32()
+	c.u32() // Attribs
+	c.u64() // ExternalSize
+	c.u16() // PermissionsEntry
+	c.take(setSize(fileOptions))
+	c.take(1) // FileType
+	return e
+}
@@ -0,0 +1,214 @@
+// Package innosetup reads the Inno Setup installers GOG ships for Windows,
+// so game files can be extracted without running the installer.
+//
+// Only installers built with the Unicode releases of Inno Setup 5.5 through
+// 6.3 are supported, which covers everything GOG has published in recent
+// years. Other versions are rejected rather than guessed at.
+package innosetup
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var errNotInno = errors.New("not an Inno Setup installer")
+
+var gameIDRe = regexp.MustCompile(`goggame-(\d+)\.`)
+
+// File is a file the installer would write into the game directory.
+type File struct {
+	Path        string // relative to the install directory, slash separated
+	Destination string // as written in the installer, e.g. {app}\Game.exe
+	Languages   string // Inno Setup language condition, empty for all
+	Size        int64
+	ModTime     time.Time
+	Checksum    []byte
+
+	location dataEntry
+}
+
+// Setup is an opened installer.
+type Setup struct {
+	Version      Version
+	AppName      string
+	AppVersion   string
+	AppID        string
+	AppPublisher string
+	Files        []File
+
+	path        string
+	f           *os.File
+	dataOffset  int64
+	baseName    string
+	compression compression
+}
+
+// Open parses the setup headers of the installer at path.
+func Open(path string) (*Setup, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := newSetup(f, path)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return s, nil
+}
+
+func newSetup(f *os.File, path string) (*Setup, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offs, err := findOffsets(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	r := io.NewSectionReader(f, int64(offs.header), info.Size()-int64(offs.header))
+	var id [64]byte
+	if _, err := io.ReadFull(r, id[:]); err != nil {
+		return nil, fmt.Errorf("failed to read setup data version: %w", err)
+	}
+	v, err := parseVersion(id[:])
+	if err != nil {
+		return nil, err
+	}
+	if !v.supported() {
+		return nil, fmt.Errorf("unsupported Inno Setup version %s", v)
+	}
+
+	header, err := readBlock(r)
+	if err != nil {
+		return nil, err
+	}
+	h, err := parseSetupHeader(v, header)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := readBlock(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := parseDataEntries(locations, h.dataCount)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Setup{
+		Version:      v,
+		AppName:      h.appName,
+		AppVersion:   h.appVersion,
+		AppID:        h.appID,
+		AppPublisher: h.appPublisher,
+		path:         path,
+		f:            f,
+		dataOffset:   int64(offs.data),
+		baseName:     h.baseFilename,
+		compression:  h.compression,
+	}
+	for _, e := range h.files {
+		if e.location == noLocation {
+			continue
+		}
+		rel, ok := appPath(e.destination)
+		if !ok {
+			continue
+		}
+		loc := data[e.location]
+		s.Files = append(s.Files, File{
+			Path:        rel,
+			Destination: e.destination,
+			Languages:   e.languages,
+			Size:        int64(loc.size),
+			ModTime:     loc.modTime,
+			Checksum:    loc.checksum,
+			location:    loc,
+		})
+	}
+	return s, nil
+}
+
+// appPath converts an {app}-relative destination to a slash separated path.
+// Files bound for other places (system folders, temp) are not part of the
+// game and are skipped.
+func appPath(dest string) (string, bool) {
+	const prefix = `{app}\`
+	if len(dest) <= len(prefix) || !strings.EqualFold(dest[:len(prefix)], prefix) {
+		return "", false
+	}
+	rel := strings.ReplaceAll(dest[len(prefix):], `\`, "/")
+	return rel, true
+}
+
+func (s *Setup) Close() error {
+	return s.f.Close()
+}
+
+// GameID returns the GOG product ID from the goggame-<id>.info file the
+// installer carries, or "" if there is none.
+func (s *Setup) GameID() string {
+	for _, f := range s.Files {
+		if m := gameIDRe.FindStringSubmatch(filepath.Base(f.Path)); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// FilesFor returns the files installed for the given Inno Setup language
+// names (e.g. "english", "german"). Files without a language condition are
+// always included; an empty list selects every file.
+func (s *Setup) FilesFor(langs []string) []File {
+	if len(langs) == 0 {
+		return s.Files
+	}
+	var files []File
+	for _, f := range s.Files {
+		if matchLanguages(f.Languages, langs) {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// matchLanguages reports whether a Languages: condition (a space separated
+// list of language names) allows any of langs.
+func matchLanguages(cond string, langs []string) bool {
+	if strings.TrimSpace(cond) == "" {
+		return true
+	}
+	for _, name := range strings.Fields(cond) {
+		for _, l := range langs {
+			if strings.EqualFold(name, l) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Languages lists the language names used in file conditions.
+func (s *Setup) Languages() []string {
+	seen := make(map[string]bool)
+	for _, f := range s.Files {
+		for _, name := range strings.Fields(f.Languages) {
+			seen[strings.ToLower(name)] = true
+		}
+	}
+	langs := make([]string, 0, len(seen))
+	for l := range seen {
+		langs = append(langs, l)
+	}
+	sort.Strings(langs)
+	return langs
+}
@@ -0,0 +1,687 @@
+package innosetup
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+type testFile struct {
+	dest  string
+	langs string
+	data  []byte
+	calls bool
+}
+
+type testSetup struct {
+	version  Version // default 5.5.7 (u)
+	files    []testFile
+	external bool
+	method   compression // default LZMA1
+}
+
+var testModTime = time.Date(2020, 5, 17, 12, 0, 0, 0, time.UTC)
+
+func putU16(b *bytes.Buffer, v uint16) { _ = binary.Write(b, binary.LittleEndian, v) }
+func putU32(b *bytes.Buffer, v uint32) { _ = binary.Write(b, binary.LittleEndian, v) }
+func putU64(b *bytes.Buffer, v uint64) { _ = binary.Write(b, binary.LittleEndian, v) }
+
+func putAnsi(b *bytes.Buffer, s string) {
+	putU32(b, uint32(len(s)))
+	b.WriteString(s)
+}
+
+func putStr(b *bytes.Buffer, s string) {
+	u := utf16.Encode([]rune(s))
+	putU32(b, uint32(len(u)*2))
+	for _, c := range u {
+		_ = binary.Write(b, binary.LittleEndian, c)
+	}
+}
+
+// rawLZMA compresses data the way Inno Setup stores it: 5 property bytes
+// and no size or end marker.
+func rawLZMA(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	cfg := lzma.WriterConfig{Size: int64(len(data)), SizeInHeader: true}
+	w, err := cfg.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	return append(b[:5:5], b[13:]...)
+}
+
+func writeBlock(t *testing.T, out *bytes.Buffer, data []byte) {
+	payload := rawLZMA(t, data)
+	var stored int
+	for p := 0; p < len(payload); p += blockChunkSize {
+		stored += 4 + min(blockChunkSize, len(payload)-p)
+	}
+	var hdr bytes.Buffer
+	putU32(&hdr, uint32(stored))
+	hdr.WriteByte(1)
+	putU32(out, crc32.ChecksumIEEE(hdr.Bytes()))
+	out.Write(hdr.Bytes())
+	for p := 0; p < len(payload); p += blockChunkSize {
+		piece := payload[p:min(p+blockChunkSize, len(payload))]
+		putU32(out, crc32.ChecksumIEEE(piece))
+		out.Write(piece)
+	}
+}
+
+// build writes a synthetic installer into dir and returns its path.
+func (ts testSetup) build(t *testing.T, dir string) string {
+	t.Helper()
+	v := ts.version
+	if v == (Version{}) {
+		v = Version{Major: 5, Minor: 5, Patch: 7, Unicode: true}
+	}
+
+	// All files go into one solid chunk
+	var raw bytes.Buffer
+	var locs bytes.Buffer
+	for _, f := range ts.files {
+		stored := append([]byte(nil), f.data...)
+		var flags uint16 = flagChunkCompressed
+		if f.calls {
+			for p := 0; p < len(stored); p += callBlockSize {
+				transformCalls(stored[p:min(p+callBlockSize, len(stored))], uint32(p), true)
+			}
+			flags |= flagCallInstructionOptimized
+		}
+		sum := sha1.Sum(f.data)
+		putU32(&locs, 0)
+		putU32(&locs, 0)
+		putU32(&locs, 0) // chunk offset, patched below
+		putU64(&locs, uint64(raw.Len()))
+		putU64(&locs, uint64(len(f.data)))
+		putU64(&locs, 0) // chunk size, patched below
+		locs.Write(sum[:])
+		putU64(&locs, uint64(testModTime.UnixNano()/100+filetimeEpoch))
+		putU64(&locs, 0)
+		_ = binary.Write(&locs, binary.LittleEndian, flags)
+		raw.Write(stored)
+	}
+	var chunk []byte
+	switch ts.method {
+	case compressZlib:
+		var zb bytes.Buffer
+		zw := zlib.NewWriter(&zb)
+		_, _ = zw.Write(raw.Bytes())
+		_ = zw.Close()
+		chunk = zb.Bytes()
+	case compressLZMA2:
+		chunk = rawLZMA2(t, raw.Bytes())
+	default:
+		ts.method = compressLZMA1
+		chunk = rawLZMA(t, raw.Bytes())
+	}
+	chunkOffset := uint32(0)
+	if ts.external {
+		chunkOffset = sliceHeaderSize
+	}
+	loc := locs.Bytes()
+	for i := range ts.files {
+		binary.LittleEndian.PutUint32(loc[i*74+8:], chunkOffset)
+		binary.LittleEndian.PutUint64(loc[i*74+28:], uint64(len(chunk)))
+	}
+
+	var hdr bytes.Buffer
+	ts.writeHeader(&hdr, v)
+
+	const tableOffset, headerOffset = 0x40, 0x100
+	var exe bytes.Buffer
+	exe.Write([]byte("MZ"))
+	exe.Write(make([]byte, legacyTablePointer-exe.Len()))
+	putU32(&exe, legacyTableMagic)
+	putU32(&exe, tableOffset)
+	putU32(&exe, ^uint32(tableOffset))
+	exe.Write(make([]byte, tableOffset-exe.Len()))
+
+	var setup0 bytes.Buffer
+	id := make([]byte, 64)
+	copy(id, fmt.Sprintf("Inno Setup Setup Data (%d.%d.%d) (u)", v.Major, v.Minor, v.Patch))
+	setup0.Write(id)
+	writeBlock(t, &setup0, hdr.Bytes())
+	writeBlock(t, &setup0, loc)
+
+	dataOffset := uint32(0)
+	if !ts.external {
+		dataOffset = uint32(headerOffset + setup0.Len())
+	}
+	var table bytes.Buffer
+	table.Write(offsetTableMagics[0])
+	putU32(&table, 1)
+	putU32(&table, 0)
+	putU32(&table, 0)
+	putU32(&table, 0)
+	putU32(&table, 0)
+	putU32(&table, headerOffset)
+	putU32(&table, dataOffset)
+	putU32(&table, crc32.ChecksumIEEE(table.Bytes()))
+	exe.Write(table.Bytes())
+	exe.Write(make([]byte, headerOffset-exe.Len()))
+	exe.Write(setup0.Bytes())
+
+	data := append(append([]byte(nil), chunkMagic...), chunk...)
+	if ts.external {
+		var slice bytes.Buffer
+		slice.Write(sliceMagics[0])
+		putU32(&slice, uint32(sliceHeaderSize+len(data)))
+		slice.Write(data)
+		if err := os.WriteFile(filepath.Join(dir, "setup_test_game-1.bin"), slice.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	} else {
+		exe.Write(data)
+	}
+
+	path := filepath.Join(dir, "setup_test_game.exe")
+	if err := os.WriteFile(path, exe.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// rawLZMA2 compresses data the way Inno Setup stores LZMA2 chunks: one byte
+// of dictionary size followed by the LZMA2 stream.
+func rawLZMA2(t *testing.T, data []byte) []byte {
+	t.Helper()
+	const dictProp = 18
+	buf := bytes.NewBuffer([]byte{dictProp})
+	cfg := lzma.Writer2Config{DictCap: lzma2DictSize(dictProp)}
+	w, err := cfg.NewWriter2(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// putVersionData writes a TSetupVersionData: Windows 0.0, NT 6.1.7601
+// (Windows 7), no service pack.
+func putVersionData(b *bytes.Buffer) {
+	putU32(b, 0)
+	putU16(b, 7601)
+	b.Write([]byte{1, 6})
+	b.Write([]byte{0, 0})
+}
+
+// writeHeader writes the first setup block the way the Inno Setup compiler
+// lays it out for v: TSetupHeader, field by field, then the entry tables.
+// Every table before [Files] gets real entries so that the file entries
+// are only found by decoding everything in front of them.
+func (ts testSetup) writeHeader(b *bytes.Buffer, v Version) {
+	for _, s := range []string{
+		"Test Game",                   // AppName
+		"Test Game 1.0",               // AppVerName
+		"{test-id}",                   // AppId
+		"(c) Test Studio",             // AppCopyright
+		"GOG.com",                     // AppPublisher
+		"https://www.gog.com",         // AppPublisherURL
+		"",                            // AppSupportPhone
+		"https://www.gog.com/support", // AppSupportURL
+		"",                            // AppUpdatesURL
+		"1.0",                         // AppVersion
+		`{autopf}\Test Game`,          // DefaultDirName
+		"Test Game",                   // DefaultGroupName
+		"setup_test_game",             // BaseFilename
+		"{app}",                       // UninstallFilesDir
+		"Test Game",                   // UninstallDisplayName
+		`{app}\game.exe`,              // UninstallDisplayIcon
+		"",                            // AppMutex
+		"{sysuserinfoname}",           // DefaultUserInfoName
+		"{sysuserinfoorg}",            // DefaultUserInfoOrg
+		"",                            // DefaultUserInfoSerial
+		"",                            // AppReadmeFile
+		"",                            // AppContact
+		"",                            // AppComments
+		"",                            // AppModifyPath
+		"yes",                         // CreateUninstallRegKey
+		"yes",                         // Uninstallable
+		"*.exe,*.dll,*.chm",           // CloseApplicationsFilter
+	} {
+		putStr(b, s)
+	}
+	if v.atLeast(5, 5, 6) {
+		putStr(b, "") // SetupMutex
+	}
+	if v.atLeast(5, 6, 1) {
+		putStr(b, "no") // ChangesEnvironment
+		putStr(b, "no") // ChangesAssociations
+	}
+	if v.atLeast(6, 3, 0) {
+		putStr(b, "x86compatible") // ArchitecturesAllowed
+		putStr(b, "x64compatible") // ArchitecturesInstallIn64BitMode
+	}
+	putAnsi(b, "")                         // LicenseText
+	putAnsi(b, "")                         // InfoBeforeText
+	putAnsi(b, "")                         // InfoAfterText
+	putAnsi(b, "IFPS\x17\x00\x00\x00code") // CompiledCodeText
+
+	for _, n := range []int{
+		2,             // NumLanguageEntries
+		1,             // NumCustomMessageEntries
+		1,             // NumPermissionEntries
+		1,             // NumTypeEntries
+		1,             // NumComponentEntries
+		1,             // NumTaskEntries
+		1,             // NumDirEntries
+		len(ts.files), // NumFileEntries
+		len(ts.files), // NumFileLocationEntries
+		1,             // NumIconEntries
+		0,             // NumIniEntries
+		2,             // NumRegistryEntries
+		0,             // NumInstallDeleteEntries
+		1,             // NumUninstallDeleteEntries
+		1,             // NumRunEntries
+		0,             // NumUninstallRunEntries
+	} {
+		putU32(b, uint32(n))
+	}
+
+	putVersionData(b)     // MinVersion
+	putVersionData(b)     // OnlyBelowVersion
+	putU32(b, 0x00ff0000) // BackColor
+	putU32(b, 0x00000000) // BackColor2
+	if !v.atLeast(5, 5, 7) {
+		putU32(b, 0x00ffffff) // WizardImageBackColor
+	}
+	if v.atLeast(6, 0, 0) {
+		b.WriteByte(1) // WizardStyle: modern
+		putU32(b, 120) // WizardSizePercentX
+		putU32(b, 120) // WizardSizePercentY
+	}
+	if v.atLeast(5, 5, 7) {
+		b.WriteByte(1) // WizardImageAlphaFormat: defined
+	}
+	b.Write(bytes.Repeat([]byte{0xa5}, 20)) // PasswordHash
+	b.Write([]byte("saltsalt"))             // PasswordSalt
+	putU64(b, 512<<20)                      // ExtraDiskSpaceRequired
+	putU32(b, 1)                            // SlicesPerDisk
+	b.WriteByte(0)                          // UninstallLogMode: append
+	b.WriteByte(1)                          // DirExistsWarning: no
+	b.WriteByte(3)                          // PrivilegesRequired: lowest
+	if v.atLeast(6, 0, 0) {
+		b.WriteByte(0x03) // PrivilegesRequiredOverridesAllowed
+	}
+	b.WriteByte(0)               // ShowLanguageDialog: yes
+	b.WriteByte(1)               // LanguageDetectionMethod: locale
+	b.WriteByte(byte(ts.method)) // CompressMethod
+	if !v.atLeast(6, 3, 0) {
+		b.WriteByte(0x06) // ArchitecturesAllowed: x86, x64
+		b.WriteByte(0x04) // ArchitecturesInstallIn64BitMode: x64
+	}
+	b.WriteByte(2)        // DisableDirPage: auto
+	b.WriteByte(2)        // DisableProgramGroupPage: auto
+	putU64(b, 1234567890) // UninstallDisplaySize
+	// Options: 46 flags in 5.5.0, ForceCloseApplications in 5.5.7, two
+	// fewer in 5.6.1, three more in 6.0 and UninstallLogging in 6.3. Every
+	// release packs them into 6 bytes except 6.3, which needs 7.
+	options := []byte{0x8b, 0x26, 0xc1, 0x3f, 0x02, 0x19}
+	if v.atLeast(6, 3, 0) {
+		options = append(options, 0x01)
+	}
+	b.Write(options)
+
+	// [Languages]
+	for _, l := range []struct {
+		name, display string
+		id            uint32
+	}{{"english", "English", 0x409}, {"german", "Deutsch", 0x407}} {
+		putStr(b, l.name)
+		putStr(b, l.display)
+		putStr(b, "Tahoma")          // DialogFontName
+		putStr(b, "Arial")           // TitleFontName
+		putStr(b, "Verdana")         // WelcomeFontName
+		putStr(b, "Arial")           // CopyrightFontName
+		putAnsi(b, "[Messages]\r\n") // Data
+		putAnsi(b, "")               // LicenseText
+		putAnsi(b, "")               // InfoBeforeText
+		putAnsi(b, "")               // InfoAfterText
+		putU32(b, l.id)              // LanguageID
+		putU32(b, 8)                 // DialogFontSize
+		putU32(b, 29)                // TitleFontSize
+		putU32(b, 12)                // WelcomeFontSize
+		putU32(b, 8)                 // CopyrightFontSize
+		b.WriteByte(0)               // RightToLeft
+	}
+
+	// [CustomMessages]
+	putStr(b, "NameAndVersion")
+	putStr(b, "%1 version %2")
+	putU32(b, 0xffffffff) // LangIndex: all
+
+	// permissions
+	putAnsi(b, "\x01\x01\x00\x00\x00\x00\x00\x05\x20\x00\x00\x00")
+
+	// [Types]
+	putStr(b, "full")
+	putStr(b, "Full installation")
+	putStr(b, "") // Languages
+	putStr(b, "") // Check
+	putVersionData(b)
+	putVersionData(b)
+	b.WriteByte(0)   // Options
+	b.WriteByte(1)   // Typ: default full
+	putU64(b, 1<<30) // Size
+
+	// [Components]
+	putStr(b, "main")
+	putStr(b, "Game files")
+	putStr(b, "full")
+	putStr(b, "")  // Languages
+	putStr(b, "")  // Check
+	putU64(b, 0)   // ExtraDiskSpaceRequired
+	putU32(b, 0)   // Level
+	b.WriteByte(1) // Used
+	putVersionData(b)
+	putVersionData(b)
+	b.WriteByte(0x01) // Options: fixed
+	putU64(b, 1<<30)  // Size
+
+	// [Tasks]
+	putStr(b, "desktopicon")
+	putStr(b, "Create a &desktop icon")
+	putStr(b, "Additional icons:")
+	putStr(b, "")  // Components
+	putStr(b, "")  // Languages
+	putStr(b, "")  // Check
+	putU32(b, 0)   // Level
+	b.WriteByte(1) // Used
+	putVersionData(b)
+	putVersionData(b)
+	b.WriteByte(0x02) // Options: unchecked
+
+	// [Dirs]
+	putStr(b, `{app}\saves`)
+	for i := 0; i < 6; i++ {
+		putStr(b, "") // Components, Tasks, Languages, Check, AfterInstall, BeforeInstall
+	}
+	putU32(b, 0) // Attribs
+	putVersionData(b)
+	putVersionData(b)
+	_ = binary.Write(b, binary.LittleEndian, int16(-1)) // PermissionsEntry
+	b.WriteByte(0x01)                                   // Options: uninsneveruninstall
+
+	// [Files]
+	for i, f := range ts.files {
+		putStr(b, "")     // SourceFilename
+		putStr(b, f.dest) // DestName
+		putStr(b, "")     // InstallFontName
+		putStr(b, "")     // StrongAssemblyName
+		putStr(b, "")     // Components
+		putStr(b, "")     // Tasks
+		putStr(b, f.langs)
+		putStr(b, "") // Check
+		putStr(b, "") // AfterInstall
+		putStr(b, "") // BeforeInstall
+		putVersionData(b)
+		putVersionData(b)
+		putU32(b, uint32(i)) // LocationEntry
+		putU32(b, 0x20)      // Attribs: archive
+		putU64(b, 0)         // ExternalSize
+		_ = binary.Write(b, binary.LittleEndian, int16(-1))
+		putU32(b, 0x04020080) // Options: ignoreversion, createallsubdirs, ...
+		b.WriteByte(0)        // FileType: user file
+	}
+
+	// [Icons], [Registry] and the rest follow; they are not decoded
+	putStr(b, `{group}\Test Game`)
+	b.Write(bytes.Repeat([]byte{0xff}, 64))
+}
+
+func callHeavy() []byte {
+	b := make([]byte, 3*callBlockSize/2)
+	for i := range b {
+		b[i] = byte(i * 7)
+	}
+	for i := 0; i+5 < len(b); i += 13 {
+		b[i] = 0xe8
+		b[i+4] = 0xff
+	}
+	return b
+}
+
+func TestExtract(t *testing.T) {
+	files := []testFile{
+		{dest: `{app}\game.exe`, data: callHeavy(), calls: true},
+		{dest: `{app}\goggame-1207658924.info`, data: []byte(`{"gameId":"1207658924"}`)},
+		{dest: `{app}\lang\english.txt`, langs: "english", data: []byte("hello")},
+		{dest: `{app}\lang\german.txt`, langs: "german", data: []byte("hallo")},
+		{dest: `{tmp}\helper.dll`, data: []byte("not part of the game")},
+	}
+
+	tests := []struct {
+		name  string
+		setup testSetup
+		langs []string
+		want  []string
+	}{
+		{
+			name:  "embedded lzma",
+			setup: testSetup{files: files},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/english.txt", "lang/german.txt"},
+		},
+		{
+			name:  "external slice zlib",
+			setup: testSetup{files: files, external: true, method: compressZlib},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/english.txt", "lang/german.txt"},
+		},
+		{
+			name:  "language filter",
+			setup: testSetup{files: files},
+			langs: []string{"German"},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/german.txt"},
+		},
+		{
+			name:  "5.5.0 layout",
+			setup: testSetup{version: Version{5, 5, 0, true}, files: files},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/english.txt", "lang/german.txt"},
+		},
+		{
+			name:  "5.6.1 layout",
+			setup: testSetup{version: Version{5, 6, 1, true}, files: files},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/english.txt", "lang/german.txt"},
+		},
+		{
+			name:  "6.0.0 layout lzma2",
+			setup: testSetup{version: Version{6, 0, 0, true}, files: files, method: compressLZMA2},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/english.txt", "lang/german.txt"},
+		},
+		{
+			name:  "6.3.0 layout",
+			setup: testSetup{version: Version{6, 3, 0, true}, files: files, external: true},
+			want:  []string{"game.exe", "goggame-1207658924.info", "lang/english.txt", "lang/german.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := tt.setup.build(t, t.TempDir())
+			s, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer func() { _ = s.Close() }()
+
+			if s.AppName != "Test Game" || s.AppVersion != "1.0" || s.AppPublisher != "GOG.com" {
+				t.Errorf("got app %q %q %q", s.AppName, s.AppVersion, s.AppPublisher)
+			}
+			if want := tt.setup.version; want != (Version{}) && s.Version != want {
+				t.Errorf("got version %s, want %s", s.Version, want)
+			}
+			if id := s.GameID(); id != "1207658924" {
+				t.Errorf("GameID() = %q", id)
+			}
+			if len(s.Files) != 4 {
+				t.Fatalf("got %d files, want 4", len(s.Files))
+			}
+
+			out := t.TempDir()
+			got, err := s.Extract(out, tt.langs)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("extracted %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("file %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+
+			data, err := os.ReadFile(filepath.Join(out, "game.exe"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, files[0].data) {
+				t.Error("game.exe content mismatch")
+			}
+			info, err := os.Stat(filepath.Join(out, "game.exe"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.ModTime().Equal(testModTime) {
+				t.Errorf("mod time = %v, want %v", info.ModTime(), testModTime)
+			}
+		})
+	}
+}
+
+func TestExtractSharedDestination(t *testing.T) {
+	files := []testFile{
+		{dest: `{app}\game.exe`, data: []byte("game")},
+		{dest: `{app}\lang.dat`, langs: "english", data: []byte("hello")},
+		{dest: `{app}\Lang.dat`, langs: "german", data: []byte("hallo")},
+	}
+	path := testSetup{files: files}.build(t, t.TempDir())
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	for _, tt := range []struct {
+		langs []string
+		want  string
+	}{
+		{nil, "hello"},
+		{[]string{"german"}, "hallo"},
+	} {
+		out := t.TempDir()
+		got, err := s.Extract(out, tt.langs)
+		if err != nil {
+			t.Fatalf("Extract(%v): %v", tt.langs, err)
+		}
+		if len(got) != 2 {
+			t.Errorf("Extract(%v) = %v, want each destination once", tt.langs, got)
+		}
+		data, err := os.ReadFile(filepath.Join(out, filepath.FromSlash(got[len(got)-1])))
+		if err != nil || string(data) != tt.want {
+			t.Errorf("Extract(%v): lang.dat = %q, %v, want %q", tt.langs, data, err, tt.want)
+		}
+	}
+}
+
+func TestOpenNotInno(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "setup.exe")
+	if err := os.WriteFile(path, make([]byte, 512), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestOpenUnsupportedVersion(t *testing.T) {
+	for _, v := range []Version{{5, 4, 3, true}, {6, 4, 0, true}} {
+		path := testSetup{version: v, files: []testFile{{dest: `{app}\game.exe`, data: []byte("x")}}}.build(t, t.TempDir())
+		_, err := Open(path)
+		if err == nil || !strings.Contains(err.Error(), "unsupported Inno Setup version") {
+			t.Errorf("%s: got error %v", v, err)
+		}
+	}
+}
+
+func TestTransformCalls(t *testing.T) {
+	orig := callHeavy()[:callBlockSize]
+	b := append([]byte(nil), orig...)
+	transformCalls(b, 0x20000, true)
+	if bytes.Equal(b, orig) {
+		t.Fatal("encode changed nothing")
+	}
+	transformCalls(b, 0x20000, false)
+	if !bytes.Equal(b, orig) {
+		t.Fatal("round trip mismatch")
+	}
+}
+
+func TestFindResource(t *testing.T) {
+	// root -> RCDATA -> 11111 -> language -> data entry
+	le := binary.LittleEndian
+	rsrc := make([]byte, 0x80)
+	dir := func(at int, name, off uint32) {
+		le.PutUint16(rsrc[at+14:], 1)
+		le.PutUint32(rsrc[at+16:], name)
+		le.PutUint32(rsrc[at+20:], off)
+	}
+	dir(0x00, resourceRCData, 0x80000000|0x18)
+	dir(0x18, resourceOffsets, 0x80000000|0x30)
+	dir(0x30, 1033, 0x48)
+	le.PutUint32(rsrc[0x48:], 0x4000)
+
+	rva, err := findResource(rsrc, resourceRCData, resourceOffsets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rva != 0x4000 {
+		t.Errorf("rva = %#x, want 0x4000", rva)
+	}
+	if _, err := findResource(rsrc, resourceRCData, 42); err == nil {
+		t.Error("expected error for missing resource")
+	}
+}
+
+func TestMatchLanguages(t *testing.T) {
+	tests := []struct {
+		cond  string
+		langs []string
+		want  bool
+	}{
+		{"", []string{"english"}, true},
+		{"english", []string{"English"}, true},
+		{"german french", []string{"french"}, true},
+		{"german", []string{"english"}, false},
+	}
+	for _, tt := range tests {
+		if got := matchLanguages(tt.cond, tt.langs); got != tt.want {
+			t.Errorf("matchLanguages(%q, %v) = %v, want %v", tt.cond, tt.langs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package innosetup
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// The setup loader stores where the compressed setup data lives in an offset
+// table. Since 5.1.5 the table is a PE resource; older versions point to it
+// from a fixed position in the DOS header area.
+const (
+	legacyTablePointer = 0x30
+	legacyTableMagic   = 0x6f6e6e49 // "Inno"
+	resourceRCData     = 10
+	resourceOffsets    = 11111
+)
+
+var offsetTableMagics = [][]byte{
+	[]byte("rDlPtS\xcd\xe6\xd7{\x0b*"),
+	[]byte("nS5W7dT\x83\xaa\x1b\x0fj"),
+}
+
+type offsets struct {
+	header uint32 // start of the setup-0 data (version ID and header blocks)
+	data   uint32 // start of embedded file data, 0 if stored in .bin slices
+}
+
+func findOffsets(r io.ReaderAt, size int64) (offsets, error) {
+	if pos, err := findOffsetResource(io.NewSectionReader(r, 0, size)); err == nil {
+		return readOffsetTable(r, pos)
+	}
+
+	var ptr [12]byte
+	if _, err := r.ReadAt(ptr[:], legacyTablePointer); err != nil {
+		return offsets{}, errNotInno
+	}
+	if binary.LittleEndian.Uint32(ptr[0:]) != legacyTableMagic {
+		return offsets{}, errNotInno
+	}
+	pos := binary.LittleEndian.Uint32(ptr[4:])
+	if pos != ^binary.LittleEndian.Uint32(ptr[8:]) {
+		return offsets{}, fmt.Errorf("corrupt setup loader offset pointer")
+	}
+	return readOffsetTable(r, int64(pos))
+}
+
+// readOffsetTable parses a TSetupLdrOffsetTable. Only the 5.1.5+ layout is
+// supported, which covers every Unicode release.
+func readOffsetTable(r io.ReaderAt, pos int64) (offsets, error) {
+	var table [44]byte
+	if _, err := r.ReadAt(table[:], pos); err != nil {
+		return offsets{}, fmt.Errorf("failed to read setup loader offset table: %w", err)
+	}
+
+	known := false
+	for _, magic := range offsetTableMagics {
+		if bytes.Equal(table[:12], magic) {
+			known = true
+		}
+	}
+	if !known {
+		return offsets{}, fmt.Errorf("unsupported setup loader version (offset table %q)", table[:12])
+	}
+
+	le := binary.LittleEndian
+	if rev := le.Uint32(table[12:]); rev != 1 {
+		return offsets{}, fmt.Errorf("unsupported setup loader revision %d", rev)
+	}
+	if crc32.ChecksumIEEE(table[:40]) != le.Uint32(table[40:]) {
+		return offsets{}, fmt.Errorf("setup loader offset table checksum mismatch")
+	}
+	return offsets{header: le.Uint32(table[32:]), data: le.Uint32(table[36:])}, nil
+}
+
+// findOffsetResource returns the file offset of the RCDATA #11111 resource.
+func findOffsetResource(r io.ReaderAt) (int64, error) {
+	f, err := pe.NewFile(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var dirs []pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:oh.NumberOfRvaAndSizes]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:oh.NumberOfRvaAndSizes]
+	}
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+		return 0, errors.New("no resource directory")
+	}
+	rva := dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE].VirtualAddress
+
+	for _, s := range f.Sections {
+		if rva < s.VirtualAddress || rva >= s.VirtualAddress+s.Size {
+			continue
+		}
+		data, err := s.Data()
+		if err != nil {
+			return 0, err
+		}
+		dataRVA, err := findResource(data[rva-s.VirtualAddress:], resourceRCData, resourceOffsets)
+		if err != nil {
+			return 0, err
+		}
+		for _, ds := range f.Sections {
+			if dataRVA >= ds.VirtualAddress && dataRVA < ds.VirtualAddress+ds.Size {
+				return int64(dataRVA-ds.VirtualAddress) + int64(ds.Offset), nil
+			}
+		}
+		return 0, errors.New("resource data outside any section")
+	}
+	return 0, errors.New("resource directory outside any section")
+}
+
+// findResource walks a PE resource tree (type, name, language) and returns
+// the RVA of the first matching leaf.
+func findResource(rsrc []byte, typ, id uint32) (uint32, error) {
+	le := binary.LittleEndian
+	entry := func(dir uint32, want uint32, any bool) (uint32, bool) {
+		if int(dir)+16 > len(rsrc) {
+			return 0, false
+		}
+		n := uint32(le.Uint16(rsrc[dir+12:])) + uint32(le.Uint16(rsrc[dir+14:]))
+		for i := uint32(0); i < n; i++ {
+			e := dir + 16 + i*8
+			if int(e)+8 > len(rsrc) {
+				return 0, false
+			}
+			name, off := le.Uint32(rsrc[e:]), le.Uint32(rsrc[e+4:])
+			if any || name == want {
+				return off, true
+			}
+		}
+		return 0, false
+	}
+
+	off, ok := entry(0, typ, false)
+	if !ok || off&0x80000000 == 0 {
+		return 0, errors.New("no RCDATA resources")
+	}
+	off, ok = entry(off&0x7fffffff, id, false)
+	if !ok || off&0x80000000 == 0 {
+		return 0, errors.New("no setup loader resource")
+	}
+	off, ok = entry(off&0x7fffffff, 0, true)
+	if !ok || off&0x80000000 != 0 || int(off)+8 > len(rsrc) {
+		return 0, errors.New("malformed setup loader resource")
+	}
+	return le.Uint32(rsrc[off:]), nil
+}
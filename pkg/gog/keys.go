@@ -0,0 +1,91 @@
+package gog
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// CDKey is a key or serial GOG shows for a game or one of its DLCs.
+type CDKey struct {
+	Name string `json:"name"` // what the key is for
+	Key  string `json:"key"`
+}
+
+var (
+	keyBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|span)>`)
+	keyTagRe   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// genericKeyLabels say nothing about what a key is for, so the game title is
+// used instead.
+var genericKeyLabels = map[string]bool{
+	"key": true, "cd key": true, "cd-key": true, "your key": true, "your cd key": true,
+	"serial": true, "serial number": true,
+}
+
+// Keys returns the CD keys of the game and its DLCs.
+func (d *GameDetails) Keys() []CDKey {
+	keys := parseCDKeys(d.CDKey, d.Title)
+	for i := range d.DLCs {
+		keys = append(keys, d.DLCs[i].Keys()...)
+	}
+	return keys
+}
+
+// parseCDKeys reads GOG's cdKey field. It is a single key, or HTML listing
+// several keys either as "Name: KEY" lines or as a "Name:" line followed by
+// the key. Unlabelled keys are named after title.
+func parseCDKeys(raw, title string) []CDKey {
+	raw = keyBreakRe.ReplaceAllString(raw, "\n")
+	raw = html.UnescapeString(keyTagRe.ReplaceAllString(raw, ""))
+
+	var keys []CDKey
+	label := ""
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasSuffix(line, ":"):
+			label = strings.TrimSpace(strings.TrimSuffix(line, ":"))
+			continue
+		}
+		name, key := label, line
+		if i := strings.LastIndex(line, ": "); i >= 0 {
+			name, key = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+2:])
+		}
+		if name == "" || genericKeyLabels[strings.ToLower(name)] {
+			name = title
+		}
+		keys = append(keys, CDKey{Name: name, Key: key})
+		label = ""
+	}
+	return keys
+}
+
+// WriteKeysFile stores the keys of title in the text file at path, replacing
+// an earlier entry for the same game and keeping the others.
+func WriteKeysFile(path, title string, keys []CDKey) error {
+	var blocks []string
+	if data, err := os.ReadFile(path); err == nil {
+		for _, block := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+			block = strings.TrimSpace(block)
+			if block != "" && strings.SplitN(block, "\n", 2)[0] != title {
+				blocks = append(blocks, block)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n  %s: %s", k.Name, k.Key)
+	}
+	blocks = append(blocks, b.String())
+	return os.WriteFile(path, []byte(strings.Join(blocks, "\n\n")+"\n"), 0600)
+}
@@ -0,0 +1,70 @@
+package gog
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseCDKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want []CDKey
+	}{
+		{"none", "", nil},
+		{"single key", "ABCD-EFGH-IJKL", []CDKey{{"Game", "ABCD-EFGH-IJKL"}}},
+		{"labelled lines", "Game: AAAA<br>Expansion: BBBB", []CDKey{{"Game", "AAAA"}, {"Expansion", "BBBB"}}},
+		{
+			"label before key",
+			"<span>Baldur&#39;s Gate:</span><br/>AAAA<br><span>Tales of the Sword Coast:</span><br>BBBB",
+			[]CDKey{{"Baldur's Gate", "AAAA"}, {"Tales of the Sword Coast", "BBBB"}},
+		},
+		{"generic label", "<p>Your CD key:</p><p>CCCC</p>", []CDKey{{"Game", "CCCC"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCDKeys(tt.raw, "Game"); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCDKeys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGameDetailsKeys(t *testing.T) {
+	var details GameDetails
+	data := `{"title": "Game", "cdKey": "AAAA", "downloads": [],
+		"dlcs": [{"title": "DLC One", "cdKey": "BBBB"}, {"title": "DLC Two", "cdKey": ""}]}`
+	if err := json.Unmarshal([]byte(data), &details); err != nil {
+		t.Fatal(err)
+	}
+	want := []CDKey{{"Game", "AAAA"}, {"DLC One", "BBBB"}}
+	if got := details.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+}
+
+func TestWriteKeysFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.txt")
+	if err := WriteKeysFile(path, "Game A", []CDKey{{"Game A", "AAAA"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteKeysFile(path, "Game B", []CDKey{{"Game B", "BBBB"}, {"DLC", "CCCC"}}); err != nil {
+		t.Fatal(err)
+	}
+	// Rewriting a game replaces its entry
+	if err := WriteKeysFile(path, "Game A", []CDKey{{"Game A", "DDDD"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Game B\n  Game B: BBBB\n  DLC: CCCC\n\nGame A\n  Game A: DDDD\n"
+	if string(data) != want {
+		t.Errorf("keys.txt = %q, want %q", data, want)
+	}
+}
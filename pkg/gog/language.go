@@ -0,0 +1,136 @@
+package gog
+
+import (
+	"sort"
+	"strings"
+)
+
+// languageCodes maps the language names used by gameDetails (native and
+// English spellings) to the codes the content system uses.
+var languageCodes = map[string]string{
+	"english":              "en-US",
+	"deutsch":              "de-DE",
+	"german":               "de-DE",
+	"français":             "fr-FR",
+	"french":               "fr-FR",
+	"español":              "es-ES",
+	"spanish":              "es-ES",
+	"español (am)":         "es-MX",
+	"italiano":             "it-IT",
+	"italian":              "it-IT",
+	"polski":               "pl-PL",
+	"polish":               "pl-PL",
+	"русский":              "ru-RU",
+	"russian":              "ru-RU",
+	"中文(简体)":               "zh-Hans",
+	"chinese":              "zh-Hans",
+	"中文(繁體)":               "zh-Hant",
+	"日本語":                  "ja-JP",
+	"japanese":             "ja-JP",
+	"한국어":                  "ko-KR",
+	"korean":               "ko-KR",
+	"português do brasil":  "pt-BR",
+	"brazilian portuguese": "pt-BR",
+	"português":            "pt-PT",
+	"portuguese":           "pt-PT",
+	"český":                "cs-CZ",
+	"czech":                "cs-CZ",
+	"magyar":               "hu-HU",
+	"hungarian":            "hu-HU",
+	"nederlands":           "nl-NL",
+	"dutch":                "nl-NL",
+	"türkçe":               "tr-TR",
+	"turkish":              "tr-TR",
+	"svenska":              "sv-SE",
+	"swedish":              "sv-SE",
+	"suomi":                "fi-FI",
+	"finnish":              "fi-FI",
+	"dansk":                "da-DK",
+	"danish":               "da-DK",
+	"norsk":                "nb-NO",
+	"norwegian":            "nb-NO",
+	"українська":           "uk-UA",
+	"ukrainian":            "uk-UA",
+	"română":               "ro-RO",
+	"romanian":             "ro-RO",
+	"ελληνικά":             "el-GR",
+	"greek":                "el-GR",
+	"български":            "bg-BG",
+	"bulgarian":            "bg-BG",
+	"ไทย":                  "th-TH",
+	"thai":                 "th-TH",
+	"العربية":              "ar",
+	"arabic":               "ar",
+	// Inno Setup's names for languages spelled differently above
+	"brazilianportuguese": "pt-BR",
+	"chinesesimplified":   "zh-Hans",
+	"chinesetraditional":  "zh-Hant",
+}
+
+// LanguageCode returns the content-system code for a GOG language name. Codes
+// are returned unchanged, and unknown names as "".
+func LanguageCode(lang string) string {
+	if code, ok := languageCodes[strings.ToLower(strings.TrimSpace(lang))]; ok {
+		return code
+	}
+	if strings.Contains(lang, "-") || len(lang) == 2 {
+		return lang
+	}
+	return ""
+}
+
+// SameLanguage reports whether a and b, GOG language names or codes, mean
+// the same language. A code without a region, such as "de", matches all of
+// its regional variants.
+func SameLanguage(a, b string) bool {
+	if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) {
+		return true
+	}
+	ca, cb := LanguageCode(a), LanguageCode(b)
+	if ca == "" || cb == "" {
+		return false
+	}
+	if strings.EqualFold(ca, cb) {
+		return true
+	}
+	pa, ra, _ := strings.Cut(ca, "-")
+	pb, rb, _ := strings.Cut(cb, "-")
+	return strings.EqualFold(pa, pb) && (ra == "" || rb == "")
+}
+
+// PreferredLanguage returns the first language of available that matches
+// prefs, trying prefs in order.
+func PreferredLanguage(available, prefs []string) (string, bool) {
+	for _, pref := range prefs {
+		for _, lang := range available {
+			if SameLanguage(lang, pref) {
+				return lang, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Locale returns the locale the product API expects for a language name or
+// code, e.g. "de-DE" for "Deutsch" or "de", or "" if lang is unknown. Codes
+// without a regional variant, such as "ar", are returned as they are.
+func Locale(lang string) string {
+	code := LanguageCode(lang)
+	if code == "" || strings.Contains(code, "-") {
+		return code
+	}
+	var variants []string
+	for _, c := range languageCodes {
+		if primary, region, ok := strings.Cut(c, "-"); ok && strings.EqualFold(primary, code) {
+			if strings.EqualFold(region, primary) {
+				return c
+			}
+			variants = append(variants, c)
+		}
+	}
+	if len(variants) == 0 {
+		return code
+	}
+	sort.Strings(variants)
+	return variants[0]
+}
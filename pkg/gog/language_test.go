@@ -0,0 +1,84 @@
+package gog
+
+import "testing"
+
+func TestLanguageCode(t *testing.T) {
+	tests := []struct {
+		lang, want string
+	}{
+		{"English", "en-US"},
+		{"deutsch", "de-DE"},
+		{" Français ", "fr-FR"},
+		{"português do brasil", "pt-BR"},
+		{"de-DE", "de-DE"},
+		{"en", "en"},
+		{"klingon", ""},
+	}
+	for _, tt := range tests {
+		if got := LanguageCode(tt.lang); got != tt.want {
+			t.Errorf("LanguageCode(%q) = %q, want %q", tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestSameLanguage(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Deutsch", "german", true},
+		{"Deutsch", "de", true},
+		{"Deutsch", "de-DE", true},
+		{"español (AM)", "es", true},
+		{"español (AM)", "español", false},
+		{"English", "de", false},
+		{"klingon", "Klingon", true},
+		{"klingon", "en", false},
+	}
+	for _, tt := range tests {
+		if got := SameLanguage(tt.a, tt.b); got != tt.want {
+			t.Errorf("SameLanguage(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	available := []string{"English", "français", "Deutsch"}
+	tests := []struct {
+		prefs []string
+		want  string
+		ok    bool
+	}{
+		{[]string{"de", "en"}, "Deutsch", true},
+		{[]string{"pl", "fr-FR", "en"}, "français", true},
+		{[]string{"pl"}, "", false},
+		{nil, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := PreferredLanguage(available, tt.prefs)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("PreferredLanguage(%v) = %q, %v, want %q, %v", tt.prefs, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLocale(t *testing.T) {
+	tests := map[string]string{
+		"Deutsch": "de-DE",
+		"de":      "de-DE",
+		"en":      "en-US",
+		"pt":      "pt-PT",
+		"pt-BR":   "pt-BR",
+		"zh":      "zh-Hans",
+		"ar":      "ar",
+		"Arabic":  "ar",
+		"no":      "no",
+		"xx":      "xx", // two letters pass as a code
+		"klingon": "",
+	}
+	for in, want := range tests {
+		if got := Locale(in); got != want {
+			t.Errorf("Locale(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
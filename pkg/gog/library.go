@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 )
 
@@ -12,8 +13,10 @@ type OwnedGamesResponse struct {
 }
 
 type Product struct {
-	ID    int    `json:"id"`
-	Title string `json:"title"`
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Slug    string `json:"slug,omitempty"`
+	IsMovie bool   `json:"isMovie,omitempty"`
 }
 
 type ProductDetails struct {
@@ -42,6 +45,9 @@ type ProductDetails struct {
 
 func (c *Client) GetProductDetails(id int) (*ProductDetails, error) {
 	url := fmt.Sprintf("%s/products/%d?expand=description", c.apiBaseURL(), id)
+	if c.Locale != "" {
+		url += "&locale=" + c.Locale
+	}
 	resp, err := c.AuthGet(url)
 	if err != nil {
 		return nil, err
@@ -116,3 +122,61 @@ func (c *Client) GetProducts(ids []int) ([]Product, error) {
 	}
 	return all, nil
 }
+
+// MatchProduct finds the product a user means by query, which may be a
+// product ID, an exact title or a unique part of a title (case-insensitive).
+func MatchProduct(products []Product, query string) (Product, error) {
+	return MatchProductIn(products, query, "in your library")
+}
+
+// MatchProductIn is MatchProduct for other product lists; where completes
+// error messages, e.g. "on your wishlist".
+func MatchProductIn(products []Product, query, where string) (Product, error) {
+	i, err := matchTitle(len(products), func(i int) (int, string) {
+		return products[i].ID, products[i].Title
+	}, query, where)
+	if err != nil {
+		return Product{}, err
+	}
+	return products[i], nil
+}
+
+// matchTitle implements the lookup rules of MatchProduct over n items and
+// returns the index of the match. where completes error messages, e.g.
+// "in your library".
+func matchTitle(n int, item func(i int) (id int, title string), query, where string) (int, error) {
+	if id, err := strconv.Atoi(query); err == nil {
+		for i := 0; i < n; i++ {
+			if itemID, _ := item(i); itemID == id {
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("no game with ID %d %s", id, where)
+	}
+
+	q := strings.ToLower(query)
+	var matches []int
+	for i := 0; i < n; i++ {
+		_, title := item(i)
+		title = strings.ToLower(title)
+		if title == q {
+			return i, nil
+		}
+		if strings.Contains(title, q) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return -1, fmt.Errorf("no game matching %q %s", query, where)
+	case 1:
+		return matches[0], nil
+	default:
+		titles := make([]string, len(matches))
+		for j, i := range matches {
+			_, titles[j] = item(i)
+		}
+		return -1, fmt.Errorf("%q matches several games: %s", query, strings.Join(titles, ", "))
+	}
+}
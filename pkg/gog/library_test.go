@@ -107,9 +107,13 @@ func TestGetProductDetails(t *testing.T) {
 		if r.URL.Query().Get("expand") != "description" {
 			t.Error("missing expand=description query param")
 		}
+		title := "Cool Game"
+		if r.URL.Query().Get("locale") == "de-DE" {
+			title = "Tolles Spiel"
+		}
 		_ = json.NewEncoder(w).Encode(ProductDetails{
 			ID:    42,
-			Title: "Cool Game",
+			Title: title,
 			Slug:  "cool-game",
 		})
 	}))
@@ -126,4 +130,45 @@ func TestGetProductDetails(t *testing.T) {
 	if details.ID != 42 {
 		t.Errorf("ID = %d, want 42", details.ID)
 	}
+
+	c.Locale = "de-DE"
+	if details, err = c.GetProductDetails(42); err != nil {
+		t.Fatalf("GetProductDetails: %v", err)
+	}
+	if details.Title != "Tolles Spiel" {
+		t.Errorf("localized Title = %q, want %q", details.Title, "Tolles Spiel")
+	}
+}
+
+func TestMatchProduct(t *testing.T) {
+	products := []Product{
+		{ID: 1, Title: "The Witcher"},
+		{ID: 2, Title: "The Witcher 2"},
+		{ID: 3, Title: "Baldur's Gate"},
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		wantID  int
+		wantErr bool
+	}{
+		{name: "by ID", query: "3", wantID: 3},
+		{name: "unknown ID", query: "99", wantErr: true},
+		{name: "exact title wins over substring", query: "the witcher", wantID: 1},
+		{name: "unique substring", query: "baldur", wantID: 3},
+		{name: "ambiguous substring", query: "witch", wantErr: true},
+		{name: "no match", query: "doom", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MatchProduct(products, tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("MatchProduct(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			}
+			if got.ID != tt.wantID {
+				t.Errorf("MatchProduct(%q) = %d, want %d", tt.query, got.ID, tt.wantID)
+			}
+		})
+	}
 }
@@ -0,0 +1,258 @@
+package macpkg
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ulikunitz/xz"
+)
+
+// Payloads are cpio archives, usually gzip compressed. Newer packages use
+// Apple's pbzx container of xz chunks instead.
+
+const (
+	cpioTrailer = "TRAILER!!!"
+
+	modeType    = 0170000
+	modeDir     = 0040000
+	modeRegular = 0100000
+	modeSymlink = 0120000
+)
+
+// Entry is a file in a package payload.
+type Entry struct {
+	Path    string // slash separated, relative to the install location
+	Mode    int64  // Unix mode including the file type bits
+	Size    int64
+	ModTime time.Time
+	Inode   int64
+	Links   int64
+}
+
+func (e *Entry) IsDir() bool     { return e.Mode&modeType == modeDir }
+func (e *Entry) IsRegular() bool { return e.Mode&modeType == modeRegular }
+func (e *Entry) IsSymlink() bool { return e.Mode&modeType == modeSymlink }
+
+// decodePayload unwraps the compression around a cpio payload.
+func decodePayload(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	switch {
+	case head[0] == 0x1f && head[1] == 0x8b:
+		return gzip.NewReader(br)
+	case string(head) == "pbzx":
+		return newPBZX(br)
+	default:
+		return br, nil
+	}
+}
+
+// pbzxReader decodes a pbzx stream: a header followed by chunks that are
+// either xz streams or stored as-is.
+type pbzxReader struct {
+	r     io.Reader
+	flags uint64
+	cur   io.Reader
+}
+
+func newPBZX(r io.Reader) (*pbzxReader, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("failed to read pbzx header: %w", err)
+	}
+	return &pbzxReader{r: r, flags: binary.BigEndian.Uint64(hdr[4:])}, nil
+}
+
+func (p *pbzxReader) Read(b []byte) (int, error) {
+	for {
+		if p.cur != nil {
+			n, err := p.cur.Read(b)
+			if err == io.EOF {
+				p.cur = nil
+				if n > 0 {
+					return n, nil
+				}
+				continue
+			}
+			return n, err
+		}
+		if p.flags&(1<<24) == 0 {
+			return 0, io.EOF
+		}
+
+		var hdr [16]byte
+		if _, err := io.ReadFull(p.r, hdr[:]); err != nil {
+			return 0, fmt.Errorf("failed to read pbzx chunk: %w", err)
+		}
+		p.flags = binary.BigEndian.Uint64(hdr[:])
+		chunk := io.LimitReader(p.r, int64(binary.BigEndian.Uint64(hdr[8:])))
+
+		br := bufio.NewReader(chunk)
+		if magic, _ := br.Peek(6); bytes.Equal(magic, []byte("\xfd7zXZ\x00")) {
+			xr, err := xz.NewReader(br)
+			if err != nil {
+				return 0, err
+			}
+			p.cur = &drain{r: xr, rest: br}
+		} else {
+			p.cur = br
+		}
+	}
+}
+
+// drain reads the xz stream and then discards any padding left in the
+// chunk, so the next chunk header is aligned.
+type drain struct {
+	r    io.Reader
+	rest io.Reader
+}
+
+func (d *drain) Read(b []byte) (int, error) {
+	n, err := d.r.Read(b)
+	if err == io.EOF {
+		if _, err := io.Copy(io.Discard, d.rest); err != nil {
+			return n, err
+		}
+	}
+	return n, err
+}
+
+// cpioReader walks the entries of an odc (070707) or newc (070701)
+// archive.
+type cpioReader struct {
+	r      *bufio.Reader
+	remain int64 // unread data of the current entry
+	pad    int64 // alignment after the current entry's data
+}
+
+func newCPIO(r io.Reader) *cpioReader {
+	return &cpioReader{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Next skips the rest of the current entry and returns the next header, or
+// io.EOF at the trailer.
+func (c *cpioReader) Next() (*Entry, error) {
+	if _, err := io.CopyN(io.Discard, c.r, c.remain+c.pad); err != nil {
+		return nil, fmt.Errorf("truncated payload: %w", err)
+	}
+	c.remain, c.pad = 0, 0
+
+	magic, err := c.r.Peek(6)
+	if err != nil {
+		return nil, fmt.Errorf("truncated payload: %w", err)
+	}
+	var e *Entry
+	switch string(magic) {
+	case "070707":
+		e, err = c.readODC()
+	case "070701", "070702":
+		e, err = c.readNewc()
+	default:
+		return nil, fmt.Errorf("unsupported cpio format %q", magic)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if e.Path == cpioTrailer {
+		return nil, io.EOF
+	}
+	return e, nil
+}
+
+func (c *cpioReader) Read(b []byte) (int, error) {
+	if c.remain <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > c.remain {
+		b = b[:c.remain]
+	}
+	n, err := c.r.Read(b)
+	c.remain -= int64(n)
+	if err == io.EOF && c.remain > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (c *cpioReader) readODC() (*Entry, error) {
+	var hdr [76]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("truncated payload: %w", err)
+	}
+	field := func(off, n int) int64 {
+		v, _ := strconv.ParseInt(string(hdr[off:off+n]), 8, 64)
+		return v
+	}
+	e := &Entry{
+		Inode:   field(12, 6),
+		Mode:    field(18, 6),
+		Links:   field(42, 6),
+		ModTime: time.Unix(field(48, 11), 0),
+		Size:    field(65, 11),
+	}
+	name, err := c.readName(field(59, 6))
+	if err != nil {
+		return nil, err
+	}
+	e.Path = name
+	c.remain = e.Size
+	return e, nil
+}
+
+func (c *cpioReader) readNewc() (*Entry, error) {
+	var hdr [110]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return nil, fmt.Errorf("truncated payload: %w", err)
+	}
+	field := func(i int) int64 {
+		off := 6 + i*8
+		v, _ := strconv.ParseInt(string(hdr[off:off+8]), 16, 64)
+		return v
+	}
+	e := &Entry{
+		Inode:   field(0),
+		Mode:    field(1),
+		Links:   field(4),
+		ModTime: time.Unix(field(5), 0),
+		Size:    field(6),
+	}
+	nameSize := field(11)
+	name, err := c.readName(nameSize)
+	if err != nil {
+		return nil, err
+	}
+	// header+name and data are each padded to four bytes
+	if _, err := io.CopyN(io.Discard, c.r, pad4(110+nameSize)); err != nil {
+		return nil, fmt.Errorf("truncated payload: %w", err)
+	}
+	e.Path = name
+	c.remain = e.Size
+	c.pad = pad4(e.Size)
+	return e, nil
+}
+
+func (c *cpioReader) readName(n int64) (string, error) {
+	if n <= 0 || n > 64*1024 {
+		return "", errors.New("bad cpio file name length")
+	}
+	name := make([]byte, n)
+	if _, err := io.ReadFull(c.r, name); err != nil {
+		return "", fmt.Errorf("truncated payload: %w", err)
+	}
+	return strings.TrimRight(string(name), "\x00"), nil
+}
+
+func pad4(n int64) int64 {
+	return (4 - n%4) % 4
+}
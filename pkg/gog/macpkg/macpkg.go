@@ -0,0 +1,343 @@
+// Package macpkg reads the macOS installer packages GOG ships: xar archives
+// holding one or more component packages whose Payload is a compressed cpio
+// archive of the game files.
+package macpkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var gameIDRe = regexp.MustCompile(`goggame-(\d+)\.`)
+
+// Package is an opened .pkg file.
+type Package struct {
+	Title           string
+	Identifier      string
+	Version         string
+	InstallLocation string
+
+	f     *os.File
+	files []*xarFile
+}
+
+type distribution struct {
+	Title   string `xml:"title"`
+	PkgRefs []struct {
+		ID      string `xml:"id,attr"`
+		Version string `xml:"version,attr"`
+	} `xml:"pkg-ref"`
+}
+
+type packageInfo struct {
+	Identifier      string `xml:"identifier,attr"`
+	Version         string `xml:"version,attr"`
+	InstallLocation string `xml:"install-location,attr"`
+}
+
+// Open reads the table of contents and package metadata of the .pkg at
+// path.
+func Open(path string) (*Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newPackage(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return p, nil
+}
+
+func newPackage(f *os.File) (*Package, error) {
+	files, err := readXar(f)
+	if err != nil {
+		return nil, err
+	}
+	p := &Package{f: f, files: files}
+
+	for _, xf := range files {
+		switch path.Base(xf.Name) {
+		case "Distribution":
+			var d distribution
+			if err := p.decodeXML(xf, &d); err != nil {
+				return nil, err
+			}
+			p.Title = d.Title
+			for _, ref := range d.PkgRefs {
+				if p.Version == "" && ref.Version != "" {
+					p.Version = ref.Version
+				}
+			}
+		case "PackageInfo":
+			var info packageInfo
+			if err := p.decodeXML(xf, &info); err != nil {
+				return nil, err
+			}
+			if p.Identifier == "" {
+				p.Identifier = info.Identifier
+				p.InstallLocation = info.InstallLocation
+			}
+			if p.Version == "" {
+				p.Version = info.Version
+			}
+		}
+	}
+	if len(p.payloads()) == 0 {
+		return nil, fmt.Errorf("no payload in package")
+	}
+	return p, nil
+}
+
+func (p *Package) decodeXML(xf *xarFile, v any) error {
+	rc, err := xf.Open()
+	if err != nil {
+		return err
+	}
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		_ = rc.Close()
+		return fmt.Errorf("failed to parse %s: %w", xf.Name, err)
+	}
+	return rc.Close()
+}
+
+func (p *Package) Close() error {
+	return p.f.Close()
+}
+
+// payloads returns the Payload file of every component package.
+func (p *Package) payloads() []*xarFile {
+	var payloads []*xarFile
+	for _, xf := range p.files {
+		if path.Base(xf.Name) == "Payload" && xf.Type == "file" {
+			payloads = append(payloads, xf)
+		}
+	}
+	return payloads
+}
+
+// walk calls fn for every payload entry. r reads the entry's data.
+func (p *Package) walk(fn func(e *Entry, r io.Reader) error) error {
+	for _, xf := range p.payloads() {
+		if err := p.walkPayload(xf, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Package) walkPayload(xf *xarFile, fn func(e *Entry, r io.Reader) error) error {
+	rc, err := xf.Open()
+	if err != nil {
+		return err
+	}
+	pr, err := decodePayload(rc)
+	if err != nil {
+		_ = rc.Close()
+		return fmt.Errorf("%s: %w", xf.Name, err)
+	}
+	cr := newCPIO(pr)
+	for {
+		e, err := cr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			_ = rc.Close()
+			return fmt.Errorf("%s: %w", xf.Name, err)
+		}
+		rel, ok := cleanPath(e.Path)
+		if !ok {
+			continue
+		}
+		e.Path = rel
+		if err := fn(e, cr); err != nil {
+			_ = rc.Close()
+			return err
+		}
+	}
+	return rc.Close()
+}
+
+// cleanPath turns a payload name like "./Game.app/Contents" into a relative
+// path, dropping the root entry.
+func cleanPath(name string) (string, bool) {
+	rel := strings.TrimPrefix(path.Clean("/"+name), "/")
+	return rel, rel != ""
+}
+
+// Entries lists the files in every payload. This decompresses the whole
+// package.
+func (p *Package) Entries() ([]Entry, error) {
+	var entries []Entry
+	err := p.walk(func(e *Entry, _ io.Reader) error {
+		entries = append(entries, *e)
+		return nil
+	})
+	return entries, err
+}
+
+// GameID returns the GOG product ID from a goggame-<id>.info file among
+// paths, or "".
+func GameID(paths []string) string {
+	for _, p := range paths {
+		if m := gameIDRe.FindStringSubmatch(path.Base(p)); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// Extract writes the payload files into dir, keeping their permission bits
+// and modification times, and returns the paths it created relative to dir.
+func (p *Package) Extract(dir string) ([]string, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	// newc archives store hard linked data once, on the last link
+	links := make(map[int64][]pendingLink)
+	err = p.walk(func(e *Entry, r io.Reader) error {
+		dest := filepath.Join(root, filepath.FromSlash(e.Path))
+		if !strings.HasPrefix(dest, root+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to extract %s outside %s", e.Path, dir)
+		}
+
+		var err error
+		switch {
+		case e.IsDir():
+			return os.MkdirAll(dest, 0755)
+		case e.IsSymlink():
+			err = extractSymlink(r, dest, root)
+		case e.IsRegular():
+			if e.Links > 1 && e.Size == 0 {
+				links[e.Inode] = append(links[e.Inode], pendingLink{dest: dest, entry: *e})
+				written = append(written, e.Path)
+				return nil
+			}
+			if err = extractFile(r, dest, e); err == nil && e.Links > 1 {
+				for _, other := range links[e.Inode] {
+					if err = linkFile(dest, other.dest); err != nil {
+						break
+					}
+				}
+				delete(links, e.Inode)
+			}
+		default:
+			return nil // devices, fifos
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract %s: %w", e.Path, err)
+		}
+		written = append(written, e.Path)
+		return nil
+	})
+	if err != nil {
+		return written, err
+	}
+	return written, createEmptyLinks(links)
+}
+
+// pendingLink is a hard link waiting for the entry that carries its data.
+type pendingLink struct {
+	dest  string
+	entry Entry
+}
+
+// createEmptyLinks creates the hard links whose data never arrived. Every
+// link of an empty file has size 0, so there is no data-carrying entry and
+// the file is created empty.
+func createEmptyLinks(links map[int64][]pendingLink) error {
+	inodes := make([]int64, 0, len(links))
+	for ino := range links {
+		inodes = append(inodes, ino)
+	}
+	sort.Slice(inodes, func(i, j int) bool { return inodes[i] < inodes[j] })
+	for _, ino := range inodes {
+		group := links[ino]
+		first := group[0]
+		if err := extractFile(strings.NewReader(""), first.dest, &first.entry); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", first.entry.Path, err)
+		}
+		for _, other := range group[1:] {
+			if err := linkFile(first.dest, other.dest); err != nil {
+				return fmt.Errorf("failed to extract %s: %w", other.entry.Path, err)
+			}
+		}
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, dest string, e *Entry) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	perm := os.FileMode(e.Mode & 0777)
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// OpenFile's perm is filtered by umask, so set the archived bits explicitly
+	if err := os.Chmod(dest, perm); err != nil {
+		return err
+	}
+	return os.Chtimes(dest, e.ModTime, e.ModTime)
+}
+
+func linkFile(src, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	_ = os.Remove(dest)
+	if err := os.Link(src, dest); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dest, data, info.Mode().Perm())
+}
+
+func extractSymlink(r io.Reader, dest, root string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	target, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	resolved := filepath.Join(filepath.Dir(dest), string(target))
+	if filepath.IsAbs(string(target)) || !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return fmt.Errorf("symlink points outside the install directory: %s", target)
+	}
+	_ = os.Remove(dest)
+	return os.Symlink(string(target), dest)
+}
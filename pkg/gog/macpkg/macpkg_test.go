@@ -0,0 +1,343 @@
+package macpkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+type cpioFile struct {
+	name  string
+	mode  int64
+	data  string
+	ino   int64
+	links int64
+}
+
+var testMTime = time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+
+func odcArchive(files []cpioFile) []byte {
+	var b bytes.Buffer
+	for _, f := range append(files, cpioFile{name: cpioTrailer}) {
+		links := f.links
+		if links == 0 {
+			links = 1
+		}
+		fmt.Fprintf(&b, "070707%06o%06o%06o%06o%06o%06o%06o%011o%06o%011o",
+			0, f.ino, f.mode, 501, 20, links, 0, testMTime.Unix(), len(f.name)+1, len(f.data))
+		b.WriteString(f.name + "\x00")
+		b.WriteString(f.data)
+	}
+	return b.Bytes()
+}
+
+func newcArchive(files []cpioFile) []byte {
+	var b bytes.Buffer
+	for _, f := range append(files, cpioFile{name: cpioTrailer}) {
+		links := f.links
+		if links == 0 {
+			links = 1
+		}
+		fmt.Fprintf(&b, "070701%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x%08x",
+			f.ino, f.mode, 501, 20, links, testMTime.Unix(), len(f.data), 0, 0, 0, 0, len(f.name)+1, 0)
+		b.WriteString(f.name + "\x00")
+		b.Write(make([]byte, pad4(int64(110+len(f.name)+1))))
+		b.WriteString(f.data)
+		b.Write(make([]byte, pad4(int64(len(f.data)))))
+	}
+	return b.Bytes()
+}
+
+func gzipped(data []byte) []byte {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return b.Bytes()
+}
+
+// pbzx wraps data in one xz chunk followed by one stored chunk.
+func pbzx(t *testing.T, data []byte) []byte {
+	half := len(data) / 2
+	var xzb bytes.Buffer
+	w, err := xz.NewWriter(&xzb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write(data[:half])
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	b.WriteString("pbzx")
+	_ = binary.Write(&b, binary.BigEndian, uint64(1<<24))
+	_ = binary.Write(&b, binary.BigEndian, uint64(1<<24))
+	_ = binary.Write(&b, binary.BigEndian, uint64(xzb.Len()))
+	b.Write(xzb.Bytes())
+	_ = binary.Write(&b, binary.BigEndian, uint64(0))
+	_ = binary.Write(&b, binary.BigEndian, uint64(len(data)-half))
+	b.Write(data[half:])
+	return b.Bytes()
+}
+
+type xarEntry struct {
+	name    string
+	data    []byte
+	zlib    bool
+	lzma    bool
+	corrupt bool
+}
+
+// xarArchive builds a .pkg with the given files; names containing a slash
+// are placed inside a component package directory.
+func xarArchive(entries []xarEntry) []byte {
+	var heap bytes.Buffer
+	var top, inner strings.Builder
+	dirName := ""
+	for i, e := range entries {
+		stored := e.data
+		encoding := "application/octet-stream"
+		if e.zlib {
+			var zb bytes.Buffer
+			zw := zlib.NewWriter(&zb)
+			_, _ = zw.Write(e.data)
+			_ = zw.Close()
+			stored = zb.Bytes()
+			encoding = "application/x-gzip"
+		}
+		if e.lzma {
+			var lb bytes.Buffer
+			lw, _ := lzma.NewWriter(&lb)
+			_, _ = lw.Write(e.data)
+			_ = lw.Close()
+			stored = lb.Bytes()
+			encoding = "application/x-lzma"
+		}
+		sum := sha1.Sum(stored)
+		if e.corrupt {
+			sum[0] ^= 0xff
+		}
+		name := e.name
+		dst := &top
+		if dir, base, ok := strings.Cut(e.name, "/"); ok {
+			dirName, name, dst = dir, base, &inner
+		}
+		fmt.Fprintf(dst, `<file id="%d"><name>%s</name><type>file</type><mode>0644</mode>`+
+			`<data><length>%d</length><offset>%d</offset><size>%d</size><encoding style="%s"/>`+
+			`<archived-checksum style="sha1">%s</archived-checksum></data></file>`,
+			i+2, name, len(stored), heap.Len(), len(e.data), encoding, hex.EncodeToString(sum[:]))
+		heap.Write(stored)
+	}
+	if dirName != "" {
+		fmt.Fprintf(&top, `<file id="1"><name>%s</name><type>directory</type>%s</file>`, dirName, inner.String())
+	}
+	toc := `<?xml version="1.0" encoding="UTF-8"?><xar><toc>` + top.String() + `</toc></xar>`
+
+	var tz bytes.Buffer
+	zw := zlib.NewWriter(&tz)
+	_, _ = zw.Write([]byte(toc))
+	_ = zw.Close()
+
+	var b bytes.Buffer
+	_ = binary.Write(&b, binary.BigEndian, xarHeader{
+		Magic: xarMagic, Size: 28, Version: 1,
+		TOCCompressed: uint64(tz.Len()), TOCUncompressed: uint64(len(toc)), ChecksumAlgorithm: 1,
+	})
+	b.Write(tz.Bytes())
+	b.Write(heap.Bytes())
+	return b.Bytes()
+}
+
+const testDistribution = `<?xml version="1.0" encoding="utf-8"?>
+<installer-gui-script minSpecVersion="1">
+  <title>Test Game</title>
+  <pkg-ref id="com.gog.testgame" version="1.2.3">#game.pkg</pkg-ref>
+</installer-gui-script>`
+
+const testPackageInfo = `<pkg-info identifier="com.gog.testgame" version="1.2.3" install-location="/Applications"/>`
+
+var testPayload = []cpioFile{
+	{name: ".", mode: 040755},
+	{name: "./Test Game.app", mode: 040755},
+	{name: "./Test Game.app/Contents/MacOS/game", mode: 0100755, data: "#!/bin/sh\necho hi\n", ino: 3},
+	{name: "./Test Game.app/Contents/Resources/goggame-1207658924.info", mode: 0100644, data: "{}", ino: 4},
+	{name: "./Test Game.app/Contents/Resources/link", mode: 0120777, data: "goggame-1207658924.info", ino: 5},
+}
+
+func writePkg(t *testing.T, entries []xarEntry) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "game.pkg")
+	if err := os.WriteFile(path, xarArchive(entries), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestExtract(t *testing.T) {
+	payloads := map[string][]byte{
+		"gzip odc":  gzipped(odcArchive(testPayload)),
+		"pbzx newc": pbzx(t, newcArchive(testPayload)),
+		"raw odc":   odcArchive(testPayload),
+	}
+	for name, payload := range payloads {
+		t.Run(name, func(t *testing.T) {
+			path := writePkg(t, []xarEntry{
+				{name: "Distribution", data: []byte(testDistribution), zlib: true},
+				{name: "game.pkg/PackageInfo", data: []byte(testPackageInfo), lzma: true},
+				{name: "game.pkg/Payload", data: payload},
+			})
+			p, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer func() { _ = p.Close() }()
+
+			if p.Title != "Test Game" || p.Version != "1.2.3" || p.Identifier != "com.gog.testgame" {
+				t.Errorf("got %q %q %q", p.Title, p.Version, p.Identifier)
+			}
+			if p.InstallLocation != "/Applications" {
+				t.Errorf("InstallLocation = %q", p.InstallLocation)
+			}
+
+			entries, err := p.Entries()
+			if err != nil {
+				t.Fatalf("Entries: %v", err)
+			}
+			if len(entries) != 4 {
+				t.Fatalf("got %d entries, want 4", len(entries))
+			}
+			var paths []string
+			for _, e := range entries {
+				paths = append(paths, e.Path)
+			}
+			if id := GameID(paths); id != "1207658924" {
+				t.Errorf("GameID = %q", id)
+			}
+
+			out := t.TempDir()
+			written, err := p.Extract(out)
+			if err != nil {
+				t.Fatalf("Extract: %v", err)
+			}
+			if len(written) != 3 {
+				t.Errorf("written = %v", written)
+			}
+
+			exe := filepath.Join(out, "Test Game.app", "Contents", "MacOS", "game")
+			info, err := os.Stat(exe)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0755 {
+				t.Errorf("mode = %v, want 0755", info.Mode().Perm())
+			}
+			if !info.ModTime().Equal(testMTime) {
+				t.Errorf("mod time = %v", info.ModTime())
+			}
+			target, err := os.Readlink(filepath.Join(out, "Test Game.app", "Contents", "Resources", "link"))
+			if err != nil || target != "goggame-1207658924.info" {
+				t.Errorf("symlink = %q, %v", target, err)
+			}
+		})
+	}
+}
+
+func TestExtractHardLinks(t *testing.T) {
+	payload := newcArchive([]cpioFile{
+		{name: "./a", mode: 0100644, ino: 7, links: 2},
+		{name: "./b", mode: 0100644, data: "shared", ino: 7, links: 2},
+		{name: "./empty1", mode: 0100644, ino: 8, links: 2},
+		{name: "./empty2", mode: 0100644, ino: 8, links: 2},
+	})
+	p, err := Open(writePkg(t, []xarEntry{{name: "Payload", data: gzipped(payload)}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+
+	out := t.TempDir()
+	if _, err := p.Extract(out); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil || string(data) != "shared" {
+			t.Errorf("%s = %q, %v", name, data, err)
+		}
+	}
+	for _, name := range []string{"empty1", "empty2"} {
+		if info, err := os.Stat(filepath.Join(out, name)); err != nil || info.Size() != 0 {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestExtractRejectsEscapes(t *testing.T) {
+	payload := odcArchive([]cpioFile{
+		{name: "./evil", mode: 0120777, data: "../../etc/passwd"},
+	})
+	p, err := Open(writePkg(t, []xarEntry{{name: "Payload", data: payload}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	if _, err := p.Extract(t.TempDir()); err == nil {
+		t.Fatal("expected error for escaping symlink")
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	p, err := Open(writePkg(t, []xarEntry{{name: "Payload", data: odcArchive(testPayload), corrupt: true}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Close() }()
+	if _, err := p.Entries(); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("got %v, want checksum mismatch", err)
+	}
+}
+
+func TestOpenNotPkg(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "game.pkg")
+	if err := os.WriteFile(path, []byte("not a package"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCPIOReadPartial(t *testing.T) {
+	// Skipping unread data must land on the next header
+	cr := newCPIO(bytes.NewReader(newcArchive([]cpioFile{
+		{name: "x", mode: 0100644, data: "hello world"},
+		{name: "y", mode: 0100644, data: "z"},
+	})))
+	e, err := cr.Next()
+	if err != nil || e.Path != "x" {
+		t.Fatalf("first = %v, %v", e, err)
+	}
+	buf := make([]byte, 3)
+	_, _ = io.ReadFull(cr, buf)
+	e, err = cr.Next()
+	if err != nil || e.Path != "y" {
+		t.Fatalf("second = %v, %v", e, err)
+	}
+	if _, err := cr.Next(); err != io.EOF {
+		t.Fatalf("got %v, want EOF", err)
+	}
+}
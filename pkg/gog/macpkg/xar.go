@@ -0,0 +1,212 @@
+package macpkg
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/ulikunitz/xz"
+	"github.com/ulikunitz/xz/lzma"
+)
+
+const xarMagic = 0x78617221 // "xar!"
+
+var errNotXar = errors.New("not a xar archive")
+
+// xarHeader is the fixed big-endian header at the start of the archive.
+type xarHeader struct {
+	Magic             uint32
+	Size              uint16
+	Version           uint16
+	TOCCompressed     uint64
+	TOCUncompressed   uint64
+	ChecksumAlgorithm uint32
+}
+
+type tocChecksum struct {
+	Style string `xml:"style,attr"`
+	Value string `xml:",chardata"`
+}
+
+type tocData struct {
+	Length   int64 `xml:"length"`
+	Offset   int64 `xml:"offset"`
+	Size     int64 `xml:"size"`
+	Encoding struct {
+		Style string `xml:"style,attr"`
+	} `xml:"encoding"`
+	Archived tocChecksum `xml:"archived-checksum"`
+}
+
+type tocFile struct {
+	Name  string    `xml:"name"`
+	Type  string    `xml:"type"`
+	Mode  string    `xml:"mode"`
+	Data  *tocData  `xml:"data"`
+	Files []tocFile `xml:"file"`
+}
+
+type toc struct {
+	Files []tocFile `xml:"toc>file"`
+}
+
+// xarFile is one entry of the archive's table of contents.
+type xarFile struct {
+	Name string // full slash separated path
+	Type string // file, directory or symlink
+	Mode int64
+	Size int64 // extracted size
+
+	data *tocData
+	r    io.ReaderAt
+	heap int64
+}
+
+// readXar parses the table of contents of the xar archive in r.
+func readXar(r io.ReaderAt) ([]*xarFile, error) {
+	var h xarHeader
+	if err := binary.Read(io.NewSectionReader(r, 0, 28), binary.BigEndian, &h); err != nil {
+		return nil, errNotXar
+	}
+	if h.Magic != xarMagic {
+		return nil, errNotXar
+	}
+
+	zr, err := zlib.NewReader(io.NewSectionReader(r, int64(h.Size), int64(h.TOCCompressed)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table of contents: %w", err)
+	}
+	var t toc
+	if err := xml.NewDecoder(io.LimitReader(zr, int64(h.TOCUncompressed))).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to parse table of contents: %w", err)
+	}
+
+	heap := int64(h.Size) + int64(h.TOCCompressed)
+	var files []*xarFile
+	var walk func(dir string, entries []tocFile)
+	walk = func(dir string, entries []tocFile) {
+		for _, e := range entries {
+			f := &xarFile{Name: path.Join(dir, e.Name), Type: e.Type, data: e.Data, r: r, heap: heap}
+			f.Mode, _ = strconv.ParseInt(e.Mode, 8, 64)
+			if e.Data != nil {
+				f.Size = e.Data.Size
+			}
+			files = append(files, f)
+			walk(f.Name, e.Files)
+		}
+	}
+	walk("", t.Files)
+	return files, nil
+}
+
+// Open returns the decoded contents of the file. The archived checksum is
+// verified as the data is read; a mismatch is reported by Close.
+func (f *xarFile) Open() (io.ReadCloser, error) {
+	if f.data == nil {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+	raw, err := newChecked(io.NewSectionReader(f.r, f.heap+f.data.Offset, f.data.Length), f.data.Archived)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Name, err)
+	}
+
+	var r io.Reader
+	switch style := f.data.Encoding.Style; style {
+	case "", "application/octet-stream":
+		r = raw
+	case "application/x-gzip":
+		// xar's "gzip" is a bare zlib stream
+		if r, err = zlib.NewReader(raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	case "application/x-bzip2":
+		r = bzip2.NewReader(raw)
+	case "application/x-xz":
+		if r, err = xz.NewReader(raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	case "application/x-lzma":
+		// A classic .lzma stream, not the xz container
+		if r, err = lzma.NewReader(raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported encoding %s", f.Name, style)
+	}
+	return &xarReader{Reader: r, raw: raw, name: f.Name}, nil
+}
+
+type xarReader struct {
+	io.Reader
+	raw  *checkedReader
+	name string
+}
+
+func (x *xarReader) Close() error {
+	if err := x.raw.finish(); err != nil {
+		return fmt.Errorf("%s: %w", x.name, err)
+	}
+	return nil
+}
+
+// checkedReader hashes the archived bytes as they are read.
+type checkedReader struct {
+	r    io.Reader
+	h    hash.Hash
+	want []byte
+}
+
+func newChecked(r io.Reader, sum tocChecksum) (*checkedReader, error) {
+	c := &checkedReader{r: r}
+	switch strings.ToLower(sum.Style) {
+	case "sha1":
+		c.h = sha1.New()
+	case "sha256":
+		c.h = sha256.New()
+	case "md5":
+		c.h = md5.New()
+	default:
+		return c, nil
+	}
+	want, err := hex.DecodeString(strings.TrimSpace(sum.Value))
+	if err != nil {
+		return nil, fmt.Errorf("bad checksum %q", sum.Value)
+	}
+	c.want = want
+	return c, nil
+}
+
+func (c *checkedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if c.h != nil {
+		c.h.Write(p[:n])
+	}
+	return n, err
+}
+
+// finish hashes whatever the decoder left unread and compares the sum.
+func (c *checkedReader) finish() error {
+	if c.h == nil {
+		return nil
+	}
+	if _, err := io.Copy(io.Discard, c); err != nil {
+		return err
+	}
+	if !bytes.Equal(c.h.Sum(nil), c.want) {
+		return errors.New("checksum mismatch")
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+// Package mojosetup reads GOG's Linux installers: a makeself shell script
+// carrying the MojoSetup binary, followed by a zip archive with the game data.
+package mojosetup
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DataPrefix is where the game files live inside the zip payload.
+const DataPrefix = "data/noarch/"
+
+// maxHeader bounds how much of the script is scanned for the makeself
+// variables. GOG's stubs are ~20 KB.
+const maxHeader = 1 << 20
+
+var (
+	offsetRe    = regexp.MustCompile(`offset=` + "`" + `head -n (\d+) "\$0"`)
+	filesizesRe = regexp.MustCompile(`filesizes="(\d+)"`)
+	labelRe     = regexp.MustCompile(`label="([^"]*)"`)
+)
+
+// Header holds the layout parsed from the makeself script.
+type Header struct {
+	Label      string
+	ScriptSize int64 // bytes of shell script before the MojoSetup archive
+	MojoSize   int64 // bytes of the MojoSetup archive
+}
+
+// ZipOffset is where the game data zip starts.
+func (h Header) ZipOffset() int64 {
+	return h.ScriptSize + h.MojoSize
+}
+
+// GameInfo is the content of data/noarch/gameinfo.
+type GameInfo struct {
+	Name    string
+	Version string
+	Build   string
+}
+
+type Installer struct {
+	Header Header
+	f      *os.File
+	zip    *zip.Reader
+}
+
+// ParseHeader reads the makeself variables from the start of r.
+func ParseHeader(r io.Reader) (Header, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, maxHeader))
+	if err != nil {
+		return Header{}, err
+	}
+	if !bytes.HasPrefix(buf, []byte("#!")) {
+		return Header{}, fmt.Errorf("not a shell script installer")
+	}
+
+	m := offsetRe.FindSubmatch(buf)
+	if m == nil {
+		return Header{}, fmt.Errorf("no makeself offset in installer header")
+	}
+	lines, _ := strconv.Atoi(string(m[1]))
+
+	m = filesizesRe.FindSubmatch(buf)
+	if m == nil {
+		return Header{}, fmt.Errorf("no makeself filesizes in installer header")
+	}
+	mojoSize, _ := strconv.ParseInt(string(m[1]), 10, 64)
+
+	// offset is the byte length of the first n lines of the script
+	var scriptSize int64
+	for i := 0; i < lines; i++ {
+		nl := bytes.IndexByte(buf[scriptSize:], '\n')
+		if nl < 0 {
+			return Header{}, fmt.Errorf("installer header shorter than %d lines", lines)
+		}
+		scriptSize += int64(nl) + 1
+	}
+
+	h := Header{ScriptSize: scriptSize, MojoSize: mojoSize}
+	if m := labelRe.FindSubmatch(buf); m != nil {
+		h.Label = string(m[1])
+	}
+	return h, nil
+}
+
+// Open parses the installer at path and opens its zip payload.
+func Open(path string) (*Installer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	inst, err := newInstaller(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return inst, nil
+}
+
+func newInstaller(f *os.File) (*Installer, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := ParseHeader(f)
+	if err != nil {
+		return nil, err
+	}
+	if h.ZipOffset() >= info.Size() {
+		return nil, fmt.Errorf("installer truncated: payload starts at %d but file is %d bytes", h.ZipOffset(), info.Size())
+	}
+
+	size := info.Size() - h.ZipOffset()
+	zr, err := zip.NewReader(io.NewSectionReader(f, h.ZipOffset(), size), size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read game data: %w", err)
+	}
+	return &Installer{Header: h, f: f, zip: zr}, nil
+}
+
+func (i *Installer) Close() error {
+	return i.f.Close()
+}
+
+// Files returns the game files in the payload, skipping MojoSetup's own
+// scripts and assets.
+func (i *Installer) Files() []*zip.File {
+	var files []*zip.File
+	for _, f := range i.zip.File {
+		if strings.HasPrefix(f.Name, DataPrefix) && f.Name != DataPrefix {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// GameInfo reads the name and version GOG stores in the payload.
+func (i *Installer) GameInfo() (GameInfo, error) {
+	f, err := i.zip.Open(DataPrefix + "gameinfo")
+	if err != nil {
+		return GameInfo{}, err
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return GameInfo{}, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var gi GameInfo
+	for n, line := range lines {
+		line = strings.TrimSpace(line)
+		switch n {
+		case 0:
+			gi.Name = line
+		case 1:
+			gi.Version = line
+		case 2:
+			gi.Build = line
+		}
+	}
+	return gi, nil
+}
+
+// Extract writes the game files into dir, keeping their permission bits, and
+// returns the paths it created relative to dir.
+func (i *Installer) Extract(dir string) ([]string, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, f := range i.Files() {
+		rel := strings.TrimPrefix(f.Name, DataPrefix)
+		dest := filepath.Join(root, filepath.FromSlash(rel))
+		if !strings.HasPrefix(dest, root+string(filepath.Separator)) {
+			return written, fmt.Errorf("refusing to extract %s outside %s", f.Name, dir)
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return written, err
+			}
+			continue
+		case mode&os.ModeSymlink != 0:
+			err = extractSymlink(f, dest, root)
+		default:
+			err = extractFile(f, dest, mode.Perm())
+		}
+		if err != nil {
+			return written, fmt.Errorf("failed to extract %s: %w", rel, err)
+		}
+		written = append(written, filepath.ToSlash(strings.TrimSuffix(rel, "/")))
+	}
+	return written, nil
+}
+
+func extractFile(f *zip.File, dest string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	// OpenFile's perm is filtered by umask, so set the archived bits explicitly
+	return os.Chmod(dest, perm)
+}
+
+func extractSymlink(f *zip.File, dest, root string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+
+	target, err := io.ReadAll(rc)
+	if err != nil {
+		return err
+	}
+	resolved := filepath.Join(filepath.Dir(dest), string(target))
+	if filepath.IsAbs(string(target)) || !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+		return fmt.Errorf("symlink points outside the install directory: %s", target)
+	}
+	_ = os.Remove(dest)
+	return os.Symlink(string(target), dest)
+}
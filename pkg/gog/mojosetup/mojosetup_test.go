@@ -0,0 +1,161 @@
+package mojosetup
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeInstaller builds a minimal makeself/MojoSetup style installer.
+func writeInstaller(t *testing.T, files map[string]string, modes map[string]os.FileMode) string {
+	t.Helper()
+
+	mojo := bytes.Repeat([]byte{0x1f, 0x8b}, 50)
+
+	var zbuf bytes.Buffer
+	zw := zip.NewWriter(&zbuf)
+	for name, content := range files {
+		hdr := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		mode := os.FileMode(0644)
+		if m, ok := modes[name]; ok {
+			mode = m
+		}
+		hdr.SetMode(mode)
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	script := strings.Join([]string{
+		"#!/bin/sh",
+		"# This script was generated using Makeself 2.1.5",
+		`label="Test Game (GOG.com)"`,
+		`offset=` + "`" + `head -n 6 "$0" | wc -c | tr -d " "` + "`",
+		fmt.Sprintf(`filesizes="%d"`, len(mojo)),
+		"exit 0",
+	}, "\n") + "\n"
+
+	path := filepath.Join(t.TempDir(), "test_game.sh")
+	data := append([]byte(script), mojo...)
+	data = append(data, zbuf.Bytes()...)
+	if err := os.WriteFile(path, data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseHeader(t *testing.T) {
+	script := "#!/bin/sh\nlabel=\"Game\"\noffset=`head -n 3 \"$0\" | wc -c | tr -d \" \"`\nfilesizes=\"1234\"\n"
+	h, err := ParseHeader(strings.NewReader(script))
+	if err != nil {
+		t.Fatalf("ParseHeader: %v", err)
+	}
+	wantScript := int64(len("#!/bin/sh\nlabel=\"Game\"\noffset=`head -n 3 \"$0\" | wc -c | tr -d \" \"`\n"))
+	if h.ScriptSize != wantScript {
+		t.Errorf("ScriptSize = %d, want %d", h.ScriptSize, wantScript)
+	}
+	if h.MojoSize != 1234 {
+		t.Errorf("MojoSize = %d, want 1234", h.MojoSize)
+	}
+	if h.ZipOffset() != wantScript+1234 {
+		t.Errorf("ZipOffset = %d, want %d", h.ZipOffset(), wantScript+1234)
+	}
+	if h.Label != "Game" {
+		t.Errorf("Label = %q, want %q", h.Label, "Game")
+	}
+
+	if _, err := ParseHeader(strings.NewReader("MZ\x90\x00")); err == nil {
+		t.Error("ParseHeader should reject non-script input")
+	}
+	if _, err := ParseHeader(strings.NewReader("#!/bin/sh\necho hi\n")); err == nil {
+		t.Error("ParseHeader should fail without makeself variables")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	path := writeInstaller(t, map[string]string{
+		"scripts/mojosetup_init.lua": "-- installer script",
+		"data/noarch/gameinfo":       "Test Game\n1.2.3\n4567\n",
+		"data/noarch/start.sh":       "#!/bin/sh\n./game/bin\n",
+		"data/noarch/game/data.pak":  "game data",
+	}, map[string]os.FileMode{
+		"data/noarch/start.sh": 0755,
+	})
+
+	inst, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = inst.Close() }()
+
+	if inst.Header.Label != "Test Game (GOG.com)" {
+		t.Errorf("Label = %q", inst.Header.Label)
+	}
+
+	gi, err := inst.GameInfo()
+	if err != nil {
+		t.Fatalf("GameInfo: %v", err)
+	}
+	if gi.Name != "Test Game" || gi.Version != "1.2.3" || gi.Build != "4567" {
+		t.Errorf("GameInfo = %+v", gi)
+	}
+
+	if len(inst.Files()) != 3 {
+		t.Errorf("got %d files, want 3 (scripts/ excluded)", len(inst.Files()))
+	}
+
+	dir := filepath.Join(t.TempDir(), "game")
+	written, err := inst.Extract(dir)
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	if len(written) != 3 {
+		t.Errorf("written = %v, want 3 files", written)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "game", "data.pak"))
+	if err != nil {
+		t.Fatalf("reading extracted file: %v", err)
+	}
+	if string(data) != "game data" {
+		t.Errorf("data.pak = %q", data)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "start.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("start.sh mode = %v, want 0755", info.Mode().Perm())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scripts")); !os.IsNotExist(err) {
+		t.Error("installer scripts should not be extracted")
+	}
+}
+
+func TestExtractRejectsTraversal(t *testing.T) {
+	path := writeInstaller(t, map[string]string{
+		"data/noarch/../../../evil": "nope",
+	}, nil)
+
+	inst, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = inst.Close() }()
+
+	if _, err := inst.Extract(t.TempDir()); err == nil {
+		t.Error("Extract should refuse paths outside the target directory")
+	}
+}
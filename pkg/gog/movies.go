@@ -0,0 +1,72 @@
+package gog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type filteredOwnedResponse struct {
+	TotalPages int       `json:"totalPages"`
+	Products   []Product `json:"products"`
+}
+
+// GetOwnedMovies returns the movies and documentaries in the library.
+// GetOwnedGameIDs includes their IDs too.
+func (c *Client) GetOwnedMovies() ([]Product, error) {
+	var movies []Product
+	for page, pages := 1, 1; page <= pages; page++ {
+		q := url.Values{"mediaType": {"2"}, "page": {strconv.Itoa(page)}}
+		resp, err := c.AuthGet(c.embedBaseURL() + "/account/getFilteredProducts?" + q.Encode())
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			body, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			return nil, fmt.Errorf("failed to get owned movies (%d): %s", resp.StatusCode, body)
+		}
+		var result filteredOwnedResponse
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		pages = result.TotalPages
+		for _, p := range result.Products {
+			p.IsMovie = true
+			movies = append(movies, p)
+		}
+	}
+	return movies, nil
+}
+
+// WithoutMovies returns ids minus the IDs of movies.
+func WithoutMovies(ids []int, movies []Product) []int {
+	isMovie := make(map[int]bool, len(movies))
+	for _, m := range movies {
+		isMovie[m.ID] = true
+	}
+	var games []int
+	for _, id := range ids {
+		if !isMovie[id] {
+			games = append(games, id)
+		}
+	}
+	return games
+}
+
+// SafeFileName turns s into a file name that is valid on every OS.
+func SafeFileName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r < 32, strings.ContainsRune(`<>:"/\|?*`, r):
+			return '_'
+		}
+		return r
+	}, s)
+	return strings.TrimRight(strings.TrimSpace(s), ". ")
+}
@@ -0,0 +1,147 @@
+package gog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetOwnedMovies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/account/getFilteredProducts" || r.URL.Query().Get("mediaType") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"totalPages": 2, "products": [{"id": 10, "title": "Doc One", "slug": "doc_one", "isMovie": true}]}`)
+		case "2":
+			fmt.Fprint(w, `{"totalPages": 2, "products": [{"id": 11, "title": "Doc Two", "slug": "doc_two"}]}`)
+		}
+	}))
+	defer ts.Close()
+
+	movies, err := newTestClient(ts).GetOwnedMovies()
+	if err != nil {
+		t.Fatalf("GetOwnedMovies: %v", err)
+	}
+	want := []Product{
+		{ID: 10, Title: "Doc One", Slug: "doc_one", IsMovie: true},
+		{ID: 11, Title: "Doc Two", Slug: "doc_two", IsMovie: true},
+	}
+	if !reflect.DeepEqual(movies, want) {
+		t.Errorf("GetOwnedMovies = %+v, want %+v", movies, want)
+	}
+	if ids := WithoutMovies([]int{1, 10, 2, 11}, movies); !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("WithoutMovies = %v, want [1 2]", ids)
+	}
+}
+
+func TestPlanAddMovie(t *testing.T) {
+	details := &GameDetails{
+		Downloads: json.RawMessage(`[["English", [
+			{"manualUrl": "/movie/en1080", "name": "1080p", "size": "4 GB"},
+			{"manualUrl": "/movie/en720", "name": "720p", "size": "2 GB"}]],
+			["Deutsch", {"windows": [{"manualUrl": "/movie/de1080", "name": "1080p", "size": "4 GB"}],
+			"mac": [{"manualUrl": "/movie/de1080", "name": "1080p", "size": "4 GB"}]}]]`),
+		Extras: []Extra{{ManualURL: "/movie/extra1", Name: "Making of: Part 1", Type: "video", Size: "300 MB"}},
+	}
+	movie := Product{ID: 10, Title: "Doc: The Movie", IsMovie: true}
+
+	p := NewPlan(PlanOptions{OS: "linux", Languages: []string{"english"}, Dest: "/dl"})
+	if err := p.AddMovie(movie, details); err != nil {
+		t.Fatalf("AddMovie: %v", err)
+	}
+	var names []string
+	for _, item := range p.Pending() {
+		names = append(names, item.FileName)
+		if item.Dest != filepath.Join("/dl", "Doc_ The Movie") {
+			t.Errorf("%s: dest %s", item.Name, item.Dest)
+		}
+	}
+	want := []string{"Doc_ The Movie (1080p)", "Doc_ The Movie (720p)", "Doc_ The Movie - Making of_ Part 1"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("file names = %q, want %q", names, want)
+	}
+
+	// A file listed for several OSes is downloaded once
+	p = NewPlan(PlanOptions{Languages: []string{"Deutsch"}})
+	if err := p.AddMovie(movie, details); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.Pending()); n != 2 {
+		t.Errorf("got %d pending, want the German video and the extra", n)
+	}
+
+	// Without languages, the names keep the languages apart
+	p = NewPlan(PlanOptions{})
+	if err := p.AddMovie(movie, details); err != nil {
+		t.Fatal(err)
+	}
+	names = nil
+	for _, item := range p.Pending() {
+		names = append(names, item.FileName)
+	}
+	want = []string{"Doc_ The Movie (1080p, English)", "Doc_ The Movie (720p, English)",
+		"Doc_ The Movie (1080p, Deutsch)", "Doc_ The Movie - Making of_ Part 1"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("file names = %q, want %q", names, want)
+	}
+
+	// Files sharing a label in one language are numbered
+	p = NewPlan(PlanOptions{})
+	if err := p.AddMovie(movie, &GameDetails{Downloads: json.RawMessage(`[["English", [
+		{"manualUrl": "/movie/part1", "name": "Doc: The Movie"},
+		{"manualUrl": "/movie/part2", "name": "Doc: The Movie"}]]]`)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Pending(); len(got) != 2 || got[0].FileName != "Doc_ The Movie" || got[1].FileName != "Doc_ The Movie 2" {
+		t.Errorf("Pending = %+v", got)
+	}
+
+	// Extras share the numbering, with each other and with the videos
+	p = NewPlan(PlanOptions{})
+	if err := p.AddMovie(movie, &GameDetails{
+		Downloads: json.RawMessage(`[["English", [{"manualUrl": "/movie/en", "name": "Doc: The Movie"}]]]`),
+		Extras: []Extra{
+			{ManualURL: "/movie/extra1", Name: "Trailer"},
+			{ManualURL: "/movie/extra2", Name: "Trailer"},
+			{ManualURL: "/movie/extra3", Name: ""},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	names = nil
+	for _, item := range p.Pending() {
+		names = append(names, item.FileName)
+	}
+	want = []string{"Doc_ The Movie", "Doc_ The Movie - Trailer", "Doc_ The Movie - Trailer 2", "Doc_ The Movie 2"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("file names = %q, want %q", names, want)
+	}
+
+	p = NewPlan(PlanOptions{Languages: []string{"français"}})
+	if err := p.AddMovie(movie, details); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Items) != 1 || p.Items[0].Skip == "" {
+		t.Errorf("Items = %+v, want one skipped item", p.Items)
+	}
+}
+
+func TestSafeFileName(t *testing.T) {
+	tests := map[string]string{
+		"Plain Title":          "Plain Title",
+		`What? A "Movie": 2/3`: "What_ A _Movie__ 2_3",
+		"Trailing dots...":     "Trailing dots",
+	}
+	for in, want := range tests {
+		if got := SafeFileName(in); got != want {
+			t.Errorf("SafeFileName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package gog
+
+import (
+	"sort"
+	"strings"
+)
+
+// Update is a local install or downloaded installer whose version differs
+// from the one GOG currently offers.
+type Update struct {
+	GameID   int    `json:"game_id"`
+	Title    string `json:"title"`
+	Kind     string `json:"kind"` // "install" or "download"
+	OS       string `json:"os,omitempty"`
+	Language string `json:"language,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Current  string `json:"current"`
+	Latest   string `json:"latest"`
+	Location string `json:"location"` // install directory or downloaded file
+}
+
+const (
+	UpdateInstall  = "install"
+	UpdateDownload = "download"
+)
+
+// LatestVersion returns the version GOG offers for targetOS in language, or
+// for any language if that one isn't available. It is "" when unknown.
+func LatestVersion(installers []Installer, targetOS, language string) string {
+	var fallback string
+	for _, inst := range FilterInstallersByOS(installers, targetOS) {
+		if inst.Version == "" {
+			continue
+		}
+		if language == "" || strings.EqualFold(inst.Language, language) {
+			return inst.Version
+		}
+		if fallback == "" {
+			fallback = inst.Version
+		}
+	}
+	return fallback
+}
+
+// IsOutdated reports whether local differs from latest. GOG only serves the
+// current build and its version strings don't sort reliably, so any
+// difference counts; unknown versions never do.
+func IsOutdated(local, latest string) bool {
+	local, latest = strings.TrimSpace(local), strings.TrimSpace(latest)
+	return local != "" && latest != "" && !strings.EqualFold(local, latest)
+}
+
+// LatestBuild looks up the newest content-system build on the branch an
+// install follows, returning its ID and version name.
+type LatestBuild func(g InstalledGame) (id, version string, err error)
+
+// FindUpdates compares the registry against the installers fetch returns
+// for each game. Games installed from a content-system build are compared
+// against latestBuild instead, if given. Entries without a game ID can't be
+// checked and are skipped, as are games pinned to a build.
+// Downloads are reported once per game, OS, language and version.
+func FindUpdates(reg *Registry, fetch func(id int) ([]Installer, error), latestBuild LatestBuild) ([]Update, error) {
+	cache := make(map[int][]Installer)
+	installers := func(id int) ([]Installer, error) {
+		if inst, ok := cache[id]; ok {
+			return inst, nil
+		}
+		inst, err := fetch(id)
+		if err != nil {
+			return nil, err
+		}
+		cache[id] = inst
+		return inst, nil
+	}
+
+	var updates []Update
+	for _, g := range reg.Games {
+		if g.ID == 0 || g.Pinned {
+			continue
+		}
+		if g.Build != "" {
+			if latestBuild == nil {
+				continue
+			}
+			id, version, err := latestBuild(g)
+			if err != nil {
+				return nil, err
+			}
+			if id != "" && id != g.Build {
+				updates = append(updates, Update{
+					GameID: g.ID, Title: g.Title, Kind: UpdateInstall, OS: g.OS, Language: g.Language, Branch: g.Branch,
+					Current: buildVersion(g.Version, g.Build), Latest: buildVersion(version, id), Location: g.Dir,
+				})
+			}
+			continue
+		}
+		inst, err := installers(g.ID)
+		if err != nil {
+			return nil, err
+		}
+		latest := LatestVersion(inst, g.OS, g.Language)
+		if IsOutdated(g.Version, latest) {
+			updates = append(updates, Update{
+				GameID: g.ID, Title: g.Title, Kind: UpdateInstall, OS: g.OS, Language: g.Language,
+				Current: g.Version, Latest: latest, Location: g.Dir,
+			})
+		}
+	}
+
+	seen := make(map[Update]bool)
+	for _, d := range reg.Downloads {
+		if d.GameID == 0 {
+			continue
+		}
+		inst, err := installers(d.GameID)
+		if err != nil {
+			return nil, err
+		}
+		latest := LatestVersion(inst, d.OS, d.Language)
+		if !IsOutdated(d.Version, latest) {
+			continue
+		}
+		u := Update{
+			GameID: d.GameID, Title: d.Title, Kind: UpdateDownload, OS: d.OS, Language: d.Language,
+			Current: d.Version, Latest: latest,
+		}
+		if seen[u] {
+			continue
+		}
+		seen[u] = true
+		u.Location = d.Path
+		updates = append(updates, u)
+	}
+
+	sort.SliceStable(updates, func(i, j int) bool {
+		return updates[i].Title < updates[j].Title
+	})
+	return updates, nil
+}
+
+// buildVersion describes a build for Update, e.g. "1.2 (build 5209)".
+func buildVersion(version, id string) string {
+	if version == "" {
+		return "build " + id
+	}
+	return version + " (build " + id + ")"
+}
@@ -0,0 +1,107 @@
+package gog
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestLatestVersion(t *testing.T) {
+	installers := []Installer{
+		{OS: "windows", Language: "English", Version: "1.5"},
+		{OS: "windows", Language: "Deutsch", Version: "1.4"},
+		{OS: "linux", Language: "English", Version: ""},
+	}
+	tests := []struct {
+		os, lang string
+		want     string
+	}{
+		{"windows", "English", "1.5"},
+		{"windows", "Deutsch", "1.4"},
+		{"windows", "Polski", "1.5"},
+		{"windows", "", "1.5"},
+		{"linux", "English", ""},
+		{"mac", "English", ""},
+	}
+	for _, tt := range tests {
+		if got := LatestVersion(installers, tt.os, tt.lang); got != tt.want {
+			t.Errorf("LatestVersion(%s, %s) = %q, want %q", tt.os, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestIsOutdated(t *testing.T) {
+	tests := []struct {
+		local, latest string
+		want          bool
+	}{
+		{"1.0", "1.1", true},
+		{"1.1", "1.1", false},
+		{"2.0 (GOG)", " 2.0 (gog)", false},
+		{"", "1.1", false},
+		{"1.0", "", false},
+	}
+	for _, tt := range tests {
+		if got := IsOutdated(tt.local, tt.latest); got != tt.want {
+			t.Errorf("IsOutdated(%q, %q) = %v, want %v", tt.local, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestFindUpdates(t *testing.T) {
+	reg := &Registry{
+		Games: []InstalledGame{
+			{ID: 1, Title: "Witcher", Version: "1.0", OS: "windows", Language: "English", Dir: "/games/witcher"},
+			{ID: 2, Title: "Baldur's Gate", Version: "2.6", OS: "linux", Language: "English", Dir: "/games/bg"},
+			{Title: "From a file", Version: "0.1", Dir: "/games/unknown"},
+			{ID: 3, Title: "Pinned", Version: "0.9", OS: "windows", Build: "100", Pinned: true, Dir: "/games/pinned"},
+			{ID: 4, Title: "Beta", Version: "2.0", OS: "windows", Build: "200", Branch: "beta", Dir: "/games/beta"},
+			{ID: 5, Title: "Current build", Version: "3.0", OS: "windows", Build: "300", Dir: "/games/current"},
+		},
+		Downloads: []DownloadRecord{
+			{GameID: 1, Title: "Witcher", Version: "1.0", OS: "windows", Language: "English", Path: "/dl/setup_witcher.exe"},
+			{GameID: 1, Title: "Witcher", Version: "1.0", OS: "windows", Language: "English", Path: "/dl/setup_witcher-1.bin"},
+			{GameID: 2, Title: "Baldur's Gate", Version: "2.6", OS: "linux", Language: "English", Path: "/dl/bg.sh"},
+		},
+	}
+	calls := make(map[int]int)
+	fetch := func(id int) ([]Installer, error) {
+		calls[id]++
+		switch id {
+		case 1:
+			return []Installer{{OS: "windows", Language: "English", Version: "1.5"}}, nil
+		case 2:
+			return []Installer{{OS: "linux", Language: "English", Version: "2.6"}}, nil
+		}
+		return nil, fmt.Errorf("unexpected id %d", id)
+	}
+
+	latestBuild := func(g InstalledGame) (string, string, error) {
+		switch {
+		case g.ID == 4 && g.Branch == "beta":
+			return "210", "2.1-beta", nil
+		case g.ID == 5 && g.Branch == "":
+			return "300", "3.0", nil
+		}
+		return "", "", fmt.Errorf("unexpected build lookup for %d on %q", g.ID, g.Branch)
+	}
+
+	updates, err := FindUpdates(reg, fetch, latestBuild)
+	if err != nil {
+		t.Fatalf("FindUpdates: %v", err)
+	}
+	if len(updates) != 3 {
+		t.Fatalf("got %d updates, want 3: %+v", len(updates), updates)
+	}
+	if u := updates[0]; u.Branch != "beta" || u.Current != "2.0 (build 200)" || u.Latest != "2.1-beta (build 210)" {
+		t.Errorf("unexpected branch update: %+v", u)
+	}
+	if updates[1].Kind != UpdateInstall || updates[1].Location != "/games/witcher" || updates[1].Latest != "1.5" {
+		t.Errorf("unexpected install update: %+v", updates[1])
+	}
+	if updates[2].Kind != UpdateDownload || updates[2].Location != "/dl/setup_witcher.exe" {
+		t.Errorf("unexpected download update: %+v", updates[2])
+	}
+	if calls[1] != 1 || calls[2] != 1 {
+		t.Errorf("game details fetched more than once: %v", calls)
+	}
+}
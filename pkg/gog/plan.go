@@ -0,0 +1,234 @@
+package gog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PlanItem is one installer file a download would fetch, or the reason a game
+// would be skipped.
+type PlanItem struct {
+	GameID    int    `json:"game_id"`
+	Game      string `json:"game"`
+	Name      string `json:"name,omitempty"`
+	ManualURL string `json:"manual_url,omitempty"`
+	Version   string `json:"version,omitempty"`
+	OS        string `json:"os,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Bytes     int64  `json:"bytes,omitempty"`
+	Dest      string `json:"dest,omitempty"`
+	FileName  string `json:"file_name,omitempty"` // without extension; "" keeps the server's name
+	Skip      string `json:"skip,omitempty"`
+}
+
+// Plan describes what a download would do without resolving or fetching
+// anything, so it can be reviewed and executed later.
+type Plan struct {
+	OS        string     `json:"os"`
+	Languages []string   `json:"languages,omitempty"`
+	Dest      string     `json:"dest"`
+	Items     []PlanItem `json:"items"`
+}
+
+type PlanOptions struct {
+	OS        string
+	Languages []string // preferred languages, names or codes, best first; empty means all
+	Dest      string
+}
+
+// NewPlan returns an empty plan for opts.
+func NewPlan(opts PlanOptions) *Plan {
+	return &Plan{OS: opts.OS, Languages: opts.Languages, Dest: opts.Dest}
+}
+
+// AddGame appends the installers of game that match the plan's OS, in the
+// first of the plan's languages the game has, or a skipped item explaining
+// why nothing matched.
+func (p *Plan) AddGame(game Product, installers []Installer) {
+	skip := func(reason string) {
+		p.Items = append(p.Items, PlanItem{GameID: game.ID, Game: game.Title, Skip: reason})
+	}
+
+	filtered := FilterInstallersByOS(installers, p.OS)
+	if len(filtered) == 0 {
+		skip(fmt.Sprintf("no %s installers", p.OS))
+		return
+	}
+
+	if len(p.Languages) > 0 {
+		lang, ok := p.language(filtered)
+		if !ok {
+			skip(fmt.Sprintf("no installers in %s", strings.Join(p.Languages, ", ")))
+			return
+		}
+		filtered = inLanguage(filtered, lang)
+	}
+
+	for _, inst := range filtered {
+		p.Items = append(p.Items, PlanItem{
+			GameID:    game.ID,
+			Game:      game.Title,
+			Name:      inst.Name,
+			ManualURL: inst.ManualURL,
+			Version:   inst.Version,
+			OS:        inst.OS,
+			Language:  inst.Language,
+			Bytes:     inst.Bytes,
+			Dest:      p.Dest,
+		})
+	}
+}
+
+// AddMovie appends the video files and extras of a movie. They go to a
+// folder named after it, named after the movie and the file's label, e.g.
+// "Movie (1080p).mp4" or "Movie - Making of.mp4". Movies play anywhere, so
+// the plan's OS doesn't apply; its languages pick the videos' language.
+// Without languages every language is added, and the names say which one a
+// file is in, e.g. "Movie (1080p, Deutsch).mp4".
+func (p *Plan) AddMovie(movie Product, details *GameDetails) error {
+	files, err := ParseInstallers(details)
+	if err != nil {
+		return err
+	}
+	videos := files
+	if len(p.Languages) > 0 {
+		lang, _ := p.language(files)
+		videos = inLanguage(files, lang)
+	}
+	multilingual := false
+	for _, f := range videos {
+		multilingual = multilingual || !SameLanguage(f.Language, videos[0].Language)
+	}
+	title := SafeFileName(movie.Title)
+	dest := filepath.Join(p.Dest, title)
+
+	// Files that would share a name are numbered rather than overwritten
+	names := make(map[string]bool)
+	unique := func(name string) string {
+		for base, n := name, 2; names[strings.ToLower(name)]; n++ {
+			name = fmt.Sprintf("%s %d", base, n)
+		}
+		names[strings.ToLower(name)] = true
+		return name
+	}
+
+	seen := make(map[string]bool)
+	added := 0
+	for _, f := range videos {
+		if seen[f.ManualURL] {
+			continue
+		}
+		seen[f.ManualURL] = true
+		var tags []string
+		if label := SafeFileName(f.Name); label != "" && !strings.EqualFold(label, title) {
+			tags = append(tags, label)
+		}
+		if lang := SafeFileName(f.Language); multilingual && lang != "" {
+			tags = append(tags, lang)
+		}
+		name := title
+		if len(tags) > 0 {
+			name = fmt.Sprintf("%s (%s)", title, strings.Join(tags, ", "))
+		}
+		name = unique(name)
+		p.Items = append(p.Items, PlanItem{
+			GameID: movie.ID, Game: movie.Title, Name: f.Name, ManualURL: f.ManualURL, Version: f.Version,
+			Language: f.Language, Bytes: f.Bytes, Dest: dest, FileName: name,
+		})
+		added++
+	}
+	if added == 0 {
+		reason := "no videos"
+		if len(files) > 0 {
+			reason = "no videos in " + strings.Join(p.Languages, ", ")
+		}
+		p.Items = append(p.Items, PlanItem{GameID: movie.ID, Game: movie.Title, Skip: reason})
+		return nil
+	}
+	for _, e := range details.Extras {
+		bytes, _ := ParseSize(e.Size)
+		name := title
+		if label := SafeFileName(e.Name); label != "" {
+			name = title + " - " + label
+		}
+		p.Items = append(p.Items, PlanItem{
+			GameID: movie.ID, Game: movie.Title, Name: e.Name, ManualURL: e.ManualURL,
+			Bytes: bytes, Dest: dest, FileName: unique(name),
+		})
+	}
+	return nil
+}
+
+// language returns the first of the plan's languages installers come in.
+func (p *Plan) language(installers []Installer) (string, bool) {
+	var available []string
+	for _, inst := range installers {
+		available = append(available, inst.Language)
+	}
+	return PreferredLanguage(available, p.Languages)
+}
+
+func inLanguage(installers []Installer, lang string) []Installer {
+	var matched []Installer
+	for _, inst := range installers {
+		if inst.Language == lang {
+			matched = append(matched, inst)
+		}
+	}
+	return matched
+}
+
+// SkipDownloaded marks items whose current version reg already has on disk.
+func (p *Plan) SkipDownloaded(reg *Registry) {
+	for i, item := range p.Items {
+		if item.Skip != "" {
+			continue
+		}
+		if d := reg.Downloaded(item.ManualURL, item.Version); d != nil {
+			p.Items[i].Skip = "already downloaded to " + d.Path
+		}
+	}
+}
+
+// Pending returns the items that would be downloaded.
+func (p *Plan) Pending() []PlanItem {
+	var items []PlanItem
+	for _, item := range p.Items {
+		if item.Skip == "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// TotalBytes sums the sizes of all pending items.
+func (p *Plan) TotalBytes() int64 {
+	var total int64
+	for _, item := range p.Pending() {
+		total += item.Bytes
+	}
+	return total
+}
+
+func SavePlan(p *Plan, path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid plan %s: %w", path, err)
+	}
+	return &p, nil
+}
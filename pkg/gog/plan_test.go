@@ -0,0 +1,133 @@
+package gog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanAddGame(t *testing.T) {
+	installers := []Installer{
+		{ManualURL: "/en/1", Name: "Game (Part 1 of 2)", OS: "windows", Language: "English", Bytes: 4 << 30},
+		{ManualURL: "/en/2", Name: "Game (Part 2 of 2)", OS: "windows", Language: "English", Bytes: 1 << 30},
+		{ManualURL: "/de/1", Name: "Game", OS: "windows", Language: "Deutsch", Bytes: 5 << 30},
+		{ManualURL: "/lin", Name: "Game", OS: "linux", Language: "English", Bytes: 3 << 30},
+	}
+	game := Product{ID: 7, Title: "Game"}
+
+	t.Run("all languages", func(t *testing.T) {
+		p := NewPlan(PlanOptions{OS: "windows", Dest: "/tmp/dl"})
+		p.AddGame(game, installers)
+		if len(p.Pending()) != 3 {
+			t.Fatalf("got %d pending, want 3", len(p.Pending()))
+		}
+		if got, want := p.TotalBytes(), int64(10<<30); got != want {
+			t.Errorf("TotalBytes = %d, want %d", got, want)
+		}
+		for _, item := range p.Items {
+			if item.Dest != "/tmp/dl" || item.GameID != 7 {
+				t.Errorf("item = %+v, want dest /tmp/dl and game 7", item)
+			}
+		}
+	})
+
+	t.Run("language filter is case-insensitive", func(t *testing.T) {
+		p := NewPlan(PlanOptions{OS: "windows", Languages: []string{"english"}})
+		p.AddGame(game, installers)
+		if len(p.Pending()) != 2 {
+			t.Fatalf("got %d pending, want 2", len(p.Pending()))
+		}
+	})
+
+	t.Run("languages are tried in order", func(t *testing.T) {
+		p := NewPlan(PlanOptions{OS: "windows", Languages: []string{"fr", "de", "en"}})
+		p.AddGame(game, installers)
+		if pending := p.Pending(); len(pending) != 1 || pending[0].Language != "Deutsch" {
+			t.Fatalf("pending = %+v, want just the Deutsch installer", pending)
+		}
+		p = NewPlan(PlanOptions{OS: "linux", Languages: []string{"de", "en-US"}})
+		p.AddGame(game, installers)
+		if pending := p.Pending(); len(pending) != 1 || pending[0].ManualURL != "/lin" {
+			t.Fatalf("pending = %+v, want the English fallback", pending)
+		}
+	})
+
+	t.Run("no installers for OS", func(t *testing.T) {
+		p := NewPlan(PlanOptions{OS: "mac"})
+		p.AddGame(game, installers)
+		if len(p.Items) != 1 || p.Items[0].Skip == "" {
+			t.Fatalf("Items = %+v, want one skipped item", p.Items)
+		}
+		if len(p.Pending()) != 0 || p.TotalBytes() != 0 {
+			t.Error("skipped items should not be pending")
+		}
+	})
+
+	t.Run("no installers in language", func(t *testing.T) {
+		p := NewPlan(PlanOptions{OS: "linux", Languages: []string{"Deutsch"}})
+		p.AddGame(game, installers)
+		if len(p.Items) != 1 || p.Items[0].Skip == "" {
+			t.Fatalf("Items = %+v, want one skipped item", p.Items)
+		}
+	})
+}
+
+func TestSaveAndLoadPlan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	original := NewPlan(PlanOptions{OS: "linux", Languages: []string{"English"}, Dest: "/mnt/archive"})
+	original.AddGame(Product{ID: 1, Title: "A"}, []Installer{
+		{ManualURL: "/a", Name: "A", OS: "linux", Language: "English", Version: "1.0", Bytes: 42},
+	})
+	original.AddGame(Product{ID: 2, Title: "B"}, nil)
+
+	if err := SavePlan(original, path); err != nil {
+		t.Fatalf("SavePlan: %v", err)
+	}
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan: %v", err)
+	}
+
+	if loaded.OS != "linux" || loaded.Dest != "/mnt/archive" {
+		t.Errorf("loaded plan = %+v", loaded)
+	}
+	if len(loaded.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(loaded.Items))
+	}
+	if loaded.Items[0] != original.Items[0] {
+		t.Errorf("Items[0] = %+v, want %+v", loaded.Items[0], original.Items[0])
+	}
+	if loaded.Items[1].Skip == "" {
+		t.Error("skip reason was not preserved")
+	}
+}
+
+func TestPlanSkipDownloaded(t *testing.T) {
+	dir := t.TempDir()
+	have := filepath.Join(dir, "setup_game_2.0.exe")
+	if err := os.WriteFile(have, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reg := &Registry{Downloads: []DownloadRecord{
+		{GameID: 1, ManualURL: "/dl/en1installer0", Version: "2.0", Path: have},
+		{GameID: 1, ManualURL: "/dl/en1installer1", Version: "2.0", Path: filepath.Join(dir, "deleted.bin")},
+		{GameID: 1, ManualURL: "/dl/en2installer0", Version: "1.0", Path: have},
+	}}
+
+	p := NewPlan(PlanOptions{OS: "windows", Dest: dir})
+	p.AddGame(Product{ID: 1, Title: "Game"}, []Installer{
+		{ManualURL: "/dl/en1installer0", Version: "2.0", OS: "windows", Language: "English"},
+		{ManualURL: "/dl/en1installer1", Version: "2.0", OS: "windows", Language: "English"},
+		{ManualURL: "/dl/en2installer0", Version: "2.0", OS: "windows", Language: "Deutsch"},
+	})
+	p.SkipDownloaded(reg)
+
+	pending := p.Pending()
+	if len(pending) != 2 {
+		t.Fatalf("got %d pending items, want 2: %+v", len(pending), p.Items)
+	}
+	if p.Items[0].Skip == "" {
+		t.Error("current download on disk was not skipped")
+	}
+}
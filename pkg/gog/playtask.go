@@ -0,0 +1,260 @@
+package gog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var gameInfoFileRe = regexp.MustCompile(`^goggame-(\d+)\.info$`)
+
+// PlayTask is a launch entry from a goggame-<id>.info file.
+type PlayTask struct {
+	Name       string   `json:"name"`
+	Category   string   `json:"category"` // game, tool, document
+	Type       string   `json:"type"`     // FileTask or URLTask
+	IsPrimary  bool     `json:"isPrimary"`
+	Path       string   `json:"path"` // relative to the info file, backslash separated
+	Arguments  string   `json:"arguments"`
+	WorkingDir string   `json:"workingDir"`
+	Link       string   `json:"link"`
+	Languages  []string `json:"languages"`
+}
+
+// GameInfoFile is a goggame-<id>.info file GOG puts in game directories.
+type GameInfoFile struct {
+	GameID     string     `json:"gameId"`
+	RootGameID string     `json:"rootGameId"`
+	Name       string     `json:"name"`
+	Language   string     `json:"language"`
+	PlayTasks  []PlayTask `json:"playTasks"`
+	Dir        string     `json:"-"` // directory holding the file; task paths are relative to it
+}
+
+// LaunchCommand is a resolved play task ready to execute.
+type LaunchCommand struct {
+	Path string
+	Args []string
+	Dir  string
+	URL  string // set instead of Path for URL tasks and documents, which are opened rather than run
+}
+
+func ParseGameInfo(path string) (*GameInfoFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var info GameInfoFile
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+	info.Dir = filepath.Dir(path)
+	return &info, nil
+}
+
+// FindGameInfo returns the game info of an install directory: the file of
+// the base game when DLC info files are present too.
+func FindGameInfo(dir string) (*GameInfoFile, error) {
+	var infos []*GameInfoFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !gameInfoFileRe.MatchString(d.Name()) {
+			return nil
+		}
+		info, err := ParseGameInfo(path)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, fmt.Errorf("no goggame-*.info file in %s", dir)
+	}
+	for _, info := range infos {
+		if len(info.PlayTasks) > 0 && (info.RootGameID == "" || info.GameID == info.RootGameID) {
+			return info, nil
+		}
+	}
+	for _, info := range infos {
+		if len(info.PlayTasks) > 0 {
+			return info, nil
+		}
+	}
+	return infos[0], nil
+}
+
+// Task returns the task called name (case-insensitive, or a unique part of
+// it), or the primary task when name is empty.
+func (g *GameInfoFile) Task(name string) (*PlayTask, error) {
+	if len(g.PlayTasks) == 0 {
+		return nil, fmt.Errorf("%s has no play tasks", g.Name)
+	}
+	if name == "" {
+		for i := range g.PlayTasks {
+			if g.PlayTasks[i].IsPrimary {
+				return &g.PlayTasks[i], nil
+			}
+		}
+		return &g.PlayTasks[0], nil
+	}
+
+	var matches []*PlayTask
+	for i := range g.PlayTasks {
+		t := &g.PlayTasks[i]
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+		if strings.Contains(strings.ToLower(t.Name), strings.ToLower(name)) {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no task matching %q", name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, t := range matches {
+			names[i] = t.Name
+		}
+		return nil, fmt.Errorf("%q matches several tasks: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// wrappers maps bundled Windows launchers to the native programs that can
+// run the same configuration on other systems.
+var wrappers = map[string]string{
+	"dosbox.exe":  "dosbox",
+	"scummvm.exe": "scummvm",
+}
+
+// Command resolves t for goos. Bundled DOSBox and ScummVM builds are
+// swapped for the native ones outside Windows, and other Windows programs
+// are run through Wine. lookPath finds programs on PATH (exec.LookPath).
+func (t *PlayTask) Command(base, goos string, lookPath func(string) (string, error)) (*LaunchCommand, error) {
+	if t.Type == "URLTask" || (t.Path == "" && t.Link != "") {
+		return &LaunchCommand{URL: t.Link}, nil
+	}
+	if t.Path == "" {
+		return nil, fmt.Errorf("task %q has no path", t.Name)
+	}
+
+	windows := goos == "windows"
+	args := splitArgs(t.Arguments)
+	if !windows {
+		for i, a := range args {
+			args[i] = strings.ReplaceAll(a, `\`, "/")
+		}
+	}
+
+	dir := base
+	if t.WorkingDir != "" {
+		dir = resolvePath(base, t.WorkingDir)
+	}
+	path := resolvePath(base, t.Path)
+	exe := strings.ToLower(filepath.Base(filepath.FromSlash(strings.ReplaceAll(t.Path, `\`, "/"))))
+	if isDocument(t.Category, exe) {
+		return &LaunchCommand{URL: path}, nil
+	}
+	cmd := &LaunchCommand{Path: path, Args: args, Dir: dir}
+	if windows {
+		return cmd, nil
+	}
+
+	if native, ok := wrappers[exe]; ok {
+		p, err := lookPath(native)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs %s; install it and make sure it is on your PATH", t.Name, native)
+		}
+		cmd.Path = p
+		return cmd, nil
+	}
+	if strings.HasSuffix(exe, ".exe") {
+		wine, err := lookPath("wine")
+		if err != nil {
+			return nil, fmt.Errorf("%s is a Windows program; install Wine to run it", filepath.Base(path))
+		}
+		cmd.Args = append([]string{path}, args...)
+		cmd.Path = wine
+	}
+	return cmd, nil
+}
+
+// documentExts are file types play tasks point at that can't be executed.
+var documentExts = map[string]bool{
+	".pdf": true, ".txt": true, ".rtf": true, ".htm": true, ".html": true,
+	".doc": true, ".docx": true, ".chm": true, ".jpg": true, ".png": true,
+}
+
+// isDocument reports whether a file task's target, with the lower-cased file
+// name name, should be opened in the default application instead of run.
+func isDocument(category, name string) bool {
+	ext := filepath.Ext(name)
+	if documentExts[ext] {
+		return true
+	}
+	return strings.EqualFold(category, "document") && ext != ".exe" && ext != ".bat" && ext != ".sh"
+}
+
+// resolvePath joins a backslash separated relative path onto base. Windows
+// paths in info files don't always match the case of the files on disk, so
+// each component falls back to a case-insensitive match.
+func resolvePath(base, rel string) string {
+	parts := strings.FieldsFunc(rel, func(r rune) bool { return r == '\\' || r == '/' })
+	p := base
+	for _, part := range parts {
+		next := filepath.Join(p, part)
+		if _, err := os.Lstat(next); errors.Is(err, fs.ErrNotExist) {
+			if entries, err := os.ReadDir(p); err == nil {
+				for _, e := range entries {
+					if strings.EqualFold(e.Name(), part) {
+						next = filepath.Join(p, e.Name())
+						break
+					}
+				}
+			}
+		}
+		p = next
+	}
+	return p
+}
+
+// splitArgs splits a Windows command line on spaces, keeping double quoted
+// sections together.
+func splitArgs(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inQuote, have := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			have = true
+		case (r == ' ' || r == '\t') && !inQuote:
+			if have {
+				args = append(args, cur.String())
+				cur.Reset()
+				have = false
+			}
+		default:
+			cur.WriteRune(r)
+			have = true
+		}
+	}
+	if have {
+		args = append(args, cur.String())
+	}
+	return args
+}
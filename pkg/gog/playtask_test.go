@@ -0,0 +1,167 @@
+package gog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testGameInfo = `{
+  "gameId": "1207658924",
+  "rootGameId": "1207658924",
+  "name": "Test Game",
+  "language": "English",
+  "playTasks": [
+    {"category": "game", "isPrimary": true, "name": "Test Game", "path": "DOSBOX\\DOSBox.exe",
+     "arguments": "-conf \"..\\dosbox_game.conf\" -noconsole -c \"exit\"", "workingDir": "DOSBOX", "type": "FileTask"},
+    {"category": "tool", "name": "Setup", "path": "SETUP.EXE", "type": "FileTask"},
+    {"category": "document", "name": "Manual", "path": "Manual.pdf", "type": "FileTask"},
+    {"category": "document", "name": "Support", "link": "https://www.gog.com/support", "type": "URLTask"}
+  ]
+}`
+
+func writeGameInfo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range []string{"DOSBox/DOSBox.exe", "setup.exe", "Manual.pdf"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "goggame-1207658924.info"), []byte(testGameInfo), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// DLC info without tasks must not win
+	dlc := `{"gameId": "1", "rootGameId": "1207658924", "name": "DLC"}`
+	if err := os.WriteFile(filepath.Join(dir, "goggame-1.info"), []byte(dlc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestFindGameInfo(t *testing.T) {
+	dir := writeGameInfo(t)
+	info, err := FindGameInfo(dir)
+	if err != nil {
+		t.Fatalf("FindGameInfo: %v", err)
+	}
+	if info.Name != "Test Game" || len(info.PlayTasks) != 4 {
+		t.Fatalf("got %+v", info)
+	}
+
+	tests := []struct {
+		query   string
+		want    string
+		wantErr bool
+	}{
+		{"", "Test Game", false},
+		{"setup", "Setup", false},
+		{"MAN", "Manual", false},
+		{"s", "", true}, // Setup and Support
+		{"editor", "", true},
+	}
+	for _, tt := range tests {
+		task, err := info.Task(tt.query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Task(%q) error = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if err == nil && task.Name != tt.want {
+			t.Errorf("Task(%q) = %q, want %q", tt.query, task.Name, tt.want)
+		}
+	}
+
+	if _, err := FindGameInfo(t.TempDir()); err == nil {
+		t.Error("expected error for directory without info file")
+	}
+}
+
+func TestPlayTaskCommand(t *testing.T) {
+	dir := writeGameInfo(t)
+	info, err := FindGameInfo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	onPath := func(have ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, h := range have {
+				if h == name {
+					return "/usr/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	dosbox, _ := info.Task("")
+	cmd, err := dosbox.Command(dir, "linux", onPath("dosbox"))
+	if err != nil {
+		t.Fatalf("Command: %v", err)
+	}
+	if cmd.Path != "/usr/bin/dosbox" || cmd.Dir != filepath.Join(dir, "DOSBox") {
+		t.Errorf("got %+v", cmd)
+	}
+	if want := []string{"-conf", "../dosbox_game.conf", "-noconsole", "-c", "exit"}; !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("args = %q, want %q", cmd.Args, want)
+	}
+	if _, err := dosbox.Command(dir, "linux", onPath()); err == nil {
+		t.Error("expected error without native dosbox")
+	}
+
+	cmd, err = dosbox.Command(dir, "windows", onPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Path != filepath.Join(dir, "DOSBox", "DOSBox.exe") {
+		t.Errorf("windows path = %s", cmd.Path)
+	}
+	if cmd.Args[1] != `..\dosbox_game.conf` {
+		t.Errorf("windows args = %q", cmd.Args)
+	}
+
+	setup, _ := info.Task("setup")
+	cmd, err = setup.Command(dir, "linux", onPath("wine"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Path != "/usr/bin/wine" || cmd.Args[0] != filepath.Join(dir, "setup.exe") {
+		t.Errorf("wine command = %+v", cmd)
+	}
+
+	manual, _ := info.Task("manual")
+	for _, goos := range []string{"linux", "windows"} {
+		cmd, err = manual.Command(dir, goos, onPath("wine"))
+		if err != nil || cmd.Path != "" || cmd.URL != filepath.Join(dir, "Manual.pdf") {
+			t.Errorf("%s document command = %+v, %v", goos, cmd, err)
+		}
+	}
+
+	support, _ := info.Task("support")
+	cmd, err = support.Command(dir, "linux", onPath())
+	if err != nil || cmd.URL != "https://www.gog.com/support" {
+		t.Errorf("url command = %+v, %v", cmd, err)
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"-a -b", []string{"-a", "-b"}},
+		{`-c "C:\Program Files\x.ini"  game`, []string{"-c", `C:\Program Files\x.ini`, "game"}},
+		{`""`, []string{""}},
+	}
+	for _, tt := range tests {
+		if got := splitArgs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
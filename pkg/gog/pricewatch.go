@@ -0,0 +1,249 @@
+package gog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Watch is a product followed by 'goggle pricewatch'.
+type Watch struct {
+	ID      int       `json:"id"`
+	Title   string    `json:"title"`
+	Below   float64   `json:"below,omitempty"` // alert threshold, 0 for none
+	AddedAt time.Time `json:"added_at"`
+}
+
+// PricePoint is a price seen at a check. Points are only added when the
+// price changes.
+type PricePoint struct {
+	Time     time.Time `json:"time"`
+	Final    float64   `json:"final"`
+	Base     float64   `json:"base"`
+	Discount int       `json:"discount,omitempty"`
+	Currency string    `json:"currency"`
+}
+
+type PriceHistory struct {
+	Title  string       `json:"title"`
+	Points []PricePoint `json:"points"`
+}
+
+// PriceAlert is a watch that fired at a check.
+type PriceAlert struct {
+	ID     int     `json:"id"`
+	Title  string  `json:"title"`
+	Price  Price   `json:"price"`
+	Reason string  `json:"reason"`
+	Low    float64 `json:"previous_low,omitempty"`
+}
+
+// PriceWatch holds the watched products and the price history of everything
+// checked, stored in ~/.config/goggle/prices.json.
+type PriceWatch struct {
+	Watches []Watch               `json:"watches"`
+	History map[int]*PriceHistory `json:"history"`
+
+	path string
+}
+
+func LoadPriceWatch() (*PriceWatch, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPriceWatchFrom(filepath.Join(home, ".config", "goggle", "prices.json"))
+}
+
+func LoadPriceWatchFrom(path string) (*PriceWatch, error) {
+	w := &PriceWatch{History: make(map[int]*PriceHistory), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return w, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if w.History == nil {
+		w.History = make(map[int]*PriceHistory)
+	}
+	return w, nil
+}
+
+// Save writes the watch list and history back to the file they were loaded
+// from.
+func (w *PriceWatch) Save() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
+
+// Add watches a product, updating the threshold if it is already watched.
+func (w *PriceWatch) Add(watch Watch) {
+	for i, old := range w.Watches {
+		if old.ID == watch.ID {
+			watch.AddedAt = old.AddedAt
+			w.Watches[i] = watch
+			return
+		}
+	}
+	w.Watches = append(w.Watches, watch)
+	sort.Slice(w.Watches, func(i, j int) bool { return w.Watches[i].Title < w.Watches[j].Title })
+}
+
+// Watched returns the watch for id, or nil if it isn't watched.
+func (w *PriceWatch) Watched(id int) *Watch {
+	for i := range w.Watches {
+		if w.Watches[i].ID == id {
+			return &w.Watches[i]
+		}
+	}
+	return nil
+}
+
+// Remove stops watching the product with id. The history is kept.
+func (w *PriceWatch) Remove(id int) bool {
+	for i, old := range w.Watches {
+		if old.ID == id {
+			w.Watches = append(w.Watches[:i], w.Watches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Last returns the most recent price recorded for id, or nil.
+func (w *PriceWatch) Last(id int) *PricePoint {
+	h := w.History[id]
+	if h == nil || len(h.Points) == 0 {
+		return nil
+	}
+	return &h.Points[len(h.Points)-1]
+}
+
+// Low returns the lowest price recorded for id in currency, or -1.
+func (w *PriceWatch) Low(id int, currency string) float64 {
+	low := -1.0
+	if h := w.History[id]; h != nil {
+		for _, p := range h.Points {
+			if p.Currency == currency && (low < 0 || p.Final < low) {
+				low = p.Final
+			}
+		}
+	}
+	return low
+}
+
+// Record adds price to the history of a product and reports whether that
+// fires an alert: the price dropped to or below threshold (when set), or
+// below the lowest price seen before. Prices that stay the same don't fire
+// again.
+func (w *PriceWatch) Record(id int, title string, price Price, threshold float64, now time.Time) *PriceAlert {
+	low := w.Low(id, price.Currency)
+	var prev *PricePoint
+	if last := w.Last(id); last != nil && last.Currency == price.Currency {
+		prev = last
+	}
+
+	h := w.History[id]
+	if h == nil {
+		h = &PriceHistory{}
+		w.History[id] = h
+	}
+	h.Title = title
+	if prev == nil || prev.Final != price.Final || prev.Base != price.Base {
+		h.Points = append(h.Points, PricePoint{
+			Time: now, Final: price.Final, Base: price.Base, Discount: price.Discount, Currency: price.Currency,
+		})
+	}
+
+	alert := &PriceAlert{ID: id, Title: title, Price: price}
+	switch {
+	case threshold > 0 && price.Final <= threshold && (prev == nil || prev.Final > threshold):
+		alert.Reason = fmt.Sprintf("at or below %.2f %s", threshold, price.Currency)
+	case low >= 0 && price.Final < low:
+		alert.Reason = fmt.Sprintf("new low, was %.2f %s", low, price.Currency)
+		alert.Low = low
+	default:
+		return nil
+	}
+	return alert
+}
+
+type pricesResponse struct {
+	Embedded struct {
+		Prices []struct {
+			Currency struct {
+				Code string `json:"code"`
+			} `json:"currency"`
+			BasePrice  string `json:"basePrice"`  // cents and currency, "3999 USD"
+			FinalPrice string `json:"finalPrice"` // same
+		} `json:"prices"`
+	} `json:"_embedded"`
+}
+
+// GetPrice returns the store price of a product in country and currency.
+// It doesn't need a login.
+func (c *Client) GetPrice(id int, country, currency string) (*Price, error) {
+	url := fmt.Sprintf("%s/products/%d/prices?countryCode=%s", c.apiBaseURL(), id, strings.ToUpper(country))
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("failed to get price of %d (%d): %s", id, resp.StatusCode, body)
+	}
+	var result pricesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	for _, p := range result.Embedded.Prices {
+		if !strings.EqualFold(p.Currency.Code, currency) {
+			continue
+		}
+		price := &Price{Currency: p.Currency.Code, Final: parseCents(p.FinalPrice), Base: parseCents(p.BasePrice)}
+		if price.Base > price.Final {
+			price.Discount = int(math.Round((price.Base - price.Final) / price.Base * 100))
+		}
+		return price, nil
+	}
+	return nil, fmt.Errorf("no %s price for product %d", strings.ToUpper(currency), id)
+}
+
+// parseCents reads an amount like "3999 USD".
+func parseCents(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	cents, _ := strconv.ParseInt(fields[0], 10, 64)
+	return float64(cents) / 100
+}
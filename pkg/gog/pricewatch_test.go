@@ -0,0 +1,112 @@
+package gog
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceWatchRecord(t *testing.T) {
+	w, err := LoadPriceWatchFrom(filepath.Join(t.TempDir(), "prices.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	usd := func(final float64) Price { return Price{Final: final, Base: 20, Currency: "USD"} }
+
+	steps := []struct {
+		name      string
+		price     Price
+		threshold float64
+		reason    string
+		points    int
+	}{
+		{"first price", usd(20), 10, "", 1},
+		{"unchanged", usd(20), 10, "", 1},
+		{"new low", usd(15), 10, "new low, was 20.00 USD", 2},
+		{"back up", usd(18), 10, "", 3},
+		{"below threshold", usd(9), 10, "at or below 10.00 USD", 4},
+		{"still below threshold", usd(9), 10, "", 4},
+		{"other currency starts over", Price{Final: 8, Base: 18, Currency: "EUR"}, 0, "", 5},
+	}
+	for _, s := range steps {
+		alert := w.Record(1, "Game", s.price, s.threshold, now)
+		switch {
+		case s.reason == "" && alert != nil:
+			t.Errorf("%s: unexpected alert %q", s.name, alert.Reason)
+		case s.reason != "" && alert == nil:
+			t.Errorf("%s: no alert, want %q", s.name, s.reason)
+		case alert != nil && alert.Reason != s.reason:
+			t.Errorf("%s: reason = %q, want %q", s.name, alert.Reason, s.reason)
+		}
+		if got := len(w.History[1].Points); got != s.points {
+			t.Errorf("%s: %d points, want %d", s.name, got, s.points)
+		}
+	}
+	if low := w.Low(1, "USD"); low != 9 {
+		t.Errorf("Low = %v, want 9", low)
+	}
+}
+
+func TestPriceWatchSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goggle", "prices.json")
+	w, err := LoadPriceWatchFrom(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Add(Watch{ID: 2, Title: "Witcher", Below: 5})
+	w.Add(Watch{ID: 1, Title: "Baldur's Gate"})
+	w.Add(Watch{ID: 2, Title: "Witcher", Below: 3})
+	w.Record(2, "Witcher", Price{Final: 10, Base: 10, Currency: "USD"}, 0, time.Now())
+	if err := w.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadPriceWatchFrom(path)
+	if err != nil {
+		t.Fatalf("LoadPriceWatchFrom: %v", err)
+	}
+	if len(loaded.Watches) != 2 || loaded.Watches[0].ID != 1 || loaded.Watches[1].Below != 3 {
+		t.Errorf("watches = %+v", loaded.Watches)
+	}
+	if got := loaded.Watched(2); got == nil || got.Below != 3 {
+		t.Errorf("Watched(2) = %+v", got)
+	}
+	if loaded.Watched(3) != nil {
+		t.Error("Watched(3) should be nil")
+	}
+	if last := loaded.Last(2); last == nil || last.Final != 10 {
+		t.Errorf("Last = %+v", last)
+	}
+	if !loaded.Remove(2) || loaded.Remove(2) {
+		t.Error("Remove should succeed once")
+	}
+}
+
+func TestGetPrice(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/products/42/prices" || r.URL.Query().Get("countryCode") != "DE" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		fmt.Fprint(w, `{"_embedded": {"prices": [
+			{"currency": {"code": "USD"}, "basePrice": "3999 USD", "finalPrice": "999 USD"},
+			{"currency": {"code": "EUR"}, "basePrice": "3999 EUR", "finalPrice": "1999 EUR"}]}}`)
+	}))
+	defer ts.Close()
+	c := &Client{HTTPClient: ts.Client(), APIBaseURL: ts.URL}
+
+	p, err := c.GetPrice(42, "de", "eur")
+	if err != nil {
+		t.Fatalf("GetPrice: %v", err)
+	}
+	want := Price{Final: 19.99, Base: 39.99, Discount: 50, Currency: "EUR"}
+	if *p != want {
+		t.Errorf("price = %+v, want %+v", *p, want)
+	}
+	if _, err := c.GetPrice(42, "de", "gbp"); err == nil {
+		t.Error("expected error for missing currency")
+	}
+}
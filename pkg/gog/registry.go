@@ -0,0 +1,330 @@
+package gog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ManifestName is written into every install directory and lists the files
+// goggle put there.
+const ManifestName = ".goggle-manifest.json"
+
+// InstalledGame records a game goggle installed and the files that belong
+// to it.
+type InstalledGame struct {
+	ID       int    `json:"id,omitempty"`
+	Title    string `json:"title"`
+	Version  string `json:"version,omitempty"`
+	OS       string `json:"os,omitempty"`
+	Language string `json:"language,omitempty"`
+	Build    string `json:"build,omitempty"`  // content-system build ID, for installs without an installer
+	Pinned   bool   `json:"pinned,omitempty"` // Build was chosen explicitly; upgrades leave it alone
+	Branch   string `json:"branch,omitempty"` // content-system branch followed, "" for the default
+	// BranchPassword unlocks a password-protected Branch. It is kept out of
+	// the install manifest.
+	BranchPassword string    `json:"branch_password,omitempty"`
+	Dependencies   []string  `json:"dependencies,omitempty"` // content-system dependency IDs, see 'goggle deps'
+	Dir            string    `json:"dir"`
+	Installers     []string  `json:"installers,omitempty"` // installer files used, absolute paths
+	InstalledAt    time.Time `json:"installed_at"`
+	Files          []string  `json:"files"` // relative to Dir, slash separated
+}
+
+// DownloadRecord is an installer file fetched by 'goggle download'.
+type DownloadRecord struct {
+	GameID       int       `json:"game_id"`
+	Title        string    `json:"title"`
+	Version      string    `json:"version,omitempty"`
+	OS           string    `json:"os,omitempty"`
+	Language     string    `json:"language,omitempty"`
+	ManualURL    string    `json:"manual_url,omitempty"` // identifies the installer across versions
+	Path         string    `json:"path"`
+	DownloadedAt time.Time `json:"downloaded_at"`
+}
+
+// Registry is the list of installed games and downloaded installers,
+// stored in ~/.config/goggle/installed.json.
+type Registry struct {
+	Games     []InstalledGame  `json:"games"`
+	Downloads []DownloadRecord `json:"downloads,omitempty"`
+
+	path string
+}
+
+func registryPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "goggle", "installed.json"), nil
+}
+
+// LoadRegistry reads the default registry. A missing file is an empty
+// registry.
+func LoadRegistry() (*Registry, error) {
+	p, err := registryPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadRegistryFrom(p)
+}
+
+func LoadRegistryFrom(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Save writes the registry back to the file it was loaded from. Only the
+// user can read it, as it holds branch passwords.
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	// A temporary file left behind by an older version keeps its mode
+	if err := os.Chmod(tmp, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+// Add records g, replacing an earlier install into the same directory.
+// Installs of the same game in other directories are kept, so their files
+// can still be uninstalled.
+func (r *Registry) Add(g InstalledGame) {
+	replaced := false
+	for i, old := range r.Games {
+		if SamePath(old.Dir, g.Dir) {
+			r.Games[i] = g
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		r.Games = append(r.Games, g)
+	}
+	sort.Slice(r.Games, func(i, j int) bool {
+		return r.Games[i].Title < r.Games[j].Title
+	})
+}
+
+// Remove drops the entry installed in g.Dir.
+func (r *Registry) Remove(g InstalledGame) {
+	for i, old := range r.Games {
+		if SamePath(old.Dir, g.Dir) {
+			r.Games = append(r.Games[:i], r.Games[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddDownload records d, replacing an earlier record of the same file.
+func (r *Registry) AddDownload(d DownloadRecord) {
+	for i, old := range r.Downloads {
+		if SamePath(old.Path, d.Path) {
+			r.Downloads[i] = d
+			return
+		}
+	}
+	r.Downloads = append(r.Downloads, d)
+}
+
+// RemoveDownload drops the record of the file at path.
+func (r *Registry) RemoveDownload(path string) {
+	for i, old := range r.Downloads {
+		if SamePath(old.Path, path) {
+			r.Downloads = append(r.Downloads[:i], r.Downloads[i+1:]...)
+			return
+		}
+	}
+}
+
+// Downloaded returns the record of a file still on disk that holds the
+// current version of the installer at manualURL, or nil.
+func (r *Registry) Downloaded(manualURL, version string) *DownloadRecord {
+	for i, d := range r.Downloads {
+		if manualURL == "" || d.ManualURL != manualURL || !strings.EqualFold(d.Version, version) {
+			continue
+		}
+		if _, err := os.Stat(d.Path); err == nil {
+			return &r.Downloads[i]
+		}
+	}
+	return nil
+}
+
+// Installs returns the installs of the game with id, one per directory.
+func (r *Registry) Installs(id int) []*InstalledGame {
+	var installs []*InstalledGame
+	for i := range r.Games {
+		if id != 0 && r.Games[i].ID == id {
+			installs = append(installs, &r.Games[i])
+		}
+	}
+	return installs
+}
+
+// InDir returns the install in dir, or nil.
+func (r *Registry) InDir(dir string) *InstalledGame {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	for i := range r.Games {
+		if SamePath(r.Games[i].Dir, dir) {
+			return &r.Games[i]
+		}
+	}
+	return nil
+}
+
+// Game returns the install of the game with id, or nil if it isn't
+// installed. dir picks one of several installs; without it, a game
+// installed in more than one directory is an error.
+func (r *Registry) Game(id int, dir string) (*InstalledGame, error) {
+	installs := r.Installs(id)
+	if dir != "" {
+		if g := r.InDir(dir); g != nil && g.ID == id {
+			return g, nil
+		}
+		if len(installs) == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s is not installed in %s", installs[0].Title, dir)
+	}
+	return onlyInstall(installs)
+}
+
+// Find looks up an installed game by ID or title using the same rules as
+// MatchProduct. dir picks one of several installs of the game, as in Game.
+func (r *Registry) Find(query, dir string) (*InstalledGame, error) {
+	// Match each game once, however often it is installed
+	var games []int
+	seen := make(map[string]bool)
+	for i, g := range r.Games {
+		if k := g.key(); !seen[k] {
+			seen[k] = true
+			games = append(games, i)
+		}
+	}
+	i, err := matchTitle(len(games), func(i int) (int, string) {
+		return r.Games[games[i]].ID, r.Games[games[i]].Title
+	}, query, "installed")
+	if err != nil {
+		return nil, err
+	}
+	key := r.Games[games[i]].key()
+	var installs []*InstalledGame
+	for j := range r.Games {
+		if r.Games[j].key() == key {
+			installs = append(installs, &r.Games[j])
+		}
+	}
+	if dir != "" {
+		if g := r.InDir(dir); g != nil && g.key() == key {
+			return g, nil
+		}
+		return nil, fmt.Errorf("%s is not installed in %s", installs[0].Title, dir)
+	}
+	return onlyInstall(installs)
+}
+
+// key identifies the game of an install: its ID, or for installs from
+// installers without one, its title.
+func (g *InstalledGame) key() string {
+	if g.ID != 0 {
+		return strconv.Itoa(g.ID)
+	}
+	return "title:" + strings.ToLower(g.Title)
+}
+
+// onlyInstall returns the single install in installs, nil if there is none,
+// or an error naming the directories to choose from.
+func onlyInstall(installs []*InstalledGame) (*InstalledGame, error) {
+	switch len(installs) {
+	case 0:
+		return nil, nil
+	case 1:
+		return installs[0], nil
+	}
+	dirs := make([]string, len(installs))
+	for i, g := range installs {
+		dirs[i] = g.Dir
+	}
+	return nil, fmt.Errorf("%s is installed in several directories, pick one with --dir: %s",
+		installs[0].Title, strings.Join(dirs, ", "))
+}
+
+// SamePath reports whether two paths name the same file or directory.
+func SamePath(a, b string) bool {
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// WriteManifest stores g in its install directory.
+func WriteManifest(g InstalledGame) error {
+	g.BranchPassword = ""
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(g.Dir, ManifestName), data, 0644); err != nil {
+		return fmt.Errorf("failed to write install manifest: %w", err)
+	}
+	return nil
+}
+
+// RemoveFiles deletes the files of g and any directories left empty,
+// leaving files the user added untouched.
+func (g InstalledGame) RemoveFiles() error {
+	root, err := filepath.Abs(g.Dir)
+	if err != nil {
+		return err
+	}
+	dirs := map[string]bool{root: true}
+	for _, rel := range append(append([]string(nil), g.Files...), ManifestName) {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		if !strings.HasPrefix(p, root+string(filepath.Separator)) {
+			return fmt.Errorf("refusing to remove %s outside %s", rel, g.Dir)
+		}
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		for d := filepath.Dir(p); d != root && strings.HasPrefix(d, root); d = filepath.Dir(d) {
+			dirs[d] = true
+		}
+	}
+
+	// Remove the deepest directories first; non-empty ones are kept
+	sorted := make([]string, 0, len(dirs))
+	for d := range dirs {
+		sorted = append(sorted, d)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, d := range sorted {
+		_ = os.Remove(d)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package gog
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistrySaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goggle", "installed.json")
+
+	r, err := LoadRegistryFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRegistryFrom missing file: %v", err)
+	}
+	if len(r.Games) != 0 {
+		t.Fatalf("expected empty registry, got %d games", len(r.Games))
+	}
+
+	r.Add(InstalledGame{ID: 2, Title: "Witcher", Version: "1.0", Dir: "/games/witcher", InstalledAt: time.Now()})
+	r.Add(InstalledGame{ID: 1, Title: "Baldur's Gate", Version: "2.6", Dir: "/games/bg"})
+	// Reinstalling replaces the entry
+	r.Add(InstalledGame{ID: 2, Title: "Witcher", Version: "1.5", Dir: "/games/witcher"})
+	if err := r.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("registry mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	loaded, err := LoadRegistryFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRegistryFrom: %v", err)
+	}
+	if len(loaded.Games) != 2 {
+		t.Fatalf("got %d games, want 2", len(loaded.Games))
+	}
+	if loaded.Games[0].Title != "Baldur's Gate" {
+		t.Errorf("games not sorted by title: %v", loaded.Games)
+	}
+
+	g, err := loaded.Find("witch", "")
+	if err != nil {
+		t.Fatalf("Find: %v", err)
+	}
+	if g.Version != "1.5" {
+		t.Errorf("Version = %q, want 1.5", g.Version)
+	}
+	if _, err := loaded.Find("1", ""); err != nil {
+		t.Errorf("Find by ID: %v", err)
+	}
+	if _, err := loaded.Find("doom", ""); err == nil {
+		t.Error("expected error for unknown game")
+	}
+
+	if g, err := loaded.Game(1, ""); err != nil || g == nil || g.Title != "Baldur's Gate" {
+		t.Errorf("Game(1) = %v, %v", g, err)
+	}
+	if g, err := loaded.Game(3, ""); g != nil || err != nil {
+		t.Errorf("Game(3) = %v, %v, want nil", g, err)
+	}
+
+	loaded.Remove(*g)
+	if len(loaded.Games) != 1 || loaded.Games[0].ID != 1 {
+		t.Errorf("after Remove: %v", loaded.Games)
+	}
+}
+
+func TestRegistryAddKeepsOtherDirs(t *testing.T) {
+	r := &Registry{}
+	r.Add(InstalledGame{ID: 2, Title: "Witcher", Version: "1.0", Dir: "/games/witcher"})
+	r.Add(InstalledGame{ID: 3, Title: "Amnesia", Dir: "/games/amnesia"})
+	// A second copy of the same game elsewhere is a separate install
+	r.Add(InstalledGame{ID: 2, Title: "Witcher", Version: "1.5", Dir: "/other/witcher"})
+	if len(r.Games) != 3 {
+		t.Fatalf("got %d games, want 3: %v", len(r.Games), r.Games)
+	}
+	// Replacing an install keeps the list sorted
+	r.Add(InstalledGame{ID: 3, Title: "Zork", Dir: "/games/amnesia/"})
+	var titles []string
+	for _, g := range r.Games {
+		titles = append(titles, g.Title)
+	}
+	if strings.Join(titles, ",") != "Witcher,Witcher,Zork" {
+		t.Errorf("titles = %v", titles)
+	}
+
+	// Either install can be reached; without a directory it is ambiguous
+	if n := len(r.Installs(2)); n != 2 {
+		t.Errorf("Installs(2) = %d installs, want 2", n)
+	}
+	for _, query := range []string{"witch", "2"} {
+		if _, err := r.Find(query, ""); err == nil || !strings.Contains(err.Error(), "--dir") {
+			t.Errorf("Find(%q) error = %v, want an ambiguity", query, err)
+		}
+	}
+	if g, err := r.Find("witch", "/other/witcher/"); err != nil || g.Version != "1.5" {
+		t.Errorf("Find in /other/witcher = %+v, %v", g, err)
+	}
+	if g, err := r.Game(2, "/games/witcher"); err != nil || g.Version != "1.0" {
+		t.Errorf("Game(2) in /games/witcher = %+v, %v", g, err)
+	}
+	if _, err := r.Game(2, ""); err == nil {
+		t.Error("Game(2) without a directory should be ambiguous")
+	}
+	if _, err := r.Find("witch", "/games/amnesia"); err == nil {
+		t.Error("Find should fail for a directory holding another game")
+	}
+	if g, err := r.Find("zork", ""); err != nil || g.ID != 3 {
+		t.Errorf("Find(zork) = %+v, %v", g, err)
+	}
+	if g := r.InDir("/games/amnesia"); g == nil || g.Title != "Zork" {
+		t.Errorf("InDir = %+v", g)
+	}
+}
+
+func TestRemoveFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "game")
+	files := []string{"game.exe", "data/level1.dat", "data/sub/level2.dat"}
+	for _, f := range append(files, "saves/slot1.sav") {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g := InstalledGame{Title: "Game", Dir: dir, Files: files, Branch: "beta", BranchPassword: "secret"}
+	if err := WriteManifest(g); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, ManifestName)); err != nil || strings.Contains(string(data), "secret") {
+		t.Errorf("manifest leaks the branch password: %s, %v", data, err)
+	}
+
+	if err := g.RemoveFiles(); err != nil {
+		t.Fatalf("RemoveFiles: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data")); !os.IsNotExist(err) {
+		t.Error("data directory should be removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestName)); !os.IsNotExist(err) {
+		t.Error("manifest should be removed")
+	}
+	// Files goggle didn't install are kept
+	if _, err := os.Stat(filepath.Join(dir, "saves", "slot1.sav")); err != nil {
+		t.Errorf("save file removed: %v", err)
+	}
+
+	bad := InstalledGame{Dir: dir, Files: []string{"../outside"}}
+	if err := bad.RemoveFiles(); err == nil {
+		t.Error("expected error for path outside install directory")
+	}
+}
@@ -0,0 +1,382 @@
+package gog
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// installerExts maps the extensions of GOG installer files to the OS they
+// are for.
+var installerExts = map[string]string{
+	".exe": "windows",
+	".bin": "windows",
+	".sh":  "linux",
+	".pkg": "mac",
+	".dmg": "mac",
+}
+
+const (
+	ScanCurrent  = "current"  // matches a current installer file
+	ScanOutdated = "outdated" // an older build of a current installer
+	ScanMismatch = "mismatch" // named like a current file but size or checksum differ
+	ScanUnknown  = "unknown"  // not matched to an owned game
+)
+
+// VersionOlder is the version of an outdated file whose name doesn't show
+// one, so 'goggle outdated' still sees that it is behind.
+const VersionOlder = "older"
+
+// LocalFile is an installer found on disk.
+type LocalFile struct {
+	Path string
+	Size int64
+}
+
+// RemoteFile is an installer as currently served by GOG.
+type RemoteFile struct {
+	Installer
+	FileName string // name the CDN serves the file under
+	FileSize int64  // exact size from the checksum file, 0 if unknown
+	MD5      string // from the checksum file, "" if unknown
+}
+
+// ScanResult says what a file found by 'goggle scan' is.
+type ScanResult struct {
+	Path      string `json:"path"`
+	Status    string `json:"status"`
+	GameID    int    `json:"game_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Installer string `json:"installer,omitempty"`
+	ManualURL string `json:"manual_url,omitempty"`
+	OS        string `json:"os,omitempty"`
+	Language  string `json:"language,omitempty"`
+	Version   string `json:"version,omitempty"` // of the local file
+	Latest    string `json:"latest,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// Record returns the download record registering r's file.
+func (r ScanResult) Record() DownloadRecord {
+	return DownloadRecord{
+		GameID:    r.GameID,
+		Title:     r.Title,
+		Version:   r.Version,
+		OS:        r.OS,
+		Language:  r.Language,
+		ManualURL: r.ManualURL,
+		Path:      r.Path,
+	}
+}
+
+// FindInstallerFiles lists the files below dir that look like GOG
+// installers.
+func FindInstallerFiles(dir string) ([]LocalFile, error) {
+	var files []LocalFile
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Installed games and app bundles are not installers
+			if p != dir && (strings.HasPrefix(d.Name(), ".") || strings.HasSuffix(d.Name(), ".app")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := installerExts[strings.ToLower(filepath.Ext(p))]; !ok || !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, LocalFile{Path: abs, Size: info.Size()})
+		return nil
+	})
+	return files, err
+}
+
+var (
+	nonSlugRe = regexp.MustCompile(`[^a-z0-9]+`)
+	partRe    = regexp.MustCompile(`-(\d+)$`)
+)
+
+// titleSlug turns a title into the form GOG uses in file names, e.g.
+// "Baldur's Gate: Enhanced Edition" becomes "baldurs_gate_enhanced_edition".
+func titleSlug(title string) string {
+	s := strings.ToLower(title)
+	s = strings.NewReplacer("'", "", "’", "", "&", "and").Replace(s)
+	return strings.Trim(nonSlugRe.ReplaceAllString(s, "_"), "_")
+}
+
+// fileStem strips the extension, the part number of .bin files and the
+// setup_/gog_ prefix from an installer file name.
+func fileStem(name string) string {
+	s := strings.ToLower(strings.TrimSuffix(name, filepath.Ext(name)))
+	s = partRe.ReplaceAllString(s, "")
+	for _, prefix := range []string{"setup_", "gog_"} {
+		s = strings.TrimPrefix(s, prefix)
+	}
+	return s
+}
+
+// MatchInstallerFile guesses which of products an installer file name
+// belongs to: the game whose title slug the name starts with, preferring the
+// longest one. It also returns the version part of the name, if any.
+func MatchInstallerFile(products []Product, name string) (Product, string, bool) {
+	stem := fileStem(name)
+	var best Product
+	var bestSlug string
+	for _, p := range products {
+		for _, slug := range []string{titleSlug(p.Title), strings.TrimPrefix(titleSlug(p.Title), "the_")} {
+			if slug == "" || len(slug) <= len(bestSlug) {
+				continue
+			}
+			if stem == slug || strings.HasPrefix(stem, slug+"_") {
+				best, bestSlug = p, slug
+			}
+		}
+	}
+	if bestSlug == "" {
+		return Product{}, "", false
+	}
+	version := strings.TrimPrefix(strings.TrimPrefix(stem, bestSlug), "_")
+	return best, strings.ReplaceAll(version, "_", " "), true
+}
+
+// partNumber returns the part suffix of a .bin file name ("-1"), or "".
+func partNumber(name string) string {
+	return partRe.FindString(strings.TrimSuffix(name, filepath.Ext(name)))
+}
+
+// MatchFile classifies f against the current installer files of game.
+// localVersion is the version found in the file name. lookup resolves the
+// name, exact size and MD5 an installer is served with (Client.RemoteFile);
+// it is only called for installers whose listed size or version fits f, and
+// for those that can't be told apart otherwise. checksum computes the MD5 of
+// a file; nil skips the comparison.
+func MatchFile(f LocalFile, game Product, localVersion string, installers []Installer, lookup func(Installer) (RemoteFile, error), checksum func(path string) (string, error)) (ScanResult, error) {
+	name := filepath.Base(f.Path)
+	ext := strings.ToLower(filepath.Ext(name))
+	res := ScanResult{Path: f.Path, Status: ScanUnknown, GameID: game.ID, Title: game.Title, OS: installerExts[ext]}
+
+	var candidates []Installer
+	for _, inst := range installers {
+		if inst.OS == res.OS {
+			candidates = append(candidates, inst)
+		}
+	}
+	if len(candidates) == 0 {
+		res.Reason = fmt.Sprintf("%s has no %s installers", game.Title, res.OS)
+		return res, nil
+	}
+	// A language in the file name, e.g. "_(french)_", rules out the others
+	if named := inLanguageNamed(candidates, name); len(named) > 0 {
+		candidates = named
+	}
+
+	// The listed sizes are rounded, so they only say which installers the
+	// file could be a current copy of
+	for _, inst := range candidates {
+		if !sizeFits(inst.Bytes, f.Size) && !sameVersion(inst.Version, localVersion) {
+			continue
+		}
+		rf, err := lookup(inst)
+		if err != nil {
+			return res, err
+		}
+		if strings.EqualFold(rf.FileName, name) {
+			return matchCurrent(res, f, rf, checksum)
+		}
+	}
+
+	// An older build. Register it against the installer it was superseded
+	// by, which has to be clear from its language or its file name.
+	var match *RemoteFile
+	if len(candidates) == 1 {
+		match = &RemoteFile{Installer: candidates[0]}
+	} else {
+		stem := namePart(fileStem(name))
+		for _, inst := range candidates {
+			rf, err := lookup(inst)
+			if err != nil {
+				return res, err
+			}
+			if strings.EqualFold(rf.FileName, name) {
+				return matchCurrent(res, f, rf, checksum)
+			}
+			if !strings.EqualFold(filepath.Ext(rf.FileName), ext) || partNumber(rf.FileName) != partNumber(name) || namePart(fileStem(rf.FileName)) != stem {
+				continue
+			}
+			if match != nil && !SameLanguage(match.Language, rf.Language) {
+				res.Reason = fmt.Sprintf("could be the %s or the %s installer", match.Language, rf.Language)
+				return res, nil
+			}
+			if match == nil {
+				match = &rf
+			}
+		}
+	}
+	if match == nil {
+		res.Reason = "no current installer matches its name or language"
+		return res, nil
+	}
+	res.Status = ScanOutdated
+	res.Installer, res.ManualURL, res.Language = match.Name, match.ManualURL, match.Language
+	res.Version, res.Latest = localVersion, match.Version
+	if strings.TrimSpace(localVersion) == "" {
+		res.Version = VersionOlder
+	}
+	return res, nil
+}
+
+// matchCurrent checks a file named like the current installer rf.
+func matchCurrent(res ScanResult, f LocalFile, rf RemoteFile, checksum func(path string) (string, error)) (ScanResult, error) {
+	res.Installer, res.ManualURL, res.Language = rf.Name, rf.ManualURL, rf.Language
+	res.Version, res.Latest = rf.Version, rf.Version
+	if rf.FileSize > 0 && rf.FileSize != f.Size {
+		res.Status = ScanMismatch
+		res.Reason = fmt.Sprintf("size is %d bytes, expected %d", f.Size, rf.FileSize)
+		return res, nil
+	}
+	if checksum != nil && rf.MD5 != "" {
+		sum, err := checksum(f.Path)
+		if err != nil {
+			return res, err
+		}
+		if !strings.EqualFold(sum, rf.MD5) {
+			res.Status = ScanMismatch
+			res.Reason = "checksum differs"
+			return res, nil
+		}
+	}
+	res.Status = ScanCurrent
+	return res, nil
+}
+
+// sizeFits reports whether a file of size bytes could be an installer
+// listed as listed bytes. GOG rounds listed sizes to one decimal place of
+// their unit; 0 means the size is unknown.
+func sizeFits(listed, size int64) bool {
+	if listed == 0 {
+		return true
+	}
+	diff := listed - size
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= listed/16+1<<10
+}
+
+var nonVersionRe = regexp.MustCompile(`[^a-z0-9.]+`)
+
+// sameVersion reports whether a version found in a file name is the
+// installer version v, ignoring punctuation and build suffixes.
+func sameVersion(v, local string) bool {
+	a := nonVersionRe.ReplaceAllString(strings.ToLower(v), "")
+	b := nonVersionRe.ReplaceAllString(strings.ToLower(local), "")
+	return a != "" && strings.HasPrefix(b, a)
+}
+
+// inLanguageNamed returns the installers whose language file name spells
+// out, e.g. "setup_game_(french)_1.0.exe".
+func inLanguageNamed(installers []Installer, name string) []Installer {
+	var named []Installer
+	for _, word := range nonSlugRe.Split(strings.ToLower(name), -1) {
+		if len(word) < 3 {
+			continue
+		}
+		for _, inst := range installers {
+			if SameLanguage(word, inst.Language) {
+				named = append(named, inst)
+			}
+		}
+	}
+	return named
+}
+
+// namePart strips the version from a file stem: everything from the first
+// word that looks like one ("1.2", "v3", "(12345)"). Words such as
+// "(french)" are kept.
+func namePart(stem string) string {
+	words := strings.Split(stem, "_")
+	for i, w := range words {
+		if versionWordRe.MatchString(w) {
+			return strings.Join(words[:i], "_")
+		}
+	}
+	return stem
+}
+
+var versionWordRe = regexp.MustCompile(`^(\d+\.|v\d|\(\d)`)
+
+// FileMD5 returns the hex MD5 of the file at path.
+func FileMD5(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+type checksumFile struct {
+	Name      string `xml:"name,attr"`
+	MD5       string `xml:"md5,attr"`
+	TotalSize int64  `xml:"total_size,attr"`
+}
+
+// RemoteFile looks up the file name inst is served under and, where GOG
+// publishes a checksum file for it, its exact size and MD5.
+func (c *Client) RemoteFile(inst Installer) (RemoteFile, error) {
+	rf := RemoteFile{Installer: inst}
+	dl, err := c.ResolveDownload(inst.ManualURL)
+	if err != nil {
+		return rf, err
+	}
+	u, err := url.Parse(dl.Downlink)
+	if err != nil {
+		return rf, fmt.Errorf("invalid download URL: %w", err)
+	}
+	rf.FileName = path.Base(u.Path)
+	if dl.Checksum == "" {
+		return rf, nil
+	}
+
+	resp, err := c.HTTPClient.Get(dl.Checksum)
+	if err != nil {
+		return rf, fmt.Errorf("checksum request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != 200 {
+		// Not every file has one; the name is still useful
+		return rf, nil
+	}
+	var sum checksumFile
+	if err := xml.NewDecoder(resp.Body).Decode(&sum); err != nil {
+		return rf, fmt.Errorf("failed to parse checksum for %s: %w", rf.FileName, err)
+	}
+	if sum.Name != "" {
+		rf.FileName = sum.Name
+	}
+	rf.FileSize, rf.MD5 = sum.TotalSize, sum.MD5
+	return rf, nil
+}
@@ -0,0 +1,246 @@
+package gog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMatchInstallerFile(t *testing.T) {
+	products := []Product{
+		{ID: 1, Title: "Baldur's Gate"},
+		{ID: 2, Title: "Baldur's Gate: Enhanced Edition"},
+		{ID: 3, Title: "The Witcher 3: Wild Hunt"},
+		{ID: 4, Title: "Stardew Valley"},
+	}
+	tests := []struct {
+		name    string
+		wantID  int
+		version string
+	}{
+		{"setup_baldurs_gate_enhanced_edition_2.6.6.0_(25478).exe", 2, "2.6.6.0 (25478)"},
+		{"setup_baldurs_gate_1.3.exe", 1, "1.3"},
+		{"setup_witcher_3_wild_hunt_4.04-2.bin", 3, "4.04"},
+		{"gog_stardew_valley_2.0.0.3.sh", 4, "2.0.0.3"},
+		{"stardew_valley.pkg", 4, ""},
+		{"setup_doom_1.9.exe", 0, ""},
+		{"setup_baldurs_gatekeeper.exe", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, version, ok := MatchInstallerFile(products, tt.name)
+			if tt.wantID == 0 {
+				if ok {
+					t.Fatalf("matched %q, want no match", p.Title)
+				}
+				return
+			}
+			if !ok || p.ID != tt.wantID {
+				t.Fatalf("got %v (ok=%v), want ID %d", p, ok, tt.wantID)
+			}
+			if version != tt.version {
+				t.Errorf("version = %q, want %q", version, tt.version)
+			}
+		})
+	}
+}
+
+func TestMatchFile(t *testing.T) {
+	game := Product{ID: 3, Title: "The Witcher 3"}
+	installers := []Installer{
+		{Name: "Witcher 3 (Part 1 of 2)", ManualURL: "/dl/en1installer0", Version: "4.04", OS: "windows", Language: "English", Bytes: 1 << 30},
+		{Name: "Witcher 3 (Part 2 of 2)", ManualURL: "/dl/en1installer1", Version: "4.04", OS: "windows", Language: "English", Bytes: 4 << 30},
+		{Name: "Witcher 3", ManualURL: "/dl/fr1installer0", Version: "4.04", OS: "windows", Language: "français", Bytes: 5 << 30},
+		{Name: "Witcher 3", ManualURL: "/dl/en2installer0", Version: "4.04", OS: "linux", Language: "English", Bytes: 5 << 30},
+	}
+	served := map[string]RemoteFile{
+		"/dl/en1installer0": {Installer: installers[0], FileName: "setup_witcher_3_4.04.exe", FileSize: 1<<30 + 5, MD5: "aaa"},
+		"/dl/en1installer1": {Installer: installers[1], FileName: "setup_witcher_3_4.04-1.bin", FileSize: 4<<30 - 7},
+		"/dl/fr1installer0": {Installer: installers[2], FileName: "setup_witcher_3_(french)_4.04.exe", FileSize: 5 << 30},
+		"/dl/en2installer0": {Installer: installers[3], FileName: "witcher_3_4_04.sh", FileSize: 5 << 30},
+	}
+	checksum := func(sum string) func(string) (string, error) {
+		return func(string) (string, error) { return sum, nil }
+	}
+
+	tests := []struct {
+		name     string
+		file     LocalFile
+		version  string // found in the file name
+		checksum func(string) (string, error)
+		status   string
+		manual   string
+		lookups  int
+	}{
+		{"current", LocalFile{Path: "/x/setup_witcher_3_4.04.exe", Size: 1<<30 + 5}, "4.04", checksum("AAA"), ScanCurrent, "/dl/en1installer0", 1},
+		{"current part without checksum", LocalFile{Path: "/x/setup_witcher_3_4.04-1.bin", Size: 4<<30 - 7}, "4.04", checksum("zzz"), ScanCurrent, "/dl/en1installer1", 2},
+		{"no checksum check", LocalFile{Path: "/x/setup_witcher_3_4.04.exe", Size: 1<<30 + 5}, "4.04", nil, ScanCurrent, "/dl/en1installer0", 1},
+		{"truncated", LocalFile{Path: "/x/setup_witcher_3_4.04.exe", Size: 1 << 20}, "4.04", nil, ScanMismatch, "/dl/en1installer0", 1},
+		{"wrong checksum", LocalFile{Path: "/x/setup_witcher_3_4.04.exe", Size: 1<<30 + 5}, "4.04", checksum("bbb"), ScanMismatch, "/dl/en1installer0", 1},
+		{"outdated part", LocalFile{Path: "/x/setup_witcher_3_3.0-1.bin", Size: 3 << 29}, "3.0", nil, ScanOutdated, "/dl/en1installer1", 3},
+		{"outdated", LocalFile{Path: "/x/setup_witcher_3_3.0.exe", Size: 3 << 29}, "3.0", nil, ScanOutdated, "/dl/en1installer0", 3},
+		{"outdated language in name", LocalFile{Path: "/x/setup_witcher_3_(french)_3.0.exe", Size: 3 << 29}, "(french) 3.0", nil, ScanOutdated, "/dl/fr1installer0", 0},
+		{"other installer", LocalFile{Path: "/x/setup_witcher_3_soundtrack_3.0.exe", Size: 3 << 29}, "soundtrack 3.0", nil, ScanUnknown, "", 3},
+		{"other os", LocalFile{Path: "/x/witcher_3_3.0.pkg", Size: 5}, "3.0", nil, ScanUnknown, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups := 0
+			lookup := func(inst Installer) (RemoteFile, error) {
+				lookups++
+				return served[inst.ManualURL], nil
+			}
+			res, err := MatchFile(tt.file, game, tt.version, installers, lookup, tt.checksum)
+			if err != nil {
+				t.Fatalf("MatchFile: %v", err)
+			}
+			if res.Status != tt.status || res.ManualURL != tt.manual {
+				t.Errorf("got %s %q (%s), want %s %q", res.Status, res.ManualURL, res.Reason, tt.status, tt.manual)
+			}
+			if lookups != tt.lookups {
+				t.Errorf("looked up %d installers, want %d", lookups, tt.lookups)
+			}
+			if tt.status == ScanOutdated && (res.Latest != "4.04" || res.Version != tt.version) {
+				t.Errorf("Version, Latest = %q, %q", res.Version, res.Latest)
+			}
+		})
+	}
+
+	lookup := func(inst Installer) (RemoteFile, error) { return served[inst.ManualURL], nil }
+	_, err := MatchFile(LocalFile{Path: "/x/setup_witcher_3_4.04.exe", Size: 1<<30 + 5}, game, "4.04", installers, lookup, func(string) (string, error) {
+		return "", errors.New("read error")
+	})
+	if err == nil {
+		t.Error("expected checksum error")
+	}
+}
+
+func TestScanThenOutdated(t *testing.T) {
+	game := Product{ID: 3, Title: "The Witcher 3"}
+	installers := []Installer{
+		{Name: "Witcher 3", ManualURL: "/dl/en1installer0", Version: "4.04", OS: "windows", Language: "English", Bytes: 5 << 30},
+	}
+	lookup := func(inst Installer) (RemoteFile, error) {
+		return RemoteFile{Installer: inst, FileName: "setup_witcher_3_4.04.exe", FileSize: 5 << 30}, nil
+	}
+	reg := &Registry{}
+	for _, f := range []struct {
+		path    string
+		version string
+	}{
+		{"/x/setup_witcher_3.exe", ""},
+		{"/y/setup_witcher_3_3.0.exe", "3.0"},
+	} {
+		res, err := MatchFile(LocalFile{Path: f.path, Size: 3 << 29}, game, f.version, installers, lookup, nil)
+		if err != nil {
+			t.Fatalf("MatchFile: %v", err)
+		}
+		if res.Status != ScanOutdated {
+			t.Fatalf("%s: got %s (%s), want outdated", f.path, res.Status, res.Reason)
+		}
+		reg.AddDownload(res.Record())
+	}
+
+	updates, err := FindUpdates(reg, func(int) ([]Installer, error) { return installers, nil }, nil)
+	if err != nil {
+		t.Fatalf("FindUpdates: %v", err)
+	}
+	if len(updates) != 2 {
+		t.Fatalf("got %d updates, want both adopted files: %+v", len(updates), updates)
+	}
+	for _, u := range updates {
+		if u.Kind != UpdateDownload || u.Latest != "4.04" {
+			t.Errorf("update = %+v", u)
+		}
+		if u.Location == "/x/setup_witcher_3.exe" && u.Current != VersionOlder {
+			t.Errorf("Current = %q, want %q", u.Current, VersionOlder)
+		}
+	}
+}
+
+func TestFindInstallerFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"setup_game.exe", "a/game.sh", "a/notes.txt", ".hidden/setup_x.exe", "Game.app/setup_y.exe"} {
+		p := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := FindInstallerFiles(dir)
+	if err != nil {
+		t.Fatalf("FindInstallerFiles: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %v, want 2 files", files)
+	}
+	for _, f := range files {
+		if f.Size != 4 || !filepath.IsAbs(f.Path) {
+			t.Errorf("unexpected file %+v", f)
+		}
+	}
+}
+
+func TestRemoteFile(t *testing.T) {
+	var ts *httptest.Server
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dl/json":
+			_ = json.NewEncoder(w).Encode(DownlinkResponse{
+				Downlink: ts.URL + "/cdn/setup_game_1.0.exe?token=x",
+				Checksum: ts.URL + "/cdn/setup_game_1.0.exe.xml",
+			})
+		case "/dl/redirect":
+			http.Redirect(w, r, "https://cdn.example.com/files/game_1.0.sh?token=x", http.StatusFound)
+		case "/cdn/setup_game_1.0.exe.xml":
+			fmt.Fprint(w, `<file name="setup_game_1.0.exe" available="1" md5="abc123" chunks="1" total_size="4096"><chunk id="0" from="0" to="4095" method="md5">abc123</chunk></file>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	c := &Client{
+		HTTPClient:   ts.Client(),
+		EmbedBaseURL: ts.URL,
+		Token:        &Token{AccessToken: "tok", ExpiresIn: 3600, SavedAt: time.Now()},
+	}
+
+	rf, err := c.RemoteFile(Installer{ManualURL: "/dl/json"})
+	if err != nil {
+		t.Fatalf("RemoteFile: %v", err)
+	}
+	if rf.FileName != "setup_game_1.0.exe" || rf.FileSize != 4096 || rf.MD5 != "abc123" {
+		t.Errorf("got %+v", rf)
+	}
+
+	rf, err = c.RemoteFile(Installer{ManualURL: "/dl/redirect"})
+	if err != nil {
+		t.Fatalf("RemoteFile: %v", err)
+	}
+	if rf.FileName != "game_1.0.sh" || rf.FileSize != 0 || rf.MD5 != "" {
+		t.Errorf("got %+v", rf)
+	}
+}
+
+func TestFileMD5(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "f")
+	if err := os.WriteFile(p, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := FileMD5(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum != "5d41402abc4b2a76b9719d911017c592" {
+		t.Errorf("FileMD5 = %s", sum)
+	}
+}
@@ -0,0 +1,116 @@
+package gog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// SeenGame is the installer versions of a game at the last update check.
+type SeenGame struct {
+	Title     string            `json:"title"`
+	Versions  map[string]string `json:"versions"` // by OS
+	CheckedAt time.Time         `json:"checked_at"`
+}
+
+// SeenVersions remembers the installer versions 'goggle updates' last saw
+// for each game, stored in ~/.config/goggle/versions.json.
+type SeenVersions struct {
+	Games map[int]SeenGame `json:"games"`
+
+	path string
+}
+
+// VersionChange is a game whose installer for OS changed since the last
+// check.
+type VersionChange struct {
+	GameID    int    `json:"game_id"`
+	Title     string `json:"title"`
+	OS        string `json:"os"`
+	Previous  string `json:"previous"`
+	Current   string `json:"current"`
+	Changelog string `json:"changelog,omitempty"`
+}
+
+// LoadSeenVersions reads the default file. A missing file means nothing was
+// seen yet.
+func LoadSeenVersions() (*SeenVersions, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadSeenVersionsFrom(filepath.Join(home, ".config", "goggle", "versions.json"))
+}
+
+func LoadSeenVersionsFrom(path string) (*SeenVersions, error) {
+	s := &SeenVersions{Games: make(map[int]SeenGame), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if s.Games == nil {
+		s.Games = make(map[int]SeenGame)
+	}
+	return s, nil
+}
+
+// Save writes the versions back to the file they were loaded from.
+func (s *SeenVersions) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// InstallerVersions returns the current version for each OS installers are
+// offered for.
+func InstallerVersions(installers []Installer) map[string]string {
+	versions := make(map[string]string)
+	for _, inst := range installers {
+		if _, ok := versions[inst.OS]; !ok {
+			if v := LatestVersion(installers, inst.OS, ""); v != "" {
+				versions[inst.OS] = v
+			}
+		}
+	}
+	return versions
+}
+
+// Update records versions as the latest seen for game and returns how they
+// differ from the previous check. The first check of a game reports nothing.
+func (s *SeenVersions) Update(game Product, versions map[string]string, now time.Time) []VersionChange {
+	prev, seen := s.Games[game.ID]
+	s.Games[game.ID] = SeenGame{Title: game.Title, Versions: versions, CheckedAt: now}
+	if !seen {
+		return nil
+	}
+
+	var changes []VersionChange
+	for osName, current := range versions {
+		if previous := prev.Versions[osName]; IsOutdated(previous, current) {
+			changes = append(changes, VersionChange{
+				GameID: game.ID, Title: game.Title, OS: osName, Previous: previous, Current: current,
+			})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].OS < changes[j].OS })
+	return changes
+}
@@ -0,0 +1,53 @@
+package gog
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInstallerVersions(t *testing.T) {
+	got := InstallerVersions([]Installer{
+		{OS: "windows", Language: "English", Version: "1.2"},
+		{OS: "windows", Language: "Deutsch", Version: "1.1"},
+		{OS: "linux", Language: "English", Version: ""},
+		{OS: "linux", Language: "English", Version: "1.0"},
+		{OS: "mac", Language: "English"},
+	})
+	if len(got) != 2 || got["windows"] != "1.2" || got["linux"] != "1.0" {
+		t.Errorf("InstallerVersions = %v", got)
+	}
+}
+
+func TestSeenVersionsUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "versions.json")
+	s, err := LoadSeenVersionsFrom(path)
+	if err != nil {
+		t.Fatalf("LoadSeenVersionsFrom: %v", err)
+	}
+	game := Product{ID: 7, Title: "Game"}
+	now := time.Now()
+
+	if changes := s.Update(game, map[string]string{"windows": "1.0", "linux": "1.0"}, now); changes != nil {
+		t.Errorf("first check reported %v", changes)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	s, err = LoadSeenVersionsFrom(path)
+	if err != nil {
+		t.Fatalf("LoadSeenVersionsFrom: %v", err)
+	}
+	changes := s.Update(game, map[string]string{"windows": "1.1", "linux": "1.0", "mac": "1.1"}, now)
+	if len(changes) != 1 {
+		t.Fatalf("got %d changes, want 1: %v", len(changes), changes)
+	}
+	c := changes[0]
+	if c.OS != "windows" || c.Previous != "1.0" || c.Current != "1.1" || c.Title != "Game" {
+		t.Errorf("change = %+v", c)
+	}
+	if changes := s.Update(game, map[string]string{"windows": "1.1", "linux": "1.0", "mac": "1.1"}, now); len(changes) != 0 {
+		t.Errorf("unchanged versions reported %v", changes)
+	}
+}
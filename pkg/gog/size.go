@@ -0,0 +1,65 @@
+package gog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var sizeUnits = map[string]int64{
+	"b":     1,
+	"byte":  1,
+	"bytes": 1,
+	"kb":    1 << 10,
+	"mb":    1 << 20,
+	"gb":    1 << 30,
+	"tb":    1 << 40,
+}
+
+// ParseSize converts a human readable size such as "1.2 GB" (as returned in
+// Installer.Size) into bytes. GOG uses binary multiples.
+func ParseSize(s string) (int64, error) {
+	fields := strings.Fields(strings.TrimSpace(s))
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	num, unit := fields[0], "b"
+	if len(fields) == 2 {
+		unit = fields[1]
+	} else {
+		// Allow "500MB" with no space between number and unit
+		i := strings.IndexFunc(num, func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.'
+		})
+		if i > 0 {
+			num, unit = num[:i], num[i:]
+		}
+	}
+
+	mult, ok := sizeUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit in %q", s)
+	}
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// FormatSize renders a byte count the same way GOG does, e.g. "1.2 GB".
+func FormatSize(n int64) string {
+	units := []string{"bytes", "KB", "MB", "GB", "TB"}
+	f := float64(n)
+	i := 0
+	for f >= 1024 && i < len(units)-1 {
+		f /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	num := strings.TrimSuffix(strconv.FormatFloat(f, 'f', 1, 64), ".0")
+	return num + " " + units[i]
+}
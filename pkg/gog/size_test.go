@@ -0,0 +1,52 @@
+package gog
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{name: "gigabytes", input: "1.5 GB", want: 1536 << 20},
+		{name: "megabytes", input: "900 MB", want: 900 << 20},
+		{name: "kilobytes", input: "12 KB", want: 12 << 10},
+		{name: "bytes", input: "512 bytes", want: 512},
+		{name: "no space", input: "500MB", want: 500 << 20},
+		{name: "lowercase unit", input: "2 gb", want: 2 << 30},
+		{name: "bare number", input: "1024", want: 1024},
+		{name: "empty", input: "", wantErr: true},
+		{name: "unknown unit", input: "3 parsecs", wantErr: true},
+		{name: "not a number", input: "big GB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSize(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		input int64
+		want  string
+	}{
+		{input: 0, want: "0 bytes"},
+		{input: 1000, want: "1000 bytes"},
+		{input: 1536, want: "1.5 KB"},
+		{input: 900 << 20, want: "900 MB"},
+		{input: 1288490188, want: "1.2 GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatSize(tt.input); got != tt.want {
+			t.Errorf("FormatSize(%d) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package gog
+
+import (
+	"sort"
+	"strconv"
+)
+
+// GameInfo bundles everything known about one owned game for reporting.
+type GameInfo struct {
+	Details    *ProductDetails
+	Installers []Installer
+}
+
+type GameSize struct {
+	ID    int    `json:"id"`
+	Title string `json:"title"`
+	Bytes int64  `json:"bytes"`
+}
+
+type LanguageCount struct {
+	Code  string `json:"code"`
+	Name  string `json:"name"`
+	Games int    `json:"games"`
+}
+
+type YearCount struct {
+	Year  int `json:"year"`
+	Games int `json:"games"`
+}
+
+type LibraryStats struct {
+	Games        int              `json:"games"`
+	Platforms    map[string]int   `json:"platforms"`
+	SizeByOS     map[string]int64 `json:"size_by_os"`
+	TotalSize    int64            `json:"total_size"`
+	Languages    []LanguageCount  `json:"languages"`
+	ReleaseYears []YearCount      `json:"release_years"`
+	Largest      []GameSize       `json:"largest"`
+}
+
+// InstallerSizeByOS sums installer sizes per OS. When a game ships separate
+// installers per language only the largest language set is counted, since an
+// archive normally keeps one language per game.
+func InstallerSizeByOS(installers []Installer) map[string]int64 {
+	perLang := map[string]map[string]int64{}
+	for _, inst := range installers {
+		if inst.Bytes <= 0 {
+			continue
+		}
+		if perLang[inst.OS] == nil {
+			perLang[inst.OS] = map[string]int64{}
+		}
+		perLang[inst.OS][inst.Language] += inst.Bytes
+	}
+
+	sizes := map[string]int64{}
+	for osName, langs := range perLang {
+		for _, n := range langs {
+			if n > sizes[osName] {
+				sizes[osName] = n
+			}
+		}
+	}
+	return sizes
+}
+
+// ComputeStats aggregates the library into a report. top limits the number of
+// entries in Largest.
+func ComputeStats(games []GameInfo, top int) *LibraryStats {
+	stats := &LibraryStats{
+		Platforms: map[string]int{"windows": 0, "mac": 0, "linux": 0},
+		SizeByOS:  map[string]int64{},
+	}
+	langs := map[string]*LanguageCount{}
+	years := map[int]int{}
+
+	for _, g := range games {
+		if g.Details == nil {
+			continue
+		}
+		d := g.Details
+		stats.Games++
+
+		if d.ContentSystemCompatibility.Windows {
+			stats.Platforms["windows"]++
+		}
+		if d.ContentSystemCompatibility.OSX {
+			stats.Platforms["mac"]++
+		}
+		if d.ContentSystemCompatibility.Linux {
+			stats.Platforms["linux"]++
+		}
+
+		for code, name := range d.Languages {
+			lc, ok := langs[code]
+			if !ok {
+				lc = &LanguageCount{Code: code, Name: name}
+				langs[code] = lc
+			}
+			lc.Games++
+		}
+
+		if len(d.ReleaseDate) >= 4 {
+			if year, err := strconv.Atoi(d.ReleaseDate[:4]); err == nil {
+				years[year]++
+			}
+		}
+
+		var gameBytes int64
+		for osName, n := range InstallerSizeByOS(g.Installers) {
+			stats.SizeByOS[osName] += n
+			gameBytes += n
+		}
+		stats.TotalSize += gameBytes
+		stats.Largest = append(stats.Largest, GameSize{ID: d.ID, Title: d.Title, Bytes: gameBytes})
+	}
+
+	for _, lc := range langs {
+		stats.Languages = append(stats.Languages, *lc)
+	}
+	sort.Slice(stats.Languages, func(i, j int) bool {
+		if stats.Languages[i].Games != stats.Languages[j].Games {
+			return stats.Languages[i].Games > stats.Languages[j].Games
+		}
+		return stats.Languages[i].Code < stats.Languages[j].Code
+	})
+
+	for year, n := range years {
+		stats.ReleaseYears = append(stats.ReleaseYears, YearCount{Year: year, Games: n})
+	}
+	sort.Slice(stats.ReleaseYears, func(i, j int) bool {
+		return stats.ReleaseYears[i].Year < stats.ReleaseYears[j].Year
+	})
+
+	sort.SliceStable(stats.Largest, func(i, j int) bool {
+		return stats.Largest[i].Bytes > stats.Largest[j].Bytes
+	})
+	if top >= 0 && len(stats.Largest) > top {
+		stats.Largest = stats.Largest[:top]
+	}
+	return stats
+}
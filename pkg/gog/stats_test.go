@@ -0,0 +1,100 @@
+package gog
+
+import "testing"
+
+func TestInstallerSizeByOS(t *testing.T) {
+	installers := []Installer{
+		{OS: "windows", Language: "English", Bytes: 1 << 30},
+		{OS: "windows", Language: "English", Bytes: 1 << 30},
+		{OS: "windows", Language: "French", Bytes: 1 << 30},
+		{OS: "linux", Language: "English", Bytes: 500 << 20},
+		{OS: "mac", Language: "English", Size: "unknown"},
+	}
+
+	got := InstallerSizeByOS(installers)
+	if got["windows"] != 2<<30 {
+		t.Errorf("windows = %d, want %d", got["windows"], int64(2<<30))
+	}
+	if got["linux"] != 500<<20 {
+		t.Errorf("linux = %d, want %d", got["linux"], int64(500<<20))
+	}
+	if _, ok := got["mac"]; ok {
+		t.Errorf("mac should be absent when no size is known, got %d", got["mac"])
+	}
+}
+
+func TestComputeStats(t *testing.T) {
+	newDetails := func(id int, title, release string, win, mac, linux bool, langs map[string]string) *ProductDetails {
+		d := &ProductDetails{ID: id, Title: title, ReleaseDate: release, Languages: langs}
+		d.ContentSystemCompatibility.Windows = win
+		d.ContentSystemCompatibility.OSX = mac
+		d.ContentSystemCompatibility.Linux = linux
+		return d
+	}
+
+	games := []GameInfo{
+		{
+			Details: newDetails(1, "Small", "1998-11-19T00:00:00+0200", true, false, false, map[string]string{"en": "English"}),
+			Installers: []Installer{
+				{OS: "windows", Language: "English", Bytes: 100 << 20},
+			},
+		},
+		{
+			Details: newDetails(2, "Big", "2015-05-19T00:00:00+0200", true, true, true, map[string]string{"en": "English", "de": "Deutsch"}),
+			Installers: []Installer{
+				{OS: "windows", Language: "English", Bytes: 2 << 30},
+				{OS: "linux", Language: "English", Bytes: 1 << 30},
+			},
+		},
+		{
+			Details: newDetails(3, "Medium", "2015-01-01", false, false, true, map[string]string{"de": "Deutsch"}),
+			Installers: []Installer{
+				{OS: "linux", Language: "Deutsch", Bytes: 500 << 20},
+			},
+		},
+		{Details: nil},
+	}
+
+	stats := ComputeStats(games, 2)
+
+	if stats.Games != 3 {
+		t.Errorf("Games = %d, want 3", stats.Games)
+	}
+	if stats.Platforms["windows"] != 2 || stats.Platforms["mac"] != 1 || stats.Platforms["linux"] != 2 {
+		t.Errorf("Platforms = %v", stats.Platforms)
+	}
+	if want := int64(2<<30 + 100<<20); stats.SizeByOS["windows"] != want {
+		t.Errorf("SizeByOS[windows] = %d, want %d", stats.SizeByOS["windows"], want)
+	}
+	if want := int64(1<<30 + 500<<20); stats.SizeByOS["linux"] != want {
+		t.Errorf("SizeByOS[linux] = %d, want %d", stats.SizeByOS["linux"], want)
+	}
+	if want := int64(3<<30 + 600<<20); stats.TotalSize != want {
+		t.Errorf("TotalSize = %d, want %d", stats.TotalSize, want)
+	}
+
+	if len(stats.Languages) != 2 {
+		t.Fatalf("got %d languages, want 2", len(stats.Languages))
+	}
+	// Ties are ordered by code
+	if stats.Languages[0].Code != "de" || stats.Languages[0].Games != 2 {
+		t.Errorf("Languages[0] = %+v, want de with 2 games", stats.Languages[0])
+	}
+
+	wantYears := []YearCount{{Year: 1998, Games: 1}, {Year: 2015, Games: 2}}
+	if len(stats.ReleaseYears) != len(wantYears) {
+		t.Fatalf("ReleaseYears = %v, want %v", stats.ReleaseYears, wantYears)
+	}
+	for i, yc := range wantYears {
+		if stats.ReleaseYears[i] != yc {
+			t.Errorf("ReleaseYears[%d] = %v, want %v", i, stats.ReleaseYears[i], yc)
+		}
+	}
+
+	if len(stats.Largest) != 2 {
+		t.Fatalf("got %d largest, want 2", len(stats.Largest))
+	}
+	if stats.Largest[0].Title != "Big" || stats.Largest[1].Title != "Medium" {
+		t.Errorf("Largest = %v, want Big then Medium", stats.Largest)
+	}
+}
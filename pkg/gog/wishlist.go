@@ -0,0 +1,179 @@
+package gog
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type wishlistResponse struct {
+	Wishlist map[string]bool `json:"wishlist"`
+}
+
+// GetWishlist returns the IDs of the products on the user's wishlist.
+func (c *Client) GetWishlist() ([]int, error) {
+	return c.wishlistRequest(c.embedBaseURL() + "/user/wishlist.json")
+}
+
+func (c *Client) AddToWishlist(id int) error {
+	_, err := c.wishlistRequest(fmt.Sprintf("%s/user/wishlist/add/%d", c.embedBaseURL(), id))
+	return err
+}
+
+func (c *Client) RemoveFromWishlist(id int) error {
+	_, err := c.wishlistRequest(fmt.Sprintf("%s/user/wishlist/remove/%d", c.embedBaseURL(), id))
+	return err
+}
+
+// wishlistRequest calls one of the wishlist endpoints, which all answer
+// with the resulting wishlist.
+func (c *Client) wishlistRequest(rawURL string) ([]int, error) {
+	resp, err := c.AuthGet(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("wishlist request failed (%d): %s", resp.StatusCode, body)
+	}
+
+	var result wishlistResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	var ids []int
+	for key, on := range result.Wishlist {
+		if id, err := strconv.Atoi(key); err == nil && on {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids, nil
+}
+
+type filteredProductsResponse struct {
+	Products []Product `json:"products"`
+}
+
+// FindProductBySlug looks up a store product by its slug, the last part of
+// its store page URL (e.g. "baldurs_gate_enhanced_edition").
+func (c *Client) FindProductBySlug(slug string) (Product, error) {
+	q := url.Values{"mediaType": {"game"}, "search": {strings.ReplaceAll(slug, "_", " ")}}
+	resp, err := c.AuthGet(c.embedBaseURL() + "/games/ajax/filtered?" + q.Encode())
+	if err != nil {
+		return Product{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != 200 {
+		body, _ := io.ReadAll(resp.Body)
+		return Product{}, fmt.Errorf("product search failed (%d): %s", resp.StatusCode, body)
+	}
+	var result filteredProductsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return Product{}, err
+	}
+	for _, p := range result.Products {
+		if strings.EqualFold(p.Slug, slug) {
+			return p, nil
+		}
+	}
+	return Product{}, fmt.Errorf("no product with slug %q", slug)
+}
+
+// ParseProductRef splits a product reference given as an ID, a slug or a
+// store page URL into an ID or a slug.
+func ParseProductRef(ref string) (id int, slug string) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, ""
+	}
+	if u, err := url.Parse(ref); err == nil && u.Host != "" {
+		ref = strings.Trim(u.Path, "/")
+		if i := strings.LastIndex(ref, "/"); i >= 0 {
+			ref = ref[i+1:]
+		}
+	}
+	return 0, ref
+}
+
+// WriteProducts writes products as "json" or "csv" (id, title, slug).
+func WriteProducts(w io.Writer, products []Product, format string) error {
+	switch format {
+	case "json":
+		if products == nil {
+			products = []Product{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(products)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"id", "title", "slug"})
+		for _, p := range products {
+			_ = cw.Write([]string{strconv.Itoa(p.ID), p.Title, p.Slug})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q (use json or csv)", format)
+	}
+}
+
+// ReadProducts reads a list written by WriteProducts. CSV files need an id
+// or slug column; other columns are optional and may come in any order.
+func ReadProducts(r io.Reader, format string) ([]Product, error) {
+	switch format {
+	case "json":
+		var products []Product
+		if err := json.NewDecoder(r).Decode(&products); err != nil {
+			return nil, fmt.Errorf("invalid JSON product list: %w", err)
+		}
+		return products, nil
+	case "csv":
+		rows, err := csv.NewReader(r).ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV product list: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		cols := make(map[string]int)
+		for i, name := range rows[0] {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		idCol, hasID := cols["id"]
+		slugCol, hasSlug := cols["slug"]
+		titleCol, hasTitle := cols["title"]
+		if !hasID && !hasSlug {
+			return nil, errors.New("CSV product list needs an id or slug column")
+		}
+		var products []Product
+		for n, row := range rows[1:] {
+			var p Product
+			if hasID && row[idCol] != "" {
+				if p.ID, err = strconv.Atoi(strings.TrimSpace(row[idCol])); err != nil {
+					return nil, fmt.Errorf("line %d: invalid id %q", n+2, row[idCol])
+				}
+			}
+			if hasSlug {
+				p.Slug = strings.TrimSpace(row[slugCol])
+			}
+			if hasTitle {
+				p.Title = row[titleCol]
+			}
+			products = append(products, p)
+		}
+		return products, nil
+	default:
+		return nil, fmt.Errorf("unknown format %q (use json or csv)", format)
+	}
+}
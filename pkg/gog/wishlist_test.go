@@ -0,0 +1,143 @@
+package gog
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWishlist(t *testing.T) {
+	wishlist := map[string]bool{"3": true, "1": true, "2": false}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id string
+		switch {
+		case r.URL.Path == "/user/wishlist.json":
+		case strings.HasPrefix(r.URL.Path, "/user/wishlist/add/"):
+			id = strings.TrimPrefix(r.URL.Path, "/user/wishlist/add/")
+			wishlist[id] = true
+		case strings.HasPrefix(r.URL.Path, "/user/wishlist/remove/"):
+			id = strings.TrimPrefix(r.URL.Path, "/user/wishlist/remove/")
+			delete(wishlist, id)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		var items []string
+		for k, v := range wishlist {
+			items = append(items, fmt.Sprintf("%q: %v", k, v))
+		}
+		fmt.Fprintf(w, `{"wishlist": {%s}, "checksum": "x"}`, strings.Join(items, ","))
+	}))
+	defer ts.Close()
+	c := newTestClient(ts)
+
+	ids, err := c.GetWishlist()
+	if err != nil {
+		t.Fatalf("GetWishlist: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 3}) {
+		t.Errorf("GetWishlist = %v, want [1 3]", ids)
+	}
+
+	if err := c.AddToWishlist(7); err != nil {
+		t.Fatalf("AddToWishlist: %v", err)
+	}
+	if err := c.RemoveFromWishlist(1); err != nil {
+		t.Fatalf("RemoveFromWishlist: %v", err)
+	}
+	if ids, _ = c.GetWishlist(); !reflect.DeepEqual(ids, []int{3, 7}) {
+		t.Errorf("after add/remove: %v, want [3 7]", ids)
+	}
+}
+
+func TestFindProductBySlug(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/games/ajax/filtered" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("search"); !strings.HasPrefix(got, "baldurs gate") {
+			t.Errorf("search = %q", got)
+		}
+		fmt.Fprint(w, `{"products": [
+			{"id": 2, "title": "Baldur's Gate II", "slug": "baldurs_gate_2_enhanced_edition"},
+			{"id": 1, "title": "Baldur's Gate", "slug": "baldurs_gate"}]}`)
+	}))
+	defer ts.Close()
+	c := newTestClient(ts)
+
+	p, err := c.FindProductBySlug("baldurs_gate")
+	if err != nil {
+		t.Fatalf("FindProductBySlug: %v", err)
+	}
+	if p.ID != 1 || p.Title != "Baldur's Gate" {
+		t.Errorf("got %+v", p)
+	}
+	if _, err := c.FindProductBySlug("baldurs_gate_3"); err == nil {
+		t.Error("expected error for unknown slug")
+	}
+}
+
+func TestParseProductRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		id   int
+		slug string
+	}{
+		{"1207658924", 1207658924, ""},
+		{"baldurs_gate", 0, "baldurs_gate"},
+		{"https://www.gog.com/en/game/baldurs_gate", 0, "baldurs_gate"},
+		{"https://www.gog.com/game/witcher_3/", 0, "witcher_3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			id, slug := ParseProductRef(tt.ref)
+			if id != tt.id || slug != tt.slug {
+				t.Errorf("ParseProductRef = %d, %q, want %d, %q", id, slug, tt.id, tt.slug)
+			}
+		})
+	}
+}
+
+func TestWriteAndReadProducts(t *testing.T) {
+	products := []Product{
+		{ID: 1, Title: "Baldur's Gate", Slug: "baldurs_gate"},
+		{ID: 2, Title: `Title, with "quotes"`, Slug: "quotes"},
+	}
+	for _, format := range []string{"json", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteProducts(&buf, products, format); err != nil {
+				t.Fatalf("WriteProducts: %v", err)
+			}
+			got, err := ReadProducts(&buf, format)
+			if err != nil {
+				t.Fatalf("ReadProducts: %v", err)
+			}
+			if !reflect.DeepEqual(got, products) {
+				t.Errorf("round trip = %+v, want %+v", got, products)
+			}
+		})
+	}
+
+	t.Run("csv with slug only", func(t *testing.T) {
+		got, err := ReadProducts(strings.NewReader("Slug\nbaldurs_gate\n"), "csv")
+		if err != nil {
+			t.Fatalf("ReadProducts: %v", err)
+		}
+		if len(got) != 1 || got[0].Slug != "baldurs_gate" || got[0].ID != 0 {
+			t.Errorf("got %+v", got)
+		}
+	})
+	t.Run("csv without id or slug", func(t *testing.T) {
+		if _, err := ReadProducts(strings.NewReader("title\nx\n"), "csv"); err == nil {
+			t.Error("expected error")
+		}
+	})
+	if err := WriteProducts(&bytes.Buffer{}, products, "xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}